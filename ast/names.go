@@ -0,0 +1,63 @@
+package ast
+
+import "strings"
+
+// ResolveName returns the fully-qualified name, without a leading backslash,
+// that the class reference name refers to within file. It follows PHP's
+// name-resolution rules: a fully-qualified name is used as is, a name relative
+// to the namespace keyword is resolved against the file's namespace, a name
+// whose first segment is a use alias has that segment replaced by the
+// imported name, and any other name is prefixed by the file's namespace.
+// The special class names self, parent, and static are returned unchanged.
+//
+// A file that declares several namespaces resolves names as its last
+// namespace does. Use the ResolveName method of a NamespaceStmt to resolve
+// a name within another.
+func ResolveName(file *File, name string) string {
+	ns := ""
+	if file.Namespace != nil {
+		ns = file.Namespace.Name
+	}
+	return resolveName(ns, file.Imports, name)
+}
+
+// ResolveName returns the fully-qualified name that the class reference name
+// refers to within the namespace n, following the same rules as the
+// ResolveName function.
+func (n *NamespaceStmt) ResolveName(name string) string {
+	return resolveName(n.Name, n.Imports, name)
+}
+
+func resolveName(ns string, imports Imports, name string) string {
+	if strings.HasPrefix(name, `\`) {
+		return name[1:]
+	}
+
+	switch strings.ToLower(name) {
+	case "self", "parent", "static":
+		return name
+	}
+
+	if len(name) > len(`namespace\`) && strings.EqualFold(name[:len(`namespace\`)], `namespace\`) {
+		return qualify(ns, name[len(`namespace\`):])
+	}
+
+	first, rest := name, ""
+	if i := strings.Index(name, `\`); i >= 0 {
+		first, rest = name[:i], name[i:]
+	}
+	if imported, ok := imports.Uses[strings.ToLower(first)]; ok {
+		return imported + rest
+	}
+	return qualify(ns, name)
+}
+
+// qualify prefixes name with the namespace ns, unless ns is the global
+// namespace.
+func qualify(ns, name string) string {
+	ns = strings.Trim(ns, `\/`)
+	if ns == "" {
+		return name
+	}
+	return ns + `\` + name
+}
@@ -40,6 +40,9 @@ const (
 
 	// InterfaceDeclaration identfies a statement which declares a interface in the local namespace.
 	InterfaceDeclaration
+
+	// TraitDeclaration identfies a statement which declares a trait in the local namespace.
+	TraitDeclaration
 )
 
 type Format struct{}
@@ -47,6 +50,7 @@ type Format struct{}
 // An Identifier is a raw string that can be used to identify
 // a variable, function, class, constant, property, etc.
 type Identifier struct {
+	Begin  token.Position
 	Parent Node
 	Value  string
 }
@@ -66,6 +70,7 @@ func (i Identifier) Children() []Node {
 }
 
 type Variable struct {
+	Begin token.Position
 	// Name is the identifier for the variable, which may be
 	// a dynamic expression.
 	Name Dynamic
@@ -147,6 +152,7 @@ type Expr interface {
 // BinaryExpression is an expression that applies an operator to one, two, or three
 // operands. The operator determines how many operands it should contain.
 type BinaryExpr struct {
+	Begin      token.Position
 	Antecedent Expr
 	Subsequent Expr
 	Type       Type
@@ -168,6 +174,7 @@ func (b BinaryExpr) EvaluatesTo() Type {
 func (b BinaryExpr) Declares() DeclarationType { return NoDeclaration }
 
 type TernaryCallExpr struct {
+	Begin                  token.Position
 	Condition, True, False Expr
 	Type                   Type
 }
@@ -189,9 +196,10 @@ func (t TernaryCallExpr) Declares() DeclarationType { return NoDeclaration }
 // UnaryExpression is an expression that applies an operator to only one operand. The
 // operator may precede or follow the operand.
 type UnaryCallExpr struct {
+	Begin     token.Position
 	Operand   Expr
 	Operator  string
-	Preceding bool
+	Preceding bool // Preceding is true if the operator precedes its operand, as in ++$a.
 }
 
 func (u UnaryCallExpr) Children() []Node {
@@ -240,6 +248,7 @@ func Echo(exprs ...Expr) EchoStmt {
 // or it may be from data outside PHP-mode, such as "here" in: <? not here ?> here <? not here ?>
 type EchoStmt struct {
 	Expressions []Expr
+	Begin       token.Position
 }
 
 func (e EchoStmt) String() string {
@@ -259,6 +268,7 @@ func (e EchoStmt) Declares() DeclarationType { return NoDeclaration }
 // ReturnStmt represents a function return.
 type ReturnStmt struct {
 	Expr
+	Begin token.Position
 }
 
 func (r ReturnStmt) String() string {
@@ -304,6 +314,32 @@ func (c ContinueStmt) Children() []Node {
 	return nil
 }
 
+// GotoStmt jumps to the statement following the label of the same name.
+type GotoStmt struct {
+	Label string
+}
+
+func (g GotoStmt) String() string {
+	return "goto " + g.Label
+}
+
+func (g GotoStmt) Children() []Node { return nil }
+
+func (g GotoStmt) Declares() DeclarationType { return NoDeclaration }
+
+// LabelStmt marks a target for goto, as in end:
+type LabelStmt struct {
+	Name string
+}
+
+func (l LabelStmt) String() string {
+	return l.Name + ":"
+}
+
+func (l LabelStmt) Children() []Node { return nil }
+
+func (l LabelStmt) Declares() DeclarationType { return NoDeclaration }
+
 type ThrowStmt struct {
 	Expr
 }
@@ -315,6 +351,8 @@ type IncludeStmt struct {
 }
 
 type Include struct {
+	Begin       token.Position
+	Keyword     string // Keyword is include, include_once, require or require_once, as written.
 	Expressions []Expr
 }
 
@@ -327,7 +365,10 @@ func (i Include) Children() []Node {
 }
 
 func (i Include) String() string {
-	return "include"
+	if i.Keyword == "" {
+		return "include"
+	}
+	return strings.ToLower(i.Keyword)
 }
 
 func (i Include) EvaluatesTo() Type {
@@ -351,7 +392,10 @@ func (e ExitStmt) String() string {
 func (e ExitStmt) Declares() DeclarationType { return NoDeclaration }
 
 type NewCallExpr struct {
-	Class     Dynamic
+	Begin token.Position
+	Class Dynamic
+	// Arguments is nil if the class is not followed by parentheses, as in
+	// new Foo.
 	Arguments []Expr
 }
 
@@ -377,7 +421,72 @@ func (c NewCallExpr) Children() []Node {
 
 func (n NewCallExpr) Declares() DeclarationType { return NoDeclaration }
 
+// CloneExpr is a shallow copy of an object, as in clone $obj.
+type CloneExpr struct {
+	Begin token.Position
+	Expr  Expr
+}
+
+func (c CloneExpr) EvaluatesTo() Type {
+	return c.Expr.EvaluatesTo()
+}
+
+func (c CloneExpr) String() string {
+	return "clone"
+}
+
+func (c CloneExpr) Children() []Node {
+	return []Node{c.Expr}
+}
+
+func (c CloneExpr) Declares() DeclarationType { return NoDeclaration }
+
+// PipeExpr passes Value as the only argument to Callable, as in
+// $x |> strtoupper(...).
+type PipeExpr struct {
+	Begin    token.Position
+	Value    Expr
+	Callable Expr
+}
+
+func (p PipeExpr) EvaluatesTo() Type {
+	return Unknown
+}
+
+func (p PipeExpr) String() string {
+	return "|>"
+}
+
+func (p PipeExpr) Children() []Node {
+	return []Node{p.Value, p.Callable}
+}
+
+func (p PipeExpr) Declares() DeclarationType { return NoDeclaration }
+
+// NamedArgument is an argument passed by the name of its parameter, as in
+// f(name: $value).
+type NamedArgument struct {
+	Begin token.Position
+	Name  string
+	Value Expr
+}
+
+func (n NamedArgument) String() string {
+	return n.Name + ":"
+}
+
+func (n NamedArgument) EvaluatesTo() Type {
+	return n.Value.EvaluatesTo()
+}
+
+func (n NamedArgument) Children() []Node {
+	return []Node{n.Value}
+}
+
+func (n NamedArgument) Declares() DeclarationType { return NoDeclaration }
+
 type AssignmentExpr struct {
+	Begin    token.Position
 	Assignee Assignable
 	Value    Expr
 	Operator string
@@ -410,8 +519,13 @@ type FunctionCallStmt struct {
 }
 
 type FunctionCallExpr struct {
+	Begin        token.Position
 	FunctionName Dynamic
 	Arguments    []Expr
+
+	// FirstClassCallable is true for first-class callable syntax, as in
+	// strlen(...), which creates a Closure rather than calling the function.
+	FirstClassCallable bool
 }
 
 func (f FunctionCallExpr) EvaluatesTo() Type {
@@ -474,9 +588,13 @@ func (f FunctionStmt) Children() []Node {
 }
 
 type AnonymousFunction struct {
+	Begin            token.Position
 	ClosureVariables []*FunctionArgument
 	Arguments        []*FunctionArgument
+	ReturnType       string
 	Body             *Block
+	Static           bool // Static is true for a closure declared static, which is not bound to $this.
+	ByRef            bool // ByRef is true for a closure declared with &, which returns a reference.
 }
 
 func (a AnonymousFunction) EvaluatesTo() Type {
@@ -501,8 +619,11 @@ func (a AnonymousFunction) String() string {
 func (a AnonymousFunction) Declares() DeclarationType { return FunctionDeclaration }
 
 type FunctionDefinition struct {
-	Name      string
-	Arguments []*FunctionArgument
+	Name       string
+	Arguments  []*FunctionArgument
+	ReturnType string
+	ByRef      bool // ByRef is true for a function declared with &, which returns a reference.
+	Begin      token.Position
 }
 
 func (fd FunctionDefinition) Children() []Node {
@@ -521,6 +642,15 @@ type FunctionArgument struct {
 	TypeHint string
 	Default  Expr
 	Variable *Variable
+	Variadic bool // Variadic is true for a parameter declared with ..., which collects the remaining arguments.
+	ByRef    bool // ByRef is true for a parameter declared with &, which is passed by reference.
+	Begin    token.Position
+
+	// Promoted is true for a constructor parameter declared with a visibility
+	// or readonly modifier, which also declares a property of the same name.
+	Promoted   bool
+	Visibility Visibility
+	Readonly   bool
 }
 
 func (fa FunctionArgument) String() string {
@@ -538,12 +668,16 @@ func (fa FunctionArgument) Children() []Node {
 }
 
 type Class struct {
+	Begin      token.Position
 	Name       string
 	Extends    string
 	Implements []string
+	Traits     []string
 	Methods    []*Method
 	Properties []*Property
 	Constants  []*Constant
+	Final      bool
+	Readonly   bool // Readonly is true if every property of the class is readonly.
 }
 
 func (c Class) String() string {
@@ -564,9 +698,22 @@ func (c Class) Children() []Node {
 
 func (c Class) Declares() DeclarationType { return ClassDeclaration }
 
+// Trait is a trait declaration. Its body holds the same members as a class.
+type Trait struct {
+	*Class
+}
+
+func (t Trait) String() string {
+	return fmt.Sprintf("trait %s", t.Name)
+}
+
+func (t Trait) Declares() DeclarationType { return TraitDeclaration }
+
 type Constant struct {
-	Name  string
-	Value interface{}
+	Name       string
+	Value      interface{}
+	Visibility Visibility
+	TypeHint   string // TypeHint is the declared type of a typed class constant.
 }
 
 func (c Constant) Children() []Node { return nil }
@@ -580,7 +727,98 @@ func (c Constant) Declares() DeclarationType { return ConstantDeclaration }
 
 func (c Constant) EvaluatesTo() Type { return Unknown }
 
+// ConstantStmt declares one or more constants outside of a class, as in
+// const A = 1, B = 2;
+type ConstantStmt struct {
+	Constants []*Constant
+}
+
+func (c ConstantStmt) String() string {
+	return "const"
+}
+
+func (c ConstantStmt) Children() []Node {
+	n := make([]Node, len(c.Constants))
+	for i, constant := range c.Constants {
+		n[i] = constant
+	}
+	return n
+}
+
+func (c ConstantStmt) Declares() DeclarationType { return ConstantDeclaration }
+
+// NamespaceStmt declares the namespace of the statements following it, as in
+// namespace Foo\Bar; or of the statements of its block, as in
+// namespace Foo\Bar { ... }. Name is empty for the global namespace, which
+// may only be declared with a block.
+type NamespaceStmt struct {
+	Name string
+	// Block holds the statements of a namespace declared with a block, and is
+	// nil otherwise.
+	Block *Block
+	// Imports holds the use imports of the namespace, which do not apply in
+	// any other namespace of the file.
+	Imports
+}
+
+func (n NamespaceStmt) String() string {
+	return "namespace " + n.Name
+}
+
+func (n NamespaceStmt) Children() []Node {
+	if n.Block == nil {
+		return nil
+	}
+	return []Node{n.Block}
+}
+
+func (n NamespaceStmt) Declares() DeclarationType { return NoDeclaration }
+
+// UseStmt imports names into the current namespace, as in use Foo\Bar as
+// Baz; or use function Foo\{bar, baz};
+type UseStmt struct {
+	// Kind is function or const for use function and use const statements,
+	// and empty for imports of classes.
+	Kind string
+	// Prefix is the name shared by the imports of a group use, including its
+	// trailing backslash, as in Foo\ for use Foo\{Bar, Baz}. It is empty
+	// unless the imports are grouped.
+	Prefix  string
+	Imports []UseImport
+}
+
+// UseImport is one of the names imported by a use statement.
+type UseImport struct {
+	// Kind is function or const for a function or constant imported by a
+	// group use of classes, as in use Foo\{Bar, function baz}.
+	Kind  string
+	Name  string
+	Alias string // Alias is empty unless the import is aliased with as.
+}
+
+func (u UseStmt) String() string {
+	return "use"
+}
+
+func (u UseStmt) Children() []Node { return nil }
+
+func (u UseStmt) Declares() DeclarationType { return NoDeclaration }
+
+// AnonymousClass is the class declared by new class { ... }.
+type AnonymousClass struct {
+	*Class
+}
+
+func (a AnonymousClass) EvaluatesTo() Type {
+	return Object
+}
+
+func (a AnonymousClass) String() string {
+	return "class@anonymous"
+}
+
 type Interface struct {
+	Begin     token.Position
 	Name      string
 	Inherits  []string
 	Methods   []Method
@@ -604,6 +842,9 @@ func (i Interface) Declares() DeclarationType { return InterfaceDeclaration }
 type Property struct {
 	Name           string
 	Visibility     Visibility
+	Static         bool
+	Readonly       bool
+	TypeHint       string
 	Type           Type
 	Initialization Expr
 }
@@ -621,12 +862,17 @@ func (p Property) Children() []Node {
 }
 
 type PropertyCallExpr struct {
+	Begin    token.Position
 	Receiver Dynamic
 	Name     Dynamic
 	Type     Type
+	Nullsafe bool // Nullsafe is true for a lookup with ?-> rather than ->.
 }
 
 func (p PropertyCallExpr) String() string {
+	if p.Nullsafe {
+		return fmt.Sprintf("%s?->%s", p.Receiver, p.Name)
+	}
 	return fmt.Sprintf("%s->%s", p.Receiver, p.Name)
 }
 
@@ -647,6 +893,7 @@ func (p PropertyCallExpr) Children() []Node {
 func (p PropertyCallExpr) Declares() DeclarationType { return NoDeclaration }
 
 type ClassExpr struct {
+	Begin    token.Position
 	Receiver Dynamic
 	Expr     Dynamic
 	Type     Type
@@ -680,6 +927,9 @@ func (c ClassExpr) Declares() DeclarationType { return NoDeclaration }
 type Method struct {
 	*FunctionStmt
 	Visibility Visibility
+	Static     bool
+	Abstract   bool
+	Final      bool
 }
 
 func (m Method) String() string {
@@ -692,6 +942,7 @@ func (m Method) Children() []Node {
 
 type MethodCallExpr struct {
 	Receiver Dynamic
+	Nullsafe bool
 	*FunctionCallExpr
 }
 
@@ -703,6 +954,9 @@ func (m MethodCallExpr) Children() []Node {
 }
 
 func (m MethodCallExpr) String() string {
+	if m.Nullsafe {
+		return fmt.Sprintf("%s?->", m.Receiver)
+	}
 	return fmt.Sprintf("%s->", m.Receiver)
 }
 
@@ -727,8 +981,10 @@ func (v Visibility) Token() token.Token {
 }
 
 type IfStmt struct {
+	Begin     token.Position
 	Branches  []IfBranch
 	ElseBlock Statement
+	Alternate bool // Alternate is true if the statement is written as if ($a): ... endif;
 }
 
 type IfBranch struct {
@@ -762,9 +1018,11 @@ func (i IfStmt) Children() []Node {
 func (i IfStmt) Declares() DeclarationType { return NoDeclaration }
 
 type SwitchStmt struct {
+	Begin       token.Position
 	Expr        Expr
 	Cases       []*SwitchCase
 	DefaultCase *Block
+	Alternate   bool // Alternate is true for switch ($a): ... endswitch;
 }
 
 func (s SwitchStmt) String() string {
@@ -802,11 +1060,15 @@ func (s SwitchCase) Children() []Node {
 	}
 }
 
+// ForStmt is a for loop. Each of its three clauses is nil if it was left
+// empty, and an empty Termination loops until broken out of.
 type ForStmt struct {
+	Begin          token.Position
 	Initialization []Expr
 	Termination    []Expr
 	Iteration      []Expr
 	LoopBlock      Statement
+	Alternate      bool // Alternate is true for for (...): ... endfor;
 }
 
 func (f ForStmt) String() string {
@@ -830,8 +1092,10 @@ func (f ForStmt) Children() []Node {
 func (_ ForStmt) Declares() DeclarationType { return NoDeclaration }
 
 type WhileStmt struct {
+	Begin       token.Position
 	Termination Expr
 	LoopBlock   Statement
+	Alternate   bool // Alternate is true for while ($a): ... endwhile;
 }
 
 func (w WhileStmt) String() string {
@@ -848,6 +1112,7 @@ func (w WhileStmt) Children() []Node {
 func (_ WhileStmt) Declares() DeclarationType { return NoDeclaration }
 
 type DoWhileStmt struct {
+	Begin       token.Position
 	Termination Expr
 	LoopBlock   Statement
 }
@@ -866,6 +1131,7 @@ func (d DoWhileStmt) Children() []Node {
 func (_ DoWhileStmt) Declares() DeclarationType { return NoDeclaration }
 
 type TryStmt struct {
+	Begin        token.Position
 	TryBlock     *Block
 	FinallyBlock *Block
 	CatchStmts   []*CatchStmt
@@ -890,11 +1156,14 @@ func (_ TryStmt) Declares() DeclarationType { return NoDeclaration }
 
 type CatchStmt struct {
 	CatchBlock *Block
-	CatchType  string
-	CatchVar   *Variable
+	CatchType  string    // CatchType holds the caught classes, separated by |.
+	CatchVar   *Variable // CatchVar is nil if the exception is not captured.
 }
 
 func (c CatchStmt) String() string {
+	if c.CatchVar == nil {
+		return fmt.Sprintf("catch %s", c.CatchType)
+	}
 	return fmt.Sprintf("catch %s %s", c.CatchType, c.CatchVar)
 }
 
@@ -903,6 +1172,7 @@ func (c CatchStmt) Children() []Node {
 }
 
 type Literal struct {
+	Begin token.Position
 	Type  Type
 	Value string
 }
@@ -922,10 +1192,13 @@ func (l Literal) Children() []Node {
 func (_ Literal) Declares() DeclarationType { return NoDeclaration }
 
 type ForeachStmt struct {
+	Begin     token.Position
 	Source    Expr
 	Key       *Variable
 	Value     *Variable
+	ByRef     bool // ByRef is true if Value is bound to each element by reference.
 	LoopBlock Statement
+	Alternate bool // Alternate is true for foreach (...): ... endforeach;
 }
 
 func (f ForeachStmt) String() string {
@@ -944,6 +1217,7 @@ func (f ForeachStmt) Children() []Node {
 func (_ ForeachStmt) Declares() DeclarationType { return NoDeclaration }
 
 type ArrayExpr struct {
+	Begin token.Position
 	ArrayType
 	Pairs []ArrayPair
 }
@@ -968,7 +1242,10 @@ type ArrayPair struct {
 }
 
 func (p ArrayPair) Children() []Node {
-	if p.Key != nil {
+	switch {
+	case p.Value == nil:
+		return nil
+	case p.Key != nil:
 		return []Node{p.Key, p.Value}
 	}
 	return []Node{p.Value}
@@ -987,6 +1264,7 @@ func (a ArrayExpr) AssignableType() Type {
 }
 
 type ArrayLookupExpr struct {
+	Begin token.Position
 	Array Dynamic
 	Index Expr
 }
@@ -1010,6 +1288,7 @@ func (a ArrayLookupExpr) AssignableType() Type {
 }
 
 type ArrayAppendExpr struct {
+	Begin token.Position
 	Array Dynamic
 }
 
@@ -1032,6 +1311,7 @@ func (a ArrayAppendExpr) String() string {
 func (_ ArrayAppendExpr) Declares() DeclarationType { return NoDeclaration }
 
 type ShellCommand struct {
+	Begin   token.Position
 	Command string
 }
 
@@ -1048,8 +1328,125 @@ func (s ShellCommand) Children() []Node {
 
 func (_ ShellCommand) Declares() DeclarationType { return NoDeclaration }
 
+// PrintExpr is a print of a single expression. Unlike echo, print is an
+// expression, and always evaluates to 1.
+type PrintExpr struct {
+	Begin token.Position
+	Expr  Expr
+}
+
+func (p PrintExpr) String() string {
+	return "print"
+}
+
+func (p PrintExpr) EvaluatesTo() Type {
+	return Integer
+}
+
+func (p PrintExpr) Children() []Node {
+	return []Node{p.Expr}
+}
+
+func (_ PrintExpr) Declares() DeclarationType { return NoDeclaration }
+
+// ThrowExpr is a throw within an expression, as in $a ?? throw new E(). A
+// throw on its own is a ThrowStmt.
+type ThrowExpr struct {
+	Begin token.Position
+	Expr  Expr
+}
+
+func (t ThrowExpr) String() string {
+	return "throw"
+}
+
+func (t ThrowExpr) EvaluatesTo() Type {
+	return Unknown
+}
+
+func (t ThrowExpr) Children() []Node {
+	return []Node{t.Expr}
+}
+
+func (_ ThrowExpr) Declares() DeclarationType { return NoDeclaration }
+
+// MatchExpr is a match expression, as in
+// match ($x) { 1, 2 => "low", default => "high" }.
+type MatchExpr struct {
+	Begin   token.Position
+	Subject Expr
+	Arms    []*MatchArm
+}
+
+// MatchArm is an arm of a match expression. The default arm has no
+// conditions.
+type MatchArm struct {
+	Begin      token.Position
+	Conditions []Expr
+	Body       Expr
+}
+
+func (m MatchExpr) String() string {
+	return "match"
+}
+
+func (m MatchExpr) EvaluatesTo() Type {
+	return Unknown
+}
+
+func (m MatchExpr) Children() []Node {
+	n := []Node{m.Subject}
+	for _, arm := range m.Arms {
+		for _, c := range arm.Conditions {
+			n = append(n, c)
+		}
+		n = append(n, arm.Body)
+	}
+	return n
+}
+
+func (_ MatchExpr) Declares() DeclarationType { return NoDeclaration }
+
+// YieldExpr is a yield from a generator, as in yield, yield $value,
+// yield $key => $value, or yield from $iterable.
+type YieldExpr struct {
+	Begin token.Position
+	Key   Expr
+	Value Expr
+	From  bool
+}
+
+func (y YieldExpr) String() string {
+	if y.From {
+		return "yield from"
+	}
+	return "yield"
+}
+
+func (y YieldExpr) EvaluatesTo() Type {
+	return Unknown
+}
+
+func (y YieldExpr) Children() []Node {
+	n := []Node{}
+	if y.Key != nil {
+		n = append(n, y.Key)
+	}
+	if y.Value != nil {
+		n = append(n, y.Value)
+	}
+	return n
+}
+
+func (_ YieldExpr) Declares() DeclarationType { return NoDeclaration }
+
+// ListStatement is a list() destructuring assignment. An assignee is nil
+// where an element is skipped, and is an *ArrayExpr where list() patterns
+// nest.
 type ListStatement struct {
+	Begin     token.Position
 	Assignees []Assignable
+	Keys      []Expr // Keys holds the key of each assignee in a keyed list, and is nil otherwise.
 	Value     Expr
 	Operator  string
 }
@@ -1092,9 +1489,13 @@ func (s StaticVariableDeclaration) Declares() DeclarationType { return NoDeclara
 type DeclareBlock struct {
 	Statements   *Block
 	Declarations []string
+	Alternate    bool // Alternate is true for declare(...): ... enddeclare;
 }
 
 func (d DeclareBlock) Children() []Node {
+	if d.Statements == nil {
+		return nil
+	}
 	return d.Statements.Children()
 }
 
@@ -0,0 +1,52 @@
+package ast
+
+import "github.com/stephens2424/php/token"
+
+// Positioner is implemented by the nodes that record the position of the
+// first token they were parsed from. Declarations, statements and
+// expressions record their position. The position of an expression that
+// begins with an operand, such as $a + 1 or $a->b(), is that of the operand.
+type Positioner interface {
+	Node
+	Position() token.Position
+}
+
+func (f FunctionDefinition) Position() token.Position { return f.Begin }
+func (f FunctionArgument) Position() token.Position   { return f.Begin }
+func (c Class) Position() token.Position              { return c.Begin }
+func (i Interface) Position() token.Position          { return i.Begin }
+func (i IfStmt) Position() token.Position             { return i.Begin }
+func (w WhileStmt) Position() token.Position          { return w.Begin }
+func (d DoWhileStmt) Position() token.Position        { return d.Begin }
+func (f ForStmt) Position() token.Position            { return f.Begin }
+func (f ForeachStmt) Position() token.Position        { return f.Begin }
+func (s SwitchStmt) Position() token.Position         { return s.Begin }
+func (t TryStmt) Position() token.Position            { return t.Begin }
+func (r ReturnStmt) Position() token.Position         { return r.Begin }
+func (e EchoStmt) Position() token.Position           { return e.Begin }
+
+func (i Identifier) Position() token.Position        { return i.Begin }
+func (v Variable) Position() token.Position          { return v.Begin }
+func (b BinaryExpr) Position() token.Position        { return b.Begin }
+func (t TernaryCallExpr) Position() token.Position   { return t.Begin }
+func (u UnaryCallExpr) Position() token.Position     { return u.Begin }
+func (i Include) Position() token.Position           { return i.Begin }
+func (n NewCallExpr) Position() token.Position       { return n.Begin }
+func (c CloneExpr) Position() token.Position         { return c.Begin }
+func (p PipeExpr) Position() token.Position          { return p.Begin }
+func (n NamedArgument) Position() token.Position     { return n.Begin }
+func (a AssignmentExpr) Position() token.Position    { return a.Begin }
+func (f FunctionCallExpr) Position() token.Position  { return f.Begin }
+func (a AnonymousFunction) Position() token.Position { return a.Begin }
+func (p PropertyCallExpr) Position() token.Position  { return p.Begin }
+func (c ClassExpr) Position() token.Position         { return c.Begin }
+func (l Literal) Position() token.Position           { return l.Begin }
+func (a ArrayExpr) Position() token.Position         { return a.Begin }
+func (a ArrayLookupExpr) Position() token.Position   { return a.Begin }
+func (a ArrayAppendExpr) Position() token.Position   { return a.Begin }
+func (s ShellCommand) Position() token.Position      { return s.Begin }
+func (p PrintExpr) Position() token.Position         { return p.Begin }
+func (t ThrowExpr) Position() token.Position         { return t.Begin }
+func (m MatchExpr) Position() token.Position         { return m.Begin }
+func (y YieldExpr) Position() token.Position         { return y.Begin }
+func (l ListStatement) Position() token.Position     { return l.Begin }
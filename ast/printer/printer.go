@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 
 	"github.com/stephens2424/php/ast"
+	"github.com/stephens2424/php/token"
 )
 
 type Printer struct {
 	w         io.Writer
 	tabLevel  int
 	tabString string
+	err       error
 }
 
 func NewPrinter(w io.Writer) *Printer {
@@ -23,6 +26,16 @@ func NewPrinter(w io.Writer) *Printer {
 	}
 }
 
+// Unparse returns PHP source code for node. Printing an *ast.File produces a
+// complete file, including the HTML outside of its PHP tags. An error is
+// returned if node contains a type of node that cannot be printed.
+func Unparse(node ast.Node) (string, error) {
+	buf := &bytes.Buffer{}
+	p := NewPrinter(buf)
+	p.PrintNode(node)
+	return buf.String(), p.err
+}
+
 func (p *Printer) tab() {
 	io.WriteString(p.w, strings.Repeat(p.tabString, p.tabLevel))
 }
@@ -36,7 +49,25 @@ func (p *Printer) detab() {
 }
 
 func (p *Printer) PrintNode(node ast.Node) {
+	if node == nil {
+		return
+	}
+	// The parser produces some nodes as values and others as pointers, so
+	// values are printed through a pointer to a copy.
+	switch v := reflect.ValueOf(node); v.Kind() {
+	case reflect.Struct:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		node = ptr.Interface().(ast.Node)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+	}
+
 	switch n := node.(type) {
+	case *ast.AnonymousClass:
+		p.PrintAnonymousClass(n, nil)
 	case *ast.AnonymousFunction:
 		p.PrintAnonymousFunction(n)
 	case *ast.ArrayAppendExpr:
@@ -47,8 +78,6 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintArrayLookupExpression(n)
 	case *ast.ArrayPair:
 		p.PrintArrayPair(n)
-	case ast.AssignmentExpr:
-		p.PrintAssignmentExpression(&n)
 	case *ast.AssignmentExpr:
 		p.PrintAssignmentExpression(n)
 	case *ast.BinaryExpr:
@@ -63,10 +92,14 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintClass(n)
 	case *ast.ClassExpr:
 		p.PrintClassExpression(n)
+	case *ast.CloneExpr:
+		p.PrintCloneExpression(n)
 	case *ast.Constant:
 		p.PrintConstant(n)
 	case *ast.ConstantExpr:
 		p.PrintConstantExpression(n)
+	case *ast.ConstantStmt:
+		p.PrintConstantStmt(n)
 	case *ast.ContinueStmt:
 		p.PrintContinueStmt(n)
 	case *ast.DeclareBlock:
@@ -79,10 +112,10 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintEmptyStatement(n)
 	case *ast.ExitStmt:
 		p.PrintExitStmt(n)
-	case ast.ExprStmt:
-		p.PrintExpressionStmt(&n)
 	case *ast.ExprStmt:
 		p.PrintExpressionStmt(n)
+	case *ast.File:
+		p.PrintFile(n)
 	case *ast.ForStmt:
 		p.PrintForStmt(n)
 	case *ast.ForeachStmt:
@@ -99,6 +132,8 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintFunctionStmt(n)
 	case *ast.GlobalDeclaration:
 		p.PrintGlobalDeclaration(n)
+	case *ast.GotoStmt:
+		p.PrintGotoStmt(n)
 	case *ast.Identifier:
 		p.PrintIdentifier(n)
 	case *ast.IfStmt:
@@ -109,24 +144,34 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintIncludeStmt(n)
 	case *ast.Interface:
 		p.PrintInterface(n)
+	case *ast.LabelStmt:
+		p.PrintLabelStmt(n)
 	case *ast.ListStatement:
 		p.PrintListStatement(n)
 	case *ast.Literal:
 		p.PrintLiteral(n)
+	case *ast.MatchExpr:
+		p.PrintMatchExpression(n)
 	case *ast.Method:
 		p.PrintMethod(n)
 	case *ast.MethodCallExpr:
 		p.PrintMethodCallExpression(n)
+	case *ast.NamedArgument:
+		p.PrintNamedArgument(n)
+	case *ast.NamespaceStmt:
+		p.PrintNamespaceStmt(n)
 	case *ast.NewCallExpr:
 		p.PrintNewExpression(n)
+	case *ast.PipeExpr:
+		p.PrintPipeExpression(n)
+	case *ast.PrintExpr:
+		p.PrintPrintExpression(n)
 	case *ast.Property:
 		p.PrintProperty(n)
 	case *ast.PropertyCallExpr:
 		p.PrintPropertyExpression(n)
 	case *ast.ReturnStmt:
 		p.PrintReturnStmt(n)
-	case ast.ShellCommand:
-		p.PrintShellCommand(&n)
 	case *ast.ShellCommand:
 		p.PrintShellCommand(n)
 	case *ast.StaticVariableDeclaration:
@@ -137,18 +182,252 @@ func (p *Printer) PrintNode(node ast.Node) {
 		p.PrintSwitchStmt(n)
 	case *ast.TernaryCallExpr:
 		p.PrintTernaryExpression(n)
+	case *ast.ThrowExpr:
+		p.PrintThrowExpression(n)
 	case *ast.ThrowStmt:
 		p.PrintThrowStmt(n)
+	case *ast.Trait:
+		p.PrintTrait(n)
 	case *ast.TryStmt:
 		p.PrintTryStmt(n)
 	case *ast.UnaryCallExpr:
 		p.PrintUnaryExpression(n)
+	case *ast.UseStmt:
+		p.PrintUseStmt(n)
 	case *ast.Variable:
 		p.PrintVariable(n)
 	case *ast.WhileStmt:
 		p.PrintWhileStmt(n)
+	case *ast.YieldExpr:
+		p.PrintYieldExpression(n)
 	default:
 		fmt.Fprintf(p.w, `/* Unsupported node type: %T */`, n)
+		if p.err == nil {
+			p.err = fmt.Errorf("printer: unsupported node type %T", n)
+		}
+	}
+}
+
+// PrintFile prints a file, switching in and out of PHP mode around the HTML
+// in the file. A file that ends in PHP mode is left without a closing tag.
+func (p *Printer) PrintFile(f *ast.File) {
+	inPHP := false
+	for _, node := range f.Nodes {
+		if html, ok := inlineHTML(node); ok {
+			if inPHP {
+				io.WriteString(p.w, "?>")
+				inPHP = false
+			}
+			io.WriteString(p.w, html)
+			continue
+		}
+		if !inPHP {
+			io.WriteString(p.w, "<?php\n")
+			inPHP = true
+		}
+		p.printStatement(node)
+	}
+}
+
+// inlineHTML reports whether node is the echo the parser produces for the
+// HTML outside of PHP tags, and returns the HTML. Unlike string literals
+// written in PHP, the HTML literal is not quoted.
+func inlineHTML(node ast.Node) (string, bool) {
+	var echo ast.EchoStmt
+	switch n := node.(type) {
+	case ast.EchoStmt:
+		echo = n
+	case *ast.EchoStmt:
+		echo = *n
+	default:
+		return "", false
+	}
+	if len(echo.Expressions) != 1 {
+		return "", false
+	}
+	var lit ast.Literal
+	switch l := echo.Expressions[0].(type) {
+	case ast.Literal:
+		lit = l
+	case *ast.Literal:
+		lit = *l
+	default:
+		return "", false
+	}
+	if lit.Type != ast.String || lit.Value == "" {
+		return "", false
+	}
+	switch v := strings.ToLower(lit.Value); {
+	case v[0] == '"', v[0] == '\'', strings.HasPrefix(v, "<<<"),
+		strings.HasPrefix(v, `b"`), strings.HasPrefix(v, "b'"):
+		return "", false
+	}
+	return lit.Value, true
+}
+
+// printStatement prints a statement on its own line at the current
+// indentation.
+func (p *Printer) printStatement(s ast.Node) {
+	if _, ok := inlineHTML(s); !ok {
+		p.tab()
+	}
+	p.PrintNode(s)
+	io.WriteString(p.w, "\n")
+}
+
+// printBody prints the statement controlled by a control structure and
+// reports whether it was a block. A statement that is not a block is printed
+// indented on the following line.
+func (p *Printer) printBody(s ast.Statement) bool {
+	switch b := s.(type) {
+	case *ast.Block:
+		io.WriteString(p.w, " ")
+		p.PrintBlock(b)
+		return true
+	case ast.Block:
+		io.WriteString(p.w, " ")
+		p.PrintBlock(&b)
+		return true
+	}
+	io.WriteString(p.w, "\n")
+	p.entab()
+	p.tab()
+	p.PrintNode(s)
+	p.detab()
+	return false
+}
+
+// printAlternateBody prints the statements controlled by a control
+// structure written in the alternative syntax, from the colon following its
+// header. The caller prints the end keyword, such as endwhile, on the
+// following line.
+func (p *Printer) printAlternateBody(s ast.Statement) {
+	io.WriteString(p.w, ":\n")
+	p.entab()
+	switch b := s.(type) {
+	case *ast.Block:
+		p.printStatements(b.Statements)
+	case ast.Block:
+		p.printStatements(b.Statements)
+	default:
+		p.printStatement(s)
+	}
+	p.detab()
+	p.tab()
+}
+
+func (p *Printer) printStatements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		if s != nil {
+			p.printStatement(s)
+		}
+	}
+}
+
+// continueBody starts the clause following the body of a control structure,
+// such as an else, on the same line as a closing brace or on a new line.
+func (p *Printer) continueBody(block bool) {
+	if block {
+		io.WriteString(p.w, " ")
+		return
+	}
+	io.WriteString(p.w, "\n")
+	p.tab()
+}
+
+// atomic is the precedence of an expression that never needs parentheses.
+const atomic = 100
+
+// precedence returns the precedence of an expression's outermost operator,
+// using the same levels as the parser so that printed code parses back to
+// the same tree.
+func precedence(e ast.Node) (level int, rightAssoc bool) {
+	var t token.Token
+	switch n := e.(type) {
+	case ast.BinaryExpr:
+		t = token.TokenMap[strings.ToLower(n.Operator)]
+	case *ast.BinaryExpr:
+		t = token.TokenMap[strings.ToLower(n.Operator)]
+	case ast.AssignmentExpr, *ast.AssignmentExpr, ast.ListStatement, *ast.ListStatement:
+		// the value of an assignment extends over the ternary operator, so an
+		// assignment is parenthesized as an operand of any tighter operator
+		level, _, _ := token.TernaryOperator1.Precedence()
+		return level, true
+	case ast.TernaryCallExpr, *ast.TernaryCallExpr:
+		t = token.TernaryOperator1
+	case ast.PipeExpr, *ast.PipeExpr:
+		t = token.PipeOperator
+	case ast.UnaryCallExpr, *ast.UnaryCallExpr:
+		t = token.UnaryOperator
+	case ast.YieldExpr, *ast.YieldExpr, ast.PrintExpr, *ast.PrintExpr, ast.ThrowExpr, *ast.ThrowExpr,
+		ast.Include, *ast.Include, ast.CloneExpr, *ast.CloneExpr,
+		ast.NewCallExpr, *ast.NewCallExpr:
+		return 0, false
+	default:
+		return atomic, false
+	}
+	level, rightAssoc, ok := t.Precedence()
+	if !ok {
+		return 0, false
+	}
+	return level, rightAssoc
+}
+
+// printOperand prints an operand of an operator at the given level,
+// parenthesizing it if it binds more loosely than the operator. If tight is
+// true, an operand at the same level is also parenthesized.
+func (p *Printer) printOperand(e ast.Node, level int, tight bool) {
+	l, _ := precedence(e)
+	if l < level || (tight && l == level) {
+		io.WriteString(p.w, "(")
+		p.PrintNode(e)
+		io.WriteString(p.w, ")")
+		return
+	}
+	p.PrintNode(e)
+}
+
+// printTrailingOperand prints the last operand of an operator. Expressions
+// introduced by a keyword, such as yield, extend as far to the right as
+// possible, so they need no parentheses there.
+func (p *Printer) printTrailingOperand(e ast.Node, level int, tight bool) {
+	if l, _ := precedence(e); l == 0 {
+		p.PrintNode(e)
+		return
+	}
+	p.printOperand(e, level, tight)
+}
+
+// printReceiver prints the expression on which a property, method, or
+// element is looked up.
+func (p *Printer) printReceiver(e ast.Node) {
+	p.printOperand(e, atomic, false)
+}
+
+func (p *Printer) printList(nodes []ast.Node) {
+	for i, n := range nodes {
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		p.PrintNode(n)
+	}
+}
+
+func (p *Printer) printExprs(exprs []ast.Expr) {
+	for i, e := range exprs {
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		p.PrintNode(e)
+	}
+}
+
+func (p *Printer) printArguments(args []*ast.FunctionArgument) {
+	for i, arg := range args {
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		p.PrintNode(arg)
 	}
 }
 
@@ -158,7 +437,14 @@ func (p *Printer) PrintIdentifier(i *ast.Identifier) {
 
 func (p *Printer) PrintVariable(v *ast.Variable) {
 	io.WriteString(p.w, "$")
-	p.PrintNode(v.Name)
+	switch n := v.Name.(type) {
+	case ast.Identifier, *ast.Identifier, ast.Variable, *ast.Variable:
+		p.PrintNode(n)
+	default:
+		io.WriteString(p.w, "{")
+		p.PrintNode(n)
+		io.WriteString(p.w, "}")
+	}
 }
 
 func (p *Printer) PrintGlobalDeclaration(g *ast.GlobalDeclaration) {
@@ -169,132 +455,273 @@ func (p *Printer) PrintGlobalDeclaration(g *ast.GlobalDeclaration) {
 			io.WriteString(p.w, ", ")
 		}
 	}
+	io.WriteString(p.w, ";")
 }
 
-func (p *Printer) PrintEmptyStatement(e *ast.EmptyStatement) {}
+func (p *Printer) PrintEmptyStatement(e *ast.EmptyStatement) {
+	io.WriteString(p.w, ";")
+}
 
 func (p *Printer) PrintBinaryExpression(b *ast.BinaryExpr) {
-	fmt.Fprintf(p.w, "%s %s %s", b.Antecedent, b.Operator, b.Subsequent)
+	level, rightAssoc := precedence(b)
+	p.printOperand(b.Antecedent, level, rightAssoc)
+	fmt.Fprintf(p.w, " %s ", b.Operator)
+	p.printTrailingOperand(b.Subsequent, level, !rightAssoc)
 }
 
 func (p *Printer) PrintTernaryExpression(t *ast.TernaryCallExpr) {
-	fmt.Fprintf(p.w, "%s ? %s : %s", t.Condition, t.True, t.False)
+	level, _ := precedence(t)
+	// PHP rejects a ternary as the condition of another unless it is
+	// parenthesized, or both are short, as in $a ?: $b ?: $c
+	cond, ok := t.Condition.(*ast.TernaryCallExpr)
+	p.printOperand(t.Condition, level, !ok || !isShortTernary(cond) || !isShortTernary(t))
+	if isShortTernary(t) {
+		io.WriteString(p.w, " ?: ")
+	} else {
+		io.WriteString(p.w, " ? ")
+		p.PrintNode(t.True)
+		io.WriteString(p.w, " : ")
+	}
+	p.printTrailingOperand(t.False, level, true)
 }
+
+// isShortTernary reports whether t is written without its true value, as in
+// $a ?: $b. The parser uses the condition as the true value of a short
+// ternary.
+func isShortTernary(t *ast.TernaryCallExpr) bool {
+	return t.True == nil || reflect.DeepEqual(t.True, t.Condition)
+}
+
 func (p *Printer) PrintUnaryExpression(u *ast.UnaryCallExpr) {
-	if u.Preceding {
-		fmt.Fprintf(p.w, "%s%s", u.Operator, u.Operand)
+	level, _ := precedence(u)
+	if !u.Preceding {
+		p.printOperand(u.Operand, level, false)
+		io.WriteString(p.w, u.Operator)
+		return
+	}
+	io.WriteString(p.w, u.Operator)
+	if strings.HasSuffix(u.Operator, ")") {
+		// casts
+		io.WriteString(p.w, " ")
+	}
+	// keep - -$x from printing as a decrement
+	inner, ok := u.Operand.(ast.UnaryCallExpr)
+	tight := ok && inner.Preceding && (u.Operator == "-" || u.Operator == "+") && strings.HasPrefix(inner.Operator, u.Operator)
+	p.printTrailingOperand(u.Operand, level, tight)
+}
+
+func (p *Printer) PrintPipeExpression(e *ast.PipeExpr) {
+	level, _ := precedence(e)
+	p.printOperand(e.Value, level, false)
+	io.WriteString(p.w, " |> ")
+	p.printTrailingOperand(e.Callable, level, true)
+}
+
+func (p *Printer) PrintNamedArgument(a *ast.NamedArgument) {
+	fmt.Fprintf(p.w, "%s: ", a.Name)
+	p.PrintNode(a.Value)
+}
+
+func (p *Printer) PrintCloneExpression(c *ast.CloneExpr) {
+	io.WriteString(p.w, "clone ")
+	p.PrintNode(c.Expr)
+}
+
+func (p *Printer) PrintPrintExpression(e *ast.PrintExpr) {
+	io.WriteString(p.w, "print ")
+	p.PrintNode(e.Expr)
+}
+
+func (p *Printer) PrintThrowExpression(t *ast.ThrowExpr) {
+	io.WriteString(p.w, "throw ")
+	p.PrintNode(t.Expr)
+}
+
+func (p *Printer) PrintYieldExpression(y *ast.YieldExpr) {
+	io.WriteString(p.w, "yield")
+	if y.From {
+		io.WriteString(p.w, " from")
+	}
+	if y.Key != nil {
+		io.WriteString(p.w, " ")
+		p.PrintNode(y.Key)
+		io.WriteString(p.w, " =>")
+	}
+	if y.Value != nil {
+		io.WriteString(p.w, " ")
+		p.PrintNode(y.Value)
 	}
-	fmt.Fprintf(p.w, "%s%s", u.Operand, u.Operator)
 }
 
 func (p *Printer) PrintEchoStmt(e *ast.EchoStmt) {
-	io.WriteString(p.w, "echo ")
-	for i, expr := range e.Expressions {
-		if i > 0 {
-			io.WriteString(p.w, ", ")
-		}
-		p.PrintNode(expr)
+	if html, ok := inlineHTML(e); ok {
+		fmt.Fprintf(p.w, "?>%s<?php", html)
+		return
 	}
+	io.WriteString(p.w, "echo ")
+	p.printExprs(e.Expressions)
 	io.WriteString(p.w, ";")
 }
 
 func (p *Printer) PrintReturnStmt(r *ast.ReturnStmt) {
-	io.WriteString(p.w, "return ")
+	io.WriteString(p.w, "return")
 	if r.Expr != nil {
+		io.WriteString(p.w, " ")
 		p.PrintNode(r.Expr)
 	}
 	io.WriteString(p.w, ";")
 }
+
 func (p *Printer) PrintBreakStmt(b *ast.BreakStmt) {
 	io.WriteString(p.w, "break")
 	if b.Expr != nil {
+		io.WriteString(p.w, " ")
 		p.PrintNode(b.Expr)
 	}
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintContinueStmt(b *ast.ContinueStmt) {
 	io.WriteString(p.w, "continue")
 	if b.Expr != nil {
+		io.WriteString(p.w, " ")
 		p.PrintNode(b.Expr)
 	}
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintThrowStmt(b *ast.ThrowStmt) {
 	io.WriteString(p.w, "throw")
 	if b.Expr != nil {
+		io.WriteString(p.w, " ")
 		p.PrintNode(b.Expr)
 	}
 	io.WriteString(p.w, ";")
+}
 
+func (p *Printer) PrintGotoStmt(g *ast.GotoStmt) {
+	fmt.Fprintf(p.w, "goto %s;", g.Label)
 }
+
+func (p *Printer) PrintLabelStmt(l *ast.LabelStmt) {
+	fmt.Fprintf(p.w, "%s:", l.Name)
+}
+
 func (p *Printer) PrintInclude(e *ast.Include) {
-	io.WriteString(p.w, "include ")
-	for i, expr := range e.Expressions {
+	if e.Keyword == "" {
+		io.WriteString(p.w, "include ")
+	} else {
+		fmt.Fprintf(p.w, "%s ", e.Keyword)
+	}
+	p.printExprs(e.Expressions)
+}
+
+func (p *Printer) PrintNamespaceStmt(n *ast.NamespaceStmt) {
+	if n.Block == nil {
+		fmt.Fprintf(p.w, "namespace %s;", n.Name)
+		return
+	}
+	io.WriteString(p.w, "namespace ")
+	if n.Name != "" {
+		fmt.Fprintf(p.w, "%s ", n.Name)
+	}
+	p.PrintBlock(n.Block)
+}
+
+func (p *Printer) PrintUseStmt(u *ast.UseStmt) {
+	io.WriteString(p.w, "use ")
+	if u.Kind != "" {
+		fmt.Fprintf(p.w, "%s ", u.Kind)
+	}
+	if u.Prefix != "" {
+		fmt.Fprintf(p.w, "%s{", u.Prefix)
+	}
+	for i, imp := range u.Imports {
 		if i > 0 {
 			io.WriteString(p.w, ", ")
 		}
-		p.PrintNode(expr)
+		if imp.Kind != "" {
+			fmt.Fprintf(p.w, "%s ", imp.Kind)
+		}
+		io.WriteString(p.w, imp.Name)
+		if imp.Alias != "" {
+			fmt.Fprintf(p.w, " as %s", imp.Alias)
+		}
+	}
+	if u.Prefix != "" {
+		io.WriteString(p.w, "}")
 	}
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintExitStmt(b *ast.ExitStmt) {
 	io.WriteString(p.w, "exit")
 	if b.Expr != nil {
+		io.WriteString(p.w, "(")
 		p.PrintNode(b.Expr)
+		io.WriteString(p.w, ")")
 	}
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintNewExpression(b *ast.NewCallExpr) {
 	io.WriteString(p.w, "new ")
-	p.PrintNode(b.Class)
-	io.WriteString(p.w, "(")
-	for i, arg := range b.Arguments {
-		if i > 0 {
-			io.WriteString(p.w, ",")
-		}
-		p.PrintNode(arg)
+	if c, ok := b.Class.(*ast.AnonymousClass); ok {
+		p.PrintAnonymousClass(c, b.Arguments)
+		return
+	}
+	switch b.Class.(type) {
+	case *ast.Identifier, *ast.Variable, *ast.PropertyCallExpr, *ast.ArrayLookupExpr, *ast.ClassExpr:
+		p.printReceiver(b.Class)
+	default:
+		// a class named by any other expression, as in new (getClass())
+		io.WriteString(p.w, "(")
+		p.PrintNode(b.Class)
+		io.WriteString(p.w, ")")
+	}
+	if b.Arguments != nil {
+		io.WriteString(p.w, "(")
+		p.printExprs(b.Arguments)
+		io.WriteString(p.w, ")")
 	}
-	io.WriteString(p.w, ")")
-
 }
+
 func (p *Printer) PrintAssignmentExpression(a *ast.AssignmentExpr) {
-	p.PrintNode(a.Assignee)
-	io.WriteString(p.w, " ")
-	io.WriteString(p.w, a.Operator)
-	io.WriteString(p.w, " ")
-	p.PrintNode(a.Value)
+	level, _ := precedence(a)
+	if pattern, ok := a.Assignee.(*ast.ArrayExpr); ok {
+		p.printNestedList(pattern, true)
+	} else {
+		p.printOperand(a.Assignee, level, true)
+	}
+	fmt.Fprintf(p.w, " %s ", a.Operator)
+	p.printTrailingOperand(a.Value, level, false)
 }
 
 func (p *Printer) PrintFunctionCallStmt(f *ast.FunctionCallStmt) {
-	p.PrintNode(f.FunctionCallExpr)
+	p.PrintNode(&f.FunctionCallExpr)
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintFunctionCallExpression(f *ast.FunctionCallExpr) {
-	p.PrintNode(f.FunctionName)
-	io.WriteString(p.w, "(")
-	for i, arg := range f.Arguments {
-		if i > 0 {
-			io.WriteString(p.w, ",")
-		}
-		p.PrintNode(arg)
+	p.printReceiver(f.FunctionName)
+	p.printCallArguments(f)
+}
+
+// printCallArguments prints the parenthesized arguments of a call.
+func (p *Printer) printCallArguments(f *ast.FunctionCallExpr) {
+	if f.FirstClassCallable {
+		io.WriteString(p.w, "(...)")
+		return
 	}
+	io.WriteString(p.w, "(")
+	p.printExprs(f.Arguments)
 	io.WriteString(p.w, ")")
-
 }
 
 func (p *Printer) PrintBlock(b *ast.Block) {
 	io.WriteString(p.w, "{\n")
 	p.entab()
-	for _, s := range b.Statements {
-		p.tab()
-		p.PrintNode(s)
-		io.WriteString(p.w, "\n")
-	}
+	p.printStatements(b.Statements)
 	p.detab()
 	p.tab()
 	io.WriteString(p.w, "}")
@@ -302,259 +729,459 @@ func (p *Printer) PrintBlock(b *ast.Block) {
 
 func (p *Printer) PrintFunctionStmt(f *ast.FunctionStmt) {
 	p.PrintNode(f.FunctionDefinition)
+	if f.Body == nil {
+		io.WriteString(p.w, ";")
+		return
+	}
+	io.WriteString(p.w, " ")
 	p.PrintNode(f.Body)
 }
+
 func (p *Printer) PrintAnonymousFunction(a *ast.AnonymousFunction) {
-	io.WriteString(p.w, "function (")
-	for i, arg := range a.Arguments {
-		if i > 0 {
-			io.WriteString(p.w, ",")
-		}
-		p.PrintNode(arg)
+	if a.Static {
+		io.WriteString(p.w, "static ")
 	}
-	io.WriteString(p.w, ") ")
+	io.WriteString(p.w, "function ")
+	if a.ByRef {
+		io.WriteString(p.w, "&")
+	}
+	io.WriteString(p.w, "(")
+	p.printArguments(a.Arguments)
+	io.WriteString(p.w, ")")
 	if len(a.ClosureVariables) > 0 {
-		fmt.Fprint(p.w, "use (")
-		for i, arg := range a.ClosureVariables {
-			if i > 0 {
-				io.WriteString(p.w, ",")
-			}
-			p.PrintNode(arg)
-		}
-		io.WriteString(p.w, ") ")
+		io.WriteString(p.w, " use (")
+		p.printArguments(a.ClosureVariables)
+		io.WriteString(p.w, ")")
+	}
+	if a.ReturnType != "" {
+		fmt.Fprintf(p.w, ": %s", a.ReturnType)
 	}
+	io.WriteString(p.w, " ")
 	p.PrintNode(a.Body)
 }
 
 func (p *Printer) PrintFunctionDefinition(fd *ast.FunctionDefinition) {
 	io.WriteString(p.w, "function ")
+	if fd.ByRef {
+		io.WriteString(p.w, "&")
+	}
 	io.WriteString(p.w, fd.Name)
 	io.WriteString(p.w, "(")
-	for i, arg := range fd.Arguments {
-		p.PrintNode(arg)
-		if i+1 < len(fd.Arguments) {
-			io.WriteString(p.w, ",")
-		}
+	p.printArguments(fd.Arguments)
+	io.WriteString(p.w, ")")
+	if fd.ReturnType != "" {
+		fmt.Fprintf(p.w, ": %s", fd.ReturnType)
 	}
-	io.WriteString(p.w, ") ")
-
 }
+
 func (p *Printer) PrintFunctionArgument(fa *ast.FunctionArgument) {
-	buf := &bytes.Buffer{}
+	if fa.Promoted {
+		p.PrintVisibility(fa.Visibility)
+		io.WriteString(p.w, " ")
+		if fa.Readonly {
+			io.WriteString(p.w, "readonly ")
+		}
+	}
 	if fa.TypeHint != "" {
-		fmt.Fprint(buf, fa.TypeHint, "")
+		fmt.Fprintf(p.w, "%s ", fa.TypeHint)
+	}
+	if fa.ByRef {
+		io.WriteString(p.w, "&")
+	}
+	if fa.Variadic {
+		io.WriteString(p.w, "...")
 	}
 	p.PrintNode(fa.Variable)
 	if fa.Default != nil {
-		io.WriteString(p.w, " =")
+		io.WriteString(p.w, " = ")
 		p.PrintNode(fa.Default)
 	}
-
 }
+
 func (p *Printer) PrintClass(c *ast.Class) {
+	for _, m := range c.Methods {
+		// a class that declares abstract methods must itself be abstract
+		if m.Abstract {
+			io.WriteString(p.w, "abstract ")
+			break
+		}
+	}
+	if c.Final {
+		io.WriteString(p.w, "final ")
+	}
+	if c.Readonly {
+		io.WriteString(p.w, "readonly ")
+	}
 	io.WriteString(p.w, "class ")
 	io.WriteString(p.w, c.Name)
+	p.printClassHeritage(c)
+	io.WriteString(p.w, " ")
+	p.printClassBody(c)
+}
+
+func (p *Printer) PrintTrait(t *ast.Trait) {
+	io.WriteString(p.w, "trait ")
+	io.WriteString(p.w, t.Name)
+	io.WriteString(p.w, " ")
+	p.printClassBody(t.Class)
+}
+
+// PrintAnonymousClass prints the class of a new class expression following
+// the new keyword, with the arguments passed to its constructor.
+func (p *Printer) PrintAnonymousClass(a *ast.AnonymousClass, args []ast.Expr) {
+	io.WriteString(p.w, "class")
+	if args != nil {
+		io.WriteString(p.w, "(")
+		p.printExprs(args)
+		io.WriteString(p.w, ")")
+	}
+	p.printClassHeritage(a.Class)
+	io.WriteString(p.w, " ")
+	p.printClassBody(a.Class)
+}
+
+func (p *Printer) printClassHeritage(c *ast.Class) {
 	if c.Extends != "" {
 		fmt.Fprintf(p.w, " extends %s", c.Extends)
 	}
-	for i, imp := range c.Implements {
-		if i > 0 {
-			io.WriteString(p.w, ",")
-		} else {
-			io.WriteString(p.w, "implements ")
-		}
-		io.WriteString(p.w, imp)
+	if len(c.Implements) > 0 {
+		fmt.Fprintf(p.w, " implements %s", strings.Join(c.Implements, ", "))
 	}
-	io.WriteString(p.w, " {\n")
+}
+
+func (p *Printer) printClassBody(c *ast.Class) {
+	io.WriteString(p.w, "{\n")
 	p.entab()
+	if len(c.Traits) > 0 {
+		p.tab()
+		fmt.Fprintf(p.w, "use %s;\n", strings.Join(c.Traits, ", "))
+	}
 	for _, c := range c.Constants {
 		p.tab()
-		p.PrintNode(c)
-		io.WriteString(p.w, "\n")
+		p.printConstantDeclaration(c)
+		io.WriteString(p.w, ";\n")
 	}
+	promoted := promotedProperties(c)
 	for _, pr := range c.Properties {
-		p.tab()
-		p.PrintNode(pr)
-		io.WriteString(p.w, "\n")
+		if !promoted[pr.Name] {
+			p.printStatement(pr)
+		}
 	}
-	for _, m := range c.Methods {
-		p.tab()
-		p.PrintNode(m)
-		io.WriteString(p.w, "\n")
+	for i, m := range c.Methods {
+		if i > 0 || len(c.Traits)+len(c.Constants)+len(c.Properties) > 0 {
+			io.WriteString(p.w, "\n")
+		}
+		p.printStatement(m)
 	}
 	p.detab()
 	p.tab()
 	io.WriteString(p.w, "}")
+}
 
+// promotedProperties returns the names of the properties of c that are
+// declared by promoting the arguments of its constructor. They are printed
+// with the constructor.
+func promotedProperties(c *ast.Class) map[string]bool {
+	promoted := map[string]bool{}
+	for _, m := range c.Methods {
+		if !strings.EqualFold(m.Name, "__construct") {
+			continue
+		}
+		for _, arg := range m.Arguments {
+			if arg.Promoted {
+				promoted[arg.Variable.String()] = true
+			}
+		}
+	}
+	return promoted
 }
 
 func (p *Printer) PrintInterface(i *ast.Interface) {
 	io.WriteString(p.w, "interface ")
 	io.WriteString(p.w, i.Name)
-
-	for i, imp := range i.Inherits {
-		if i > 0 {
-			io.WriteString(p.w, ", ")
-		} else {
-			io.WriteString(p.w, "implements ")
-		}
-		io.WriteString(p.w, imp)
+	if len(i.Inherits) > 0 {
+		fmt.Fprintf(p.w, " extends %s", strings.Join(i.Inherits, ", "))
 	}
-
-	io.WriteString(p.w, " {")
+	io.WriteString(p.w, " {\n")
+	p.entab()
 	for _, c := range i.Constants {
-		p.PrintNode(c)
+		// interface constants are always public
+		p.tab()
+		io.WriteString(p.w, "const ")
+		p.PrintConstant(&c)
+		io.WriteString(p.w, ";\n")
 	}
-
 	for _, m := range i.Methods {
-		p.PrintNode(m)
+		p.printStatement(m)
 	}
-
+	p.detab()
+	p.tab()
 	io.WriteString(p.w, "}")
-
 }
 
 func (p *Printer) PrintProperty(pr *ast.Property) {
-	buf := &bytes.Buffer{}
 	p.PrintVisibility(pr.Visibility)
-	fmt.Fprintf(buf, " %s", pr.Name)
+	if pr.Static {
+		io.WriteString(p.w, " static")
+	}
+	if pr.Readonly {
+		io.WriteString(p.w, " readonly")
+	}
+	if pr.TypeHint != "" {
+		fmt.Fprintf(p.w, " %s", pr.TypeHint)
+	}
+	fmt.Fprintf(p.w, " %s", pr.Name)
 	if pr.Initialization != nil {
+		io.WriteString(p.w, " = ")
 		p.PrintNode(pr.Initialization)
 	}
 	io.WriteString(p.w, ";")
-
 }
+
 func (p *Printer) PrintPropertyExpression(pr *ast.PropertyCallExpr) {
-	p.PrintNode(pr.Receiver)
+	p.printReceiver(pr.Receiver)
+	p.printObjectOperator(pr.Nullsafe)
+	p.printMemberName(pr.Name)
+}
+
+func (p *Printer) printObjectOperator(nullsafe bool) {
+	if nullsafe {
+		io.WriteString(p.w, "?->")
+		return
+	}
 	io.WriteString(p.w, "->")
-	p.PrintNode(pr.Name)
+}
+
+// printMemberName prints the name of a property or method, which is either
+// an identifier, a variable, or an expression in braces.
+func (p *Printer) printMemberName(name ast.Node) {
+	switch name.(type) {
+	case ast.Identifier, *ast.Identifier, ast.Variable, *ast.Variable:
+		p.PrintNode(name)
+	default:
+		io.WriteString(p.w, "{")
+		p.PrintNode(name)
+		io.WriteString(p.w, "}")
+	}
 }
 
 func (p *Printer) PrintClassExpression(c *ast.ClassExpr) {
-	p.PrintNode(c.Receiver)
+	p.printReceiver(c.Receiver)
 	io.WriteString(p.w, "::")
 	p.PrintNode(c.Expr)
 }
+
 func (p *Printer) PrintMethod(m *ast.Method) {
+	if m.Abstract {
+		io.WriteString(p.w, "abstract ")
+	}
+	if m.Final {
+		io.WriteString(p.w, "final ")
+	}
 	p.PrintVisibility(m.Visibility)
+	if m.Static {
+		io.WriteString(p.w, " static")
+	}
 	io.WriteString(p.w, " ")
 	p.PrintNode(m.FunctionStmt)
 }
+
 func (p *Printer) PrintMethodCallExpression(m *ast.MethodCallExpr) {
-	p.PrintNode(m.Receiver)
-	io.WriteString(p.w, "->")
-	p.PrintNode(m.FunctionCallExpr)
+	p.printReceiver(m.Receiver)
+	p.printObjectOperator(m.Nullsafe)
+	p.printMemberName(m.FunctionName)
+	p.printCallArguments(m.FunctionCallExpr)
 }
-func (p *Printer) PrintIfStmt(i *ast.IfStmt) {
-	if len(i.Branches) == 0 {
-		return
-	}
 
-	fmt.Fprintf(p.w, "if (%s) {\n%s\n}", i.Branches[0].Condition, i.Branches[0].Block)
-	for _, branch := range i.Branches[1:] {
-		fmt.Fprintf(p.w, " else if (%s) {\n%s\n}", branch.Condition, branch.Block)
+func (p *Printer) PrintIfStmt(i *ast.IfStmt) {
+	for n, branch := range i.Branches {
+		if n > 0 {
+			io.WriteString(p.w, "elseif")
+		} else {
+			io.WriteString(p.w, "if")
+		}
+		io.WriteString(p.w, " (")
+		p.PrintNode(branch.Condition)
+		io.WriteString(p.w, ")")
+		if i.Alternate {
+			p.printAlternateBody(branch.Block)
+			continue
+		}
+		block := p.printBody(branch.Block)
+		if n+1 < len(i.Branches) || i.ElseBlock != nil {
+			p.continueBody(block)
+		}
 	}
 	if i.ElseBlock != nil {
-		fmt.Fprintf(p.w, " else {\n%s\n}", i.ElseBlock)
+		io.WriteString(p.w, "else")
+		if i.Alternate {
+			p.printAlternateBody(i.ElseBlock)
+		} else {
+			p.printBody(i.ElseBlock)
+		}
+	}
+	if i.Alternate {
+		io.WriteString(p.w, "endif;")
 	}
 }
 
+// PrintMatchExpression prints a match expression with each arm on its own
+// line.
+func (p *Printer) PrintMatchExpression(m *ast.MatchExpr) {
+	io.WriteString(p.w, "match (")
+	p.PrintNode(m.Subject)
+	io.WriteString(p.w, ") {\n")
+	p.entab()
+	for _, arm := range m.Arms {
+		p.tab()
+		if arm.Conditions == nil {
+			io.WriteString(p.w, "default")
+		} else {
+			p.printExprs(arm.Conditions)
+		}
+		io.WriteString(p.w, " => ")
+		p.PrintNode(arm.Body)
+		io.WriteString(p.w, ",\n")
+	}
+	p.detab()
+	p.tab()
+	io.WriteString(p.w, "}")
+}
+
 func (p *Printer) PrintSwitchStmt(s *ast.SwitchStmt) {
-	fmt.Fprintf(p.w, "switch (%s) {\n", s.Expr)
+	io.WriteString(p.w, "switch (")
+	p.PrintNode(s.Expr)
+	if s.Alternate {
+		io.WriteString(p.w, "):\n")
+	} else {
+		io.WriteString(p.w, ") {\n")
+	}
+	p.entab()
 	for _, c := range s.Cases {
-		p.PrintNode(c)
-		io.WriteString(p.w, "\n")
+		p.tab()
+		p.PrintSwitchCase(c)
 	}
 	if s.DefaultCase != nil {
-		fmt.Fprintf(p.w, "default:\n")
-		p.PrintNode(s.DefaultCase)
+		p.tab()
+		io.WriteString(p.w, "default:\n")
+		p.printSwitchBlock(s.DefaultCase)
+	}
+	p.detab()
+	p.tab()
+	if s.Alternate {
+		io.WriteString(p.w, "endswitch;")
+	} else {
+		io.WriteString(p.w, "}")
 	}
-	io.WriteString(p.w, "}")
 }
 
+// PrintSwitchCase prints a case followed by its statements, each on its own
+// line.
 func (p *Printer) PrintSwitchCase(s *ast.SwitchCase) {
 	io.WriteString(p.w, "case ")
 	p.PrintNode(s.Expr)
 	io.WriteString(p.w, ":\n")
-	p.PrintNode(s.Block)
-	io.WriteString(p.w, "\n")
+	p.printSwitchBlock(&s.Block)
+}
+
+// printSwitchBlock prints the statements of a case one level deeper than
+// the case.
+func (p *Printer) printSwitchBlock(b *ast.Block) {
+	p.entab()
+	p.printStatements(b.Statements)
+	p.detab()
 }
+
 func (p *Printer) PrintForStmt(f *ast.ForStmt) {
-	fmt.Fprintf(p.w, "for (")
-	for i, e := range f.Initialization {
-		if i > 0 {
-			io.WriteString(p.w, ", ")
-		}
-		p.PrintNode(e)
-	}
-	for i, e := range f.Termination {
-		if i > 0 {
-			io.WriteString(p.w, ", ")
-		}
-		p.PrintNode(e)
-	}
-	for i, e := range f.Iteration {
-		if i > 0 {
-			io.WriteString(p.w, ", ")
+	io.WriteString(p.w, "for (")
+	for clause, exprs := range [][]ast.Expr{f.Initialization, f.Termination, f.Iteration} {
+		if clause > 0 {
+			io.WriteString(p.w, ";")
+			if len(exprs) > 0 {
+				io.WriteString(p.w, " ")
+			}
 		}
-		p.PrintNode(e)
+		p.printExprs(exprs)
 	}
-	io.WriteString(p.w, ") ")
-	p.PrintNode(f.LoopBlock)
+	io.WriteString(p.w, ")")
+	p.printLoopBody(f.LoopBlock, f.Alternate, "endfor;")
+}
 
+// printLoopBody prints the statements controlled by a loop, followed by end
+// if the loop is written in the alternative syntax.
+func (p *Printer) printLoopBody(s ast.Statement, alternate bool, end string) {
+	if !alternate {
+		p.printBody(s)
+		return
+	}
+	p.printAlternateBody(s)
+	io.WriteString(p.w, end)
 }
+
 func (p *Printer) PrintWhileStmt(wh *ast.WhileStmt) {
-	fmt.Fprintf(p.w, "while (%s) %s", wh.Termination, wh.LoopBlock)
+	io.WriteString(p.w, "while (")
+	p.PrintNode(wh.Termination)
+	io.WriteString(p.w, ")")
+	p.printLoopBody(wh.LoopBlock, wh.Alternate, "endwhile;")
 }
+
 func (p *Printer) PrintDoWhileStmt(wh *ast.DoWhileStmt) {
-	fmt.Fprintf(p.w, "do %s while (%s);", wh.LoopBlock, wh.Termination)
+	io.WriteString(p.w, "do")
+	p.continueBody(p.printBody(wh.LoopBlock))
+	io.WriteString(p.w, "while (")
+	p.PrintNode(wh.Termination)
+	io.WriteString(p.w, ");")
 }
+
 func (p *Printer) PrintTryStmt(t *ast.TryStmt) {
-	fmt.Fprintf(p.w, "try ")
+	io.WriteString(p.w, "try ")
 	p.PrintNode(t.TryBlock)
 	for _, c := range t.CatchStmts {
+		io.WriteString(p.w, " ")
 		p.PrintNode(c)
 	}
 	if t.FinallyBlock != nil {
-		fmt.Fprintf(p.w, "finally ")
+		io.WriteString(p.w, " finally ")
 		p.PrintNode(t.FinallyBlock)
 	}
-
 }
+
 func (p *Printer) PrintCatchStmt(c *ast.CatchStmt) {
-	fmt.Fprintf(p.w, "catch (%s ", c.CatchType)
-	p.PrintNode(c.CatchVar)
+	fmt.Fprintf(p.w, "catch (%s", c.CatchType)
+	if c.CatchVar != nil {
+		io.WriteString(p.w, " ")
+		p.PrintNode(c.CatchVar)
+	}
 	io.WriteString(p.w, ") ")
 	p.PrintNode(c.CatchBlock)
 }
 
 func (p *Printer) PrintLiteral(l *ast.Literal) {
-	switch l.Type {
-	case ast.String:
-		io.WriteString(p.w, l.Value)
-	case ast.Integer, ast.Float:
-		io.WriteString(p.w, l.Value)
-	case ast.Boolean:
-		io.WriteString(p.w, l.Value)
-	case ast.Null:
+	if l.Type == ast.Null && l.Value == "" {
 		io.WriteString(p.w, "null")
-	default:
-		io.WriteString(p.w, l.Value)
+		return
 	}
+	io.WriteString(p.w, l.Value)
 }
 
 func (p *Printer) PrintForeachStmt(f *ast.ForeachStmt) {
-	fmt.Fprintf(p.w, "foreach (%s as ", f.Source)
+	io.WriteString(p.w, "foreach (")
+	p.PrintNode(f.Source)
+	io.WriteString(p.w, " as ")
 	if f.Key != nil {
-		fmt.Fprintf(p.w, "%s => ", f.Key)
+		p.PrintNode(f.Key)
+		io.WriteString(p.w, " => ")
+	}
+	if f.ByRef {
+		io.WriteString(p.w, "&")
 	}
 	p.PrintNode(f.Value)
-	io.WriteString(p.w, ") ")
-	p.PrintNode(f.LoopBlock)
+	io.WriteString(p.w, ")")
+	p.printLoopBody(f.LoopBlock, f.Alternate, "endforeach;")
 }
 
 func (p *Printer) PrintArrayExpression(a *ast.ArrayExpr) {
-	fmt.Fprintf(p.w, "array(")
+	io.WriteString(p.w, "array(")
 	for i, pair := range a.Pairs {
 		if i > 0 {
 			io.WriteString(p.w, ", ")
@@ -567,21 +1194,21 @@ func (p *Printer) PrintArrayExpression(a *ast.ArrayExpr) {
 func (p *Printer) PrintArrayPair(pr *ast.ArrayPair) {
 	if pr.Key != nil {
 		p.PrintNode(pr.Key)
-		fmt.Fprintf(p.w, " => ")
-		p.PrintNode(pr.Value)
+		io.WriteString(p.w, " => ")
 	}
 	p.PrintNode(pr.Value)
 }
 
 func (p *Printer) PrintArrayLookupExpression(a *ast.ArrayLookupExpr) {
-	p.PrintNode(a.Array)
+	p.printReceiver(a.Array)
 	io.WriteString(p.w, "[")
 	p.PrintNode(a.Index)
 	io.WriteString(p.w, "]")
 }
 
 func (p *Printer) PrintArrayAppendExpression(a *ast.ArrayAppendExpr) {
-	fmt.Fprintf(p.w, "%s[]", a.Array)
+	p.printReceiver(a.Array)
+	io.WriteString(p.w, "[]")
 }
 
 func (p *Printer) PrintShellCommand(s *ast.ShellCommand) {
@@ -589,19 +1216,62 @@ func (p *Printer) PrintShellCommand(s *ast.ShellCommand) {
 }
 
 func (p *Printer) PrintListStatement(l *ast.ListStatement) {
-	fmt.Fprintf(p.w, "list(")
-	for i, a := range l.Assignees {
+	p.printListAssignees(l.Assignees, l.Keys, false)
+	fmt.Fprintf(p.w, " %s ", l.Operator)
+	p.PrintNode(l.Value)
+}
+
+// printListAssignees prints a list() destructuring pattern or, if short is
+// true, a [] pattern. Skipped elements are nil.
+func (p *Printer) printListAssignees(assignees []ast.Assignable, keys []ast.Expr, short bool) {
+	open, close := "list(", ")"
+	if short {
+		open, close = "[", "]"
+	}
+	io.WriteString(p.w, open)
+	for i, a := range assignees {
 		if i > 0 {
 			io.WriteString(p.w, ", ")
 		}
-		p.PrintNode(a)
+		if keys != nil {
+			p.PrintNode(keys[i])
+			io.WriteString(p.w, " => ")
+		}
+		if nested, ok := a.(*ast.ArrayExpr); ok {
+			p.printNestedList(nested, short)
+			continue
+		}
+		if a != nil {
+			p.PrintNode(a)
+		}
 	}
-	io.WriteString(p.w, ") =")
-	p.PrintNode(l.Value)
+	if n := len(assignees); n > 0 && assignees[n-1] == nil {
+		// keep a skipped last element from reading as a trailing comma
+		io.WriteString(p.w, ",")
+	}
+	io.WriteString(p.w, close)
+}
+
+// printNestedList prints a destructuring pattern that the parser represents
+// as an array of its assignees, as it does a [] pattern or a pattern nested
+// within another.
+func (p *Printer) printNestedList(a *ast.ArrayExpr, short bool) {
+	assignees := make([]ast.Assignable, len(a.Pairs))
+	var keys []ast.Expr
+	for i, pair := range a.Pairs {
+		assignees[i], _ = pair.Value.(ast.Assignable)
+		if pair.Key != nil {
+			if keys == nil {
+				keys = make([]ast.Expr, len(a.Pairs))
+			}
+			keys[i] = pair.Key
+		}
+	}
+	p.printListAssignees(assignees, keys, short)
 }
 
 func (p *Printer) PrintStaticVariableDeclaration(s *ast.StaticVariableDeclaration) {
-	fmt.Fprintf(p.w, "static ")
+	io.WriteString(p.w, "static ")
 	for i, d := range s.Declarations {
 		if i > 0 {
 			io.WriteString(p.w, ", ")
@@ -610,21 +1280,53 @@ func (p *Printer) PrintStaticVariableDeclaration(s *ast.StaticVariableDeclaratio
 	}
 	io.WriteString(p.w, ";")
 }
+
 func (p *Printer) PrintDeclareBlock(d *ast.DeclareBlock) {
-	io.WriteString(p.w, "declare (")
-	for i, decl := range d.Declarations {
-		if i > 0 {
-			io.WriteString(p.w, ",")
-		}
-		io.WriteString(p.w, decl)
+	fmt.Fprintf(p.w, "declare(%s)", strings.Join(d.Declarations, ", "))
+	if d.Statements == nil {
+		io.WriteString(p.w, ";")
+		return
+	}
+	if d.Alternate {
+		p.printAlternateBody(d.Statements)
+		io.WriteString(p.w, "enddeclare;")
+		return
 	}
-	io.WriteString(p.w, ") {")
+	io.WriteString(p.w, " ")
 	p.PrintNode(d.Statements)
-	io.WriteString(p.w, "}")
 }
 
+// PrintConstant prints the declaration of a constant, without the const
+// keyword shared by the constants declared together.
 func (p *Printer) PrintConstant(c *ast.Constant) {
+	if c.TypeHint != "" {
+		fmt.Fprintf(p.w, "%s ", c.TypeHint)
+	}
 	io.WriteString(p.w, c.Name)
+	if v, ok := c.Value.(ast.Node); ok {
+		io.WriteString(p.w, " = ")
+		p.PrintNode(v)
+	}
+}
+
+func (p *Printer) printConstantDeclaration(c *ast.Constant) {
+	if c.Visibility != ast.Public {
+		p.PrintVisibility(c.Visibility)
+		io.WriteString(p.w, " ")
+	}
+	io.WriteString(p.w, "const ")
+	p.PrintConstant(c)
+}
+
+func (p *Printer) PrintConstantStmt(c *ast.ConstantStmt) {
+	io.WriteString(p.w, "const ")
+	for i, constant := range c.Constants {
+		if i > 0 {
+			io.WriteString(p.w, ", ")
+		}
+		p.PrintConstant(constant)
+	}
+	io.WriteString(p.w, ";")
 }
 
 func (p *Printer) PrintConstantExpression(c *ast.ConstantExpr) {
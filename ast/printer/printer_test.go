@@ -1,19 +1,24 @@
 package printer
 
 import (
-	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stephens2424/php/lexer"
 	"github.com/stephens2424/php/parser"
+	"github.com/stephens2424/php/token"
 )
 
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
 type Test struct {
 	Before, After string
 }
 
 func TestPrinter(t *testing.T) {
-	t.SkipNow() // disabling this test before some ast restructuring
-
 	for _, test := range tests {
 		p := parser.NewParser()
 		file, err := p.Parse("test.php", test.Before)
@@ -26,12 +31,14 @@ func TestPrinter(t *testing.T) {
 			continue
 		}
 
-		buf := &bytes.Buffer{}
-		pr := NewPrinter(buf)
-		pr.PrintNode(file.Nodes[0])
+		formatted, err := Unparse(file)
+		if err != nil {
+			t.Error("printing error:", err)
+			continue
+		}
 
-		if buf.String() != test.After {
-			t.Fatalf("formatted text did not match\nFormatted\n\n%s\n\nExpected\n\n%s\n", buf.String(), test.After)
+		if formatted != test.After {
+			t.Fatalf("formatted text did not match\nFormatted\n\n%s\n\nExpected\n\n%s\n", formatted, test.After)
 		}
 	}
 }
@@ -48,3 +55,76 @@ $var = "x";
 `,
 	},
 }
+
+// TestGolden prints each program in testdata and compares the result with
+// the .golden file of the same name. The printed program must lex to the
+// same tokens as the original, and print unchanged when parsed again.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		src, err := ioutil.ReadFile(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := unparseSource(string(src))
+		if err != nil {
+			t.Errorf("%s: %s", input, err)
+			continue
+		}
+
+		golden := strings.TrimSuffix(input, ".php") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(formatted), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatted != string(expected) {
+			t.Errorf("%s: formatted text did not match\nFormatted\n\n%s\n\nExpected\n\n%s\n", input, formatted, expected)
+			continue
+		}
+
+		found, expectedTokens := significantTokens(formatted), significantTokens(string(src))
+		if len(found) != len(expectedTokens) {
+			t.Errorf("%s: formatted text has %d tokens, expected %d", input, len(found), len(expectedTokens))
+			continue
+		}
+		for i := range found {
+			if found[i].Typ != expectedTokens[i].Typ || found[i].Val != expectedTokens[i].Val {
+				t.Errorf("%s: formatted text has token %s at %d, expected %s", input, found[i], i, expectedTokens[i])
+				break
+			}
+		}
+
+		again, err := unparseSource(formatted)
+		if err != nil {
+			t.Errorf("%s: reformatting: %s", input, err)
+		} else if again != formatted {
+			t.Errorf("%s: formatting is not stable\nReformatted\n\n%s\n", input, again)
+		}
+	}
+}
+
+func unparseSource(src string) (string, error) {
+	file, err := parser.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return Unparse(file)
+}
+
+// significantTokens lexes src, leaving out whitespace and comments.
+func significantTokens(src string) []token.Item {
+	var items []token.Item
+	s := token.Subset(lexer.NewLexer(src), token.Significant)
+	for item := s.Next(); item.Typ != token.EOF && item != (token.Item{}); item = s.Next() {
+		items = append(items, item)
+	}
+	return items
+}
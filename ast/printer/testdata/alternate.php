@@ -0,0 +1,32 @@
+<ul>
+<?php foreach ($items as $item): ?>
+  <li><?php echo $item; ?></li>
+<?php endforeach; ?>
+</ul>
+<?php
+if ($a):
+    echo 1;
+elseif ($b):
+    echo 2;
+else:
+    echo 3;
+endif;
+while ($i < 10):
+    $i++;
+    if ($i > 5) {
+        break;
+    }
+endwhile;
+for ($i = 0; $i < 3; $i++):
+    echo $i;
+endfor;
+switch ($x):
+    case 1:
+        echo "one";
+        break;
+    default:
+        echo "other";
+endswitch;
+declare(ticks=1):
+    tick();
+enddeclare;
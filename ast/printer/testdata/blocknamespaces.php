@@ -0,0 +1,10 @@
+<?php
+namespace App\Models {
+    use App\Contracts\Repository;
+
+    $repository = new Repository;
+}
+
+namespace {
+    $repository = new App\Models\Repository;
+}
@@ -0,0 +1,30 @@
+<?php
+interface Shape extends Countable, JsonSerializable {
+    const SIDES = 0;
+    public function area(): float;
+}
+
+trait Named {
+    public function name() { return __CLASS__; }
+}
+
+abstract class Polygon implements Shape {
+    use Named;
+    const SIDES = 3;
+    protected static $count = 0;
+    public ?string $label = null;
+
+    public function __construct(private array $points, public readonly int $scale = 1) {
+        self::$count++;
+    }
+
+    abstract protected function perimeter();
+
+    final public static function make(array $points) {
+        return new Triangle($points);
+    }
+}
+
+$shape = Polygon::make(array(array(0, 0), array(1, 0), array(0, 1)));
+echo $shape->name(), $shape?->label, Polygon::SIDES;
+$shape->points[0] = array(2, 2);
@@ -0,0 +1,36 @@
+<?php
+$total = $price * ($quantity + $extra) - $discount;
+$ratio = ($a - $b) / ($a + $b);
+$negated = -($x + 1);
+$flag = !$done && ($count > 10 || $force);
+$label = $name ?: "anonymous";
+$size = $big ? "large" : ($medium ? "medium" : "small");
+$kind = ($small ? "small" : "other") ? "known" : "unknown";
+$first = $a ?: $b ?: $c;
+$text = "Hello, " . strtoupper($name) . "!";
+$value = (int) $input;
+$copy = clone $original;
+$contents = @file_get_contents($path);
+$list = array(1, 2, "three" => 3);
+list($first, $second) = $list;
+$closure = function ($x) use ($factor): int { return $x * $factor; };
+$result = $closure(3);
+$upper = $text |> "strtoupper";
+$lower = $text |> strtolower(...);
+$matches = $obj instanceof Countable;
+$a .= "suffix";
+$i++;
+--$j;
+$len = strlen(...);
+$m = $o->method(...);
+$s = A::make(...);
+f(1, ...$rest);
+$point = new Point(x: 1, y: $a ? 1 : 2);
+$found = $cache[$key] ?? $default ?? throw new RuntimeException("missing");
+$made = new $class($x);
+$built = new (getClass())(1, 2);
+$names = array(Foo::class, self::class, $obj::class);
+$kind = match ($n) {
+	1, 2 => "low",
+	default => "high",
+};
@@ -0,0 +1,52 @@
+<?php
+// functions and control structures
+function fib($n) {
+  if ($n < 2) { return $n; }
+  return fib($n - 1) + fib($n - 2);
+}
+
+function classify(int $n, &$seen = null, ...$rest): string
+{
+    global $counts;
+    static $calls = 0;
+    $calls++;
+    if ($n < 0) {
+        $kind = "negative";
+    } elseif ($n == 0) {
+        $kind = 'zero';
+    } else
+        $kind = "positive";
+    switch ($kind) {
+    case "zero":
+        echo "nothing";
+        break;
+    default:
+        echo $kind, "\n";
+    }
+    for ($i = 0; $i < $n; $i++) {
+        if ($i % 2 == 0) continue;
+        $seen[] = $i;
+    }
+    foreach ($rest as $key => &$value) { $value = $value * ($n - 1); }
+    while ($n > 10) $n = $n / 2;
+    do {
+        $n--;
+    } while ($n > 0);
+    try {
+        throw new Exception("failed");
+    } catch (InvalidArgumentException | RangeException $e) {
+        return $e->getMessage();
+    } catch (Exception) {
+        return null;
+    } finally {
+    }
+    return $kind;
+}
+
+function &registry() {
+    static $items = array();
+    $add = function &($key) use (&$items) {
+        return $items[$key];
+    };
+    return $items;
+}
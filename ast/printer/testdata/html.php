@@ -0,0 +1,13 @@
+<!DOCTYPE html>
+<html>
+<body>
+<?php
+$items = array("one", "two");
+foreach ($items as $item) {
+?>
+    <li><?php echo $item; ?></li>
+<?php
+}
+?>
+</body>
+</html>
@@ -0,0 +1,19 @@
+<?php
+namespace App\Models;
+
+use App\Contracts\Repository, App\Support\Str as S;
+use function App\Support\{format, parse as parseValue};
+use const App\Config\DEBUG;
+use App\Events\{Created, function dispatch};
+
+require_once __DIR__ . "/bootstrap.php";
+include_once "helpers.php";
+$config = require "config.php";
+
+$repository = new Repository;
+$event = new Created();
+[$first, , $third] = $items;
+[$id, [$name, $email]] = $row;
+["id" => $key, "tags" => [, $tag]] = $record;
+list($x, list(, $y)) = $pairs;
+[$last, , ] = $items;
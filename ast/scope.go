@@ -57,6 +57,40 @@ type File struct {
 	Name      string
 	Namespace *Namespace
 	Nodes     []Node
+
+	// Imports holds the use imports of the file. The imports of a file that
+	// declares several namespaces are those of its last namespace, and each
+	// NamespaceStmt holds its own.
+	Imports
+}
+
+// Imports holds the names imported by the use statements of a namespace.
+type Imports struct {
+	// Uses maps the lowercased alias of each use import to the
+	// fully-qualified name it imports.
+	Uses map[string]string
+	// FunctionUses is like Uses, for the imports of use function statements.
+	FunctionUses map[string]string
+	// ConstantUses maps the alias of each import of a use const statement,
+	// which is case-sensitive, to the fully-qualified name it imports.
+	ConstantUses map[string]string
+}
+
+// NewImports returns an empty set of imports.
+func NewImports() Imports {
+	return Imports{
+		Uses:         map[string]string{},
+		FunctionUses: map[string]string{},
+		ConstantUses: map[string]string{},
+	}
+}
+
+func (f File) String() string {
+	return f.Name
+}
+
+func (f File) Children() []Node {
+	return f.Nodes
 }
 
 type FileSet struct {
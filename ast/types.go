@@ -74,7 +74,7 @@ func (t BasicType) Single() bool {
 }
 
 func (t BasicType) Union(o Type) Type {
-	return compoundType{t: struct{}{}, o: struct{}{}}
+	return compoundType{t: struct{}{}}.Union(o)
 }
 
 type compoundType map[Type]struct{}
@@ -123,8 +123,18 @@ func (c compoundType) Contains(t Type) bool {
 
 // Union returns a new type that includes both the receiver and the argument.
 func (c compoundType) Union(t Type) Type {
-	c[t] = struct{}{}
-	return c
+	u := make(compoundType, len(c)+1)
+	for it := range c {
+		u[it] = struct{}{}
+	}
+	if ct, ok := t.(compoundType); ok {
+		for it := range ct {
+			u[it] = struct{}{}
+		}
+		return u
+	}
+	u[t] = struct{}{}
+	return u
 }
 
 // Single returns true if the receiver expresses one type and only one type.
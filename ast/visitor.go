@@ -0,0 +1,26 @@
+package ast
+
+// A Visitor's Enter method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with w, followed by a call of w.Leave(node).
+type Visitor interface {
+	Enter(node Node) (w Visitor)
+	Leave(node Node)
+}
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// v.Enter(root), and walks the children of root with the visitor it returns
+// unless that visitor is nil.
+func Walk(v Visitor, root Node) {
+	if root == nil {
+		return
+	}
+	w := v.Enter(root)
+	if w == nil {
+		return
+	}
+	for _, child := range root.Children() {
+		Walk(w, child)
+	}
+	w.Leave(root)
+}
@@ -0,0 +1,95 @@
+package ast_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stephens2424/php/ast"
+	"github.com/stephens2424/php/parser"
+)
+
+type functionCounter struct {
+	count int
+}
+
+func (f *functionCounter) Enter(node ast.Node) ast.Visitor {
+	if _, ok := node.(*ast.FunctionStmt); ok {
+		f.count++
+	}
+	return f
+}
+
+func (f *functionCounter) Leave(node ast.Node) {}
+
+type stringCollector struct {
+	strings []string
+	skip    string
+}
+
+func (s *stringCollector) Enter(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.FunctionStmt:
+		if n.Name == s.skip {
+			return nil
+		}
+	case *ast.Literal:
+		if n.Type == ast.String {
+			s.strings = append(s.strings, n.Value)
+		}
+	}
+	return s
+}
+
+func (s *stringCollector) Leave(node ast.Node) {}
+
+const visitorSource = `<?php
+function a() {
+  echo "a";
+}
+function b($x) {
+  if ($x) {
+    return "b";
+  }
+  function c() {
+    return 'c';
+  }
+}
+echo "d", 1;`
+
+func TestVisitorCountsFunctions(t *testing.T) {
+	file, err := parser.Parse(visitorSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &functionCounter{}
+	for _, node := range file.Nodes {
+		ast.Walk(f, node)
+	}
+	if f.count != 3 {
+		t.Errorf("expected 3 functions, found %d", f.count)
+	}
+}
+
+func TestVisitorCollectsStrings(t *testing.T) {
+	file, err := parser.Parse(visitorSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stringCollector{}
+	for _, node := range file.Nodes {
+		ast.Walk(s, node)
+	}
+	expected := []string{`"a"`, `"b"`, `'c'`, `"d"`}
+	if !reflect.DeepEqual(s.strings, expected) {
+		t.Errorf("expected %v, found %v", expected, s.strings)
+	}
+
+	s = &stringCollector{skip: "b"}
+	for _, node := range file.Nodes {
+		ast.Walk(s, node)
+	}
+	expected = []string{`"a"`, `"d"`}
+	if !reflect.DeepEqual(s.strings, expected) {
+		t.Errorf("expected %v after pruning b, found %v", expected, s.strings)
+	}
+}
@@ -28,8 +28,6 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		for _, node := range file.Nodes {
-			p.PrintNode(node)
-		}
+		p.PrintNode(file)
 	}
 }
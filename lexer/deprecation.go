@@ -0,0 +1,73 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stephens2424/php/token"
+)
+
+// A Deprecation is a use of syntax that PHP has deprecated or removed.
+type Deprecation struct {
+	Construct  string  // Construct names the syntax used, as in "(real) cast".
+	Deprecated Version // Deprecated is the first version deprecating it, or 0.
+	Removed    Version // Removed is the first version no longer supporting it, or 0.
+	Begin      token.Position
+}
+
+func (d Deprecation) Error() string {
+	switch {
+	case d.Removed == 0:
+		return fmt.Sprintf("%s is deprecated since PHP %s", d.Construct, d.Deprecated)
+	case d.Deprecated == 0:
+		return fmt.Sprintf("%s is removed in PHP %s", d.Construct, d.Removed)
+	}
+	return fmt.Sprintf("%s is deprecated since PHP %s and removed in PHP %s", d.Construct, d.Deprecated, d.Removed)
+}
+
+// Deprecations lexes input and returns its uses of deprecated or removed
+// syntax, in order. If input cannot be lexed, the deprecations found before
+// the error are returned with it.
+func Deprecations(input string) ([]Deprecation, error) {
+	var found []Deprecation
+	s := NewScanner(input)
+	for i, ok := s.Next(); ok; i, ok = s.Next() {
+		if i.Typ == token.Error {
+			return found, errors.New(i.Val)
+		}
+		if d, ok := deprecation(i); ok {
+			found = append(found, d)
+		}
+	}
+	return found, nil
+}
+
+// deprecation returns the deprecation i begins and true, or false if i is
+// not deprecated.
+func deprecation(i token.Item) (Deprecation, bool) {
+	d := Deprecation{Begin: i.Begin}
+	switch i.Typ {
+	case token.CurlyLookupOperatorLeft:
+		d.Construct, d.Deprecated, d.Removed = "curly brace offset", PHP74, PHP80
+	case token.ShellCommand:
+		d.Construct, d.Deprecated = "backtick operator", PHP85
+	case token.CastOperator:
+		cast := strings.ToLower(strings.Join(strings.Fields(i.Val), ""))
+		switch cast {
+		case "(real)":
+			d.Deprecated, d.Removed = PHP74, PHP80
+		case "(unset)":
+			d.Deprecated, d.Removed = PHP72, PHP80
+		case "(integer)", "(boolean)", "(double)":
+			// the non-canonical names of the (int), (bool) and (float) casts
+			d.Deprecated = PHP85
+		default:
+			return d, false
+		}
+		d.Construct = cast + " cast"
+	default:
+		return d, false
+	}
+	return d, true
+}
@@ -0,0 +1,34 @@
+package lexer
+
+import "testing"
+
+func TestDeprecations(t *testing.T) {
+	src := "<?php\n$a = (real) $b;\necho $s{0};\n$c = ( Unset )$d . `ls`;\n$e = (int) $f . $s[0] . (integer) $g;"
+	found, err := Deprecations(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		err            string
+		line, position int
+	}{
+		{"(real) cast is deprecated since PHP 7.4 and removed in PHP 8.0", 2, 11},
+		{"curly brace offset is deprecated since PHP 7.4 and removed in PHP 8.0", 3, 29},
+		{"(unset) cast is deprecated since PHP 7.2 and removed in PHP 8.0", 4, 39},
+		{"backtick operator is deprecated since PHP 8.5", 4, 53},
+		{"(integer) cast is deprecated since PHP 8.5", 5, 83},
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("found %d deprecations, expected %d: %v", len(found), len(expected), found)
+	}
+	for n, d := range found {
+		if d.Error() != expected[n].err || d.Begin.Line != expected[n].line || d.Begin.Position != expected[n].position {
+			t.Errorf("found %q at line %d, position %d, expected %q at line %d, position %d",
+				d, d.Begin.Line, d.Begin.Position, expected[n].err, expected[n].line, expected[n].position)
+		}
+	}
+
+	if _, err := Deprecations("<?php $a = (real) 'x"); err == nil {
+		t.Error("expected an error for an unterminated string")
+	}
+}
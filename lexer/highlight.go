@@ -0,0 +1,73 @@
+package lexer
+
+import "github.com/stephens2424/php/token"
+
+// A Span is a range of source bytes, from Start up to End, and the class of
+// syntax it holds.
+type Span struct {
+	Start, End int
+	Class      string
+}
+
+// Highlight classifies the whole of src for a syntax highlighter. The spans it
+// returns are in order and cover src without gaps or overlaps, and adjacent
+// spans have different classes. A span's class is one of html, tag, keyword,
+// identifier, variable, operator, punctuation, string, number, constant,
+// comment, space, or error, the last for text that could not be lexed.
+func Highlight(src string) []Span {
+	var spans []Span
+	s := NewScanner(src, PreserveTrivia)
+	prev := token.Token(-1)
+	for i, ok := s.Next(); ok; i, ok = s.Next() {
+		class := highlightClass(i.Typ, prev)
+		prev = i.Typ
+		start, end := i.Begin.Position, i.End.Position
+		if start == end {
+			continue
+		}
+		if n := len(spans); n > 0 && spans[n-1].Class == class {
+			spans[n-1].End = end
+			continue
+		}
+		spans = append(spans, Span{Start: start, End: end, Class: class})
+	}
+	return spans
+}
+
+// highlightClass returns the class of a t item following a prev item.
+func highlightClass(t, prev token.Token) string {
+	switch t {
+	case token.HTML:
+		return "html"
+	case token.PHPBegin, token.PHPEnd:
+		return "tag"
+	case token.Error:
+		return "error"
+	case token.VariableOperator:
+		return "variable"
+	case token.Identifier, token.This:
+		if prev == token.VariableOperator {
+			return "variable"
+		}
+		return "identifier"
+	case token.NumberLiteral:
+		return "number"
+	case token.BooleanLiteral, token.Null, token.MagicConstant:
+		return "constant"
+	}
+	switch typ := t.Type(); {
+	case typ.Is(token.KeywordType):
+		return "keyword"
+	case typ.Is(token.LiteralType):
+		return "string"
+	case typ.Is(token.OperatorType):
+		return "operator"
+	case typ.Is(token.MarkerType):
+		return "punctuation"
+	case typ.Is(token.CommentType):
+		return "comment"
+	case typ.Is(token.WhitespaceType):
+		return "space"
+	}
+	return "identifier"
+}
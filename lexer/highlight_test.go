@@ -0,0 +1,64 @@
+package lexer
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	src := "<p><?php\n// hi\nif ($a > 1.5) { echo \"x\", true; } § ?>\n</p>"
+	spans := Highlight(src)
+
+	end := 0
+	for _, s := range spans {
+		if s.Start != end || s.End <= s.Start {
+			t.Fatalf("span %+v follows one ending at %d", s, end)
+		}
+		end = s.End
+	}
+	if end != len(src) {
+		t.Fatalf("spans end at %d, expected %d", end, len(src))
+	}
+
+	classes := map[string]string{
+		"<p>":     "html",
+		"<?php":   "tag",
+		"// hi\n": "comment",
+		"if":      "keyword",
+		"$a":      "variable",
+		">":       "operator",
+		"1.5":     "number",
+		"{":       "punctuation",
+		"echo":    "keyword",
+		`"x"`:     "string",
+		"true":    "constant",
+		"§":       "error",
+		"?>":      "tag",
+		"\n</p>":  "html",
+	}
+	for _, s := range spans {
+		text := src[s.Start:s.End]
+		if class, ok := classes[text]; ok {
+			if class != s.Class {
+				t.Errorf("%q highlighted as %s, expected %s", text, s.Class, class)
+			}
+			delete(classes, text)
+		}
+	}
+	for text := range classes {
+		t.Errorf("no span of %q", text)
+	}
+}
+
+func TestHighlightTrailingOperator(t *testing.T) {
+	for _, src := range []string{"<?php\n\n.", "<?php $a = $b .", "<?php $a +"} {
+		spans := Highlight(src)
+		if len(spans) == 0 {
+			t.Fatalf("no spans for %q", src)
+		}
+		last := spans[len(spans)-1]
+		if last.End != len(src) {
+			t.Errorf("spans of %q end at %d, expected %d", src, last.End, len(src))
+		}
+		if last.Class != "operator" {
+			t.Errorf("%q ends with a %s span, expected operator", src, last.Class)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package lexer
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -19,44 +20,150 @@ type lexer struct {
 	lastStart int // lastStart stores the start position of the previously lexed token..
 	lastPos   int // lastPos stores the position of the previous lexed element.
 
-	pos     int             // pos is the current position of the lexer in the input, as an index of the input string.
-	line    int             // line is the current line number
-	width   int             // width is the length of the current rune
-	itemsCh chan token.Item // channel of scanned items.
-	items   []token.Item    // the items lexed so far
-	itemPos int             // the current position in items
+	pos     int          // pos is the current position of the lexer in the input, as an index of the input string.
+	line    int          // line is the current line number
+	width   int          // width is the length of the current rune
+	state   stateFn      // state is the next state function to run, or nil once lexing is done.
+	pending []token.Item // pending are the items emitted but not yet returned.
+	items   []token.Item // the items lexed so far
+	itemPos int          // the current position in items
 
 	// input is the full input string.
 	input string
 
 	// file is the filename of the input, used to print errors.
 	file string
+
+	// lineStart is the offset in input of the line lexing has reached.
+	lineStart int
+
+	signature      bool          // signature is true between a function keyword and the end of its parameter list.
+	signatureDepth int           // signatureDepth is the paren depth within a signature.
+	afterParams    bool          // afterParams is true just past the closing paren of a parameter list.
+	typeStart      bool          // typeStart is true where the type of a parameter or property may begin.
+	paramType      bool          // paramType is true just past the type of a parameter, where a & makes the parameter a reference.
+	constType      bool          // constType is true after const, where the type of a typed constant may begin.
+	returnType     bool          // returnType is true after the colon of a return type, where the type begins.
+	catchType      bool          // catchType is true just inside the parens of a catch, where its exception types begin.
+	statementStart bool          // statementStart is true where a statement, and so a label, may begin.
+	condition      bool          // condition is true between a control structure keyword and the end of its condition.
+	conditionDepth int           // conditionDepth is the paren depth within a condition.
+	afterCondition bool          // afterCondition is true where the colon opening an alternate syntax block may appear.
+	afterVariable  bool          // afterVariable is true just past a variable, property or offset, where { begins a curly brace offset.
+	afterOperand   bool          // afterOperand is true just past any operand, where [ begins an offset rather than an array.
+	declaration    bool          // declaration is true after the $ of a declared property or parameter.
+	braces         []token.Token // braces hold, for each open brace, CurlyLookupOperatorLeft or the token before the block it opened.
+	brackets       []token.Token // brackets hold the token closing each open square bracket.
+	lastToken      token.Token   // lastToken is the type of the last significant token emitted.
+	matchHeads     []matchHead   // matchHeads are the heads of possible match expressions being lexed.
+
+	// recover is true if lexing should continue after an error.
+	recover bool
+
+	// preserveTrivia is true if errors should hold the input they skip.
+	preserveTrivia bool
+
+	// noShortTags is true if <? does not begin PHP code.
+	noShortTags bool
+
+	version    Version     // version is the targeted version of PHP, if any.
+	violations []Violation // violations are the uses of syntax newer than version.
+	features   FeatureSet  // features are the features used, if they are being collected.
+}
+
+// An Option configures a lexer created by NewLexer.
+type Option func(*lexer)
+
+// PreserveTrivia makes lexing lossless: the values of the items lexed,
+// concatenated in order, reproduce the input byte for byte. Each item's value
+// is the exact slice of the input it was lexed from, including whitespace and
+// comments. Lexing continues after an error, as with NewRecoveringLexer, but
+// each Error item holds the text that could not be lexed rather than a
+// message.
+var PreserveTrivia Option = func(l *lexer) {
+	l.preserveTrivia = true
+	l.recover = true
+}
+
+// NoShortTags disables the short open tag, <?, as PHP's short_open_tag
+// setting does. The text <? is then lexed as HTML unless it begins <?php or
+// <?=.
+var NoShortTags Option = func(l *lexer) {
+	l.noShortTags = true
+}
+
+func NewLexer(input string, options ...Option) token.Stream {
+	return newLexer(input, options...)
 }
 
-func NewLexer(input string) token.Stream {
+func newLexer(input string, options ...Option) *lexer {
 	l := &lexer{
-		line:    1,
-		input:   input,
-		itemsCh: make(chan token.Item),
+		line:  1,
+		input: input,
+		state: lexFileBegin,
+	}
+	for _, option := range options {
+		option(l)
 	}
-	go l.run()
 	return l
 }
 
+// NewRecoveringLexer returns a lexer that does not stop at the first error.
+// After emitting an Error item it skips ahead to the next space or delimiter
+// and resumes lexing, so that one bad token does not hide the rest of the
+// input from tools such as syntax highlighters.
+func NewRecoveringLexer(input string) token.Stream {
+	return newLexer(input, func(l *lexer) { l.recover = true })
+}
+
+// Tokens lexes input to completion and returns its items, ending with the EOF
+// item. If input cannot be lexed, the items before the error are returned
+// with it.
+func Tokens(input string) ([]token.Item, error) {
+	var items []token.Item
+	err := WalkTokens(input, func(i token.Item) bool {
+		items = append(items, i)
+		return true
+	})
+	return items, err
+}
+
+// WalkTokens lexes input, calling fn with each item in turn, ending with the
+// EOF item, until fn returns false. Unlike Tokens it keeps no items, so even
+// a huge input is lexed in little memory. If input cannot be lexed, fn is
+// called with the items before the error, which is then returned.
+func WalkTokens(input string, fn func(token.Item) bool) error {
+	s := NewScanner(input)
+	for {
+		i, ok := s.Next()
+		if i.Typ == token.Error {
+			return errors.New(i.Val)
+		}
+		if !fn(i) || !ok {
+			return nil
+		}
+	}
+}
+
 // stateFn represents the state of the scanner
 // as a function that returns the next state.
 type stateFn func(*lexer) stateFn
 
-// Run lexes the input by executing state functions until
-// the state is nil. It is typically called in a goroutine.
-func (l *lexer) run() {
-	for state := lexHTML; state != nil; {
-		state = state(l)
+// nextItem executes state functions until an item has been emitted and
+// returns it. Once the state is nil it returns the zero value, an EOF item.
+func (l *lexer) nextItem() token.Item {
+	for len(l.pending) == 0 {
+		if l.state == nil {
+			return token.Item{}
+		}
+		l.state = l.state(l)
 	}
-	close(l.itemsCh) // No more tokens will be delivered.
+	i := l.pending[0]
+	l.pending = append(l.pending[:0], l.pending[1:]...)
+	return i
 }
 
-// emit gets the current token., sends it on the token. channel
+// emit queues the current token. to be returned by nextItem
 // and prepares for lexing the next token.
 func (l *lexer) emit(t token.Token) {
 	i := token.Item{
@@ -70,11 +177,138 @@ func (l *lexer) emit(t token.Token) {
 	l.start = l.pos
 
 	i.End = l.currentLocation()
-	l.itemsCh <- i
+	l.trackFeatures(i)
+	l.trackSignature(t)
+	l.pending = append(l.pending, i)
+}
+
+// trackSignature follows function signatures and declaration modifiers so
+// that a colon after a parameter list can be lexed as the start of a return
+// type, and a ? before a parameter or property type as a nullable marker,
+// rather than as part of a ternary.
+func (l *lexer) trackSignature(t token.Token) {
+	afterParams, typeStart, constType, statementStart, afterCondition := false, false, false, false, false
+	afterVariable, afterOperand, catchType, returnType := false, false, false, false
+	switch t {
+	case token.Space, token.CommentLine, token.CommentBlock, token.Attribute:
+		return
+	case token.Function:
+		l.signature, l.signatureDepth = true, 0
+	case token.Use:
+		// the use list of a closure comes between its parameters and its
+		// return type
+		if l.afterParams {
+			l.signature, l.signatureDepth = true, 0
+		}
+	case token.If, token.ElseIf, token.While, token.For, token.Foreach, token.Switch, token.Declare:
+		l.condition, l.conditionDepth = true, 0
+	case token.Else:
+		afterCondition = true
+	case token.OpenParen:
+		if l.signature {
+			l.signatureDepth++
+			typeStart = l.signatureDepth == 1
+		}
+		if l.condition {
+			l.conditionDepth++
+		}
+		catchType = l.lastToken == token.Catch
+	case token.Comma:
+		typeStart = l.signature && l.signatureDepth == 1
+	case token.Public, token.Protected, token.Private, token.Static, token.Var, token.Readonly:
+		// modifiers precede the type of a property or promoted constructor
+		// property
+		typeStart = true
+	case token.CloseParen:
+		if l.signature {
+			l.signatureDepth--
+			if l.signatureDepth == 0 {
+				l.signature = false
+				afterParams = true
+			}
+		}
+		if l.condition {
+			l.conditionDepth--
+			if l.conditionDepth == 0 {
+				l.condition = false
+				afterCondition = true
+			}
+		}
+		// the value of a parenthesized expression or call, but not a
+		// condition, as in if ($a) [$b] = $c;
+		afterOperand = !afterCondition
+	case token.Const:
+		constType = true
+	case token.TernaryOperator2:
+		returnType = l.afterParams
+	case token.StatementEnd:
+		l.signature = false
+		statementStart = true
+	case token.BlockBegin:
+		l.signature = false
+		statementStart = true
+		l.braces = append(l.braces, l.lastToken)
+	case token.BlockEnd:
+		statementStart = true
+		if n := len(l.braces); n > 0 {
+			switch l.braces[n-1] {
+			case token.VariableOperator, token.ObjectOperator, token.NullsafeObjectOperator, token.ScopeResolutionOperator:
+				// the end of a dynamic name, as in ${'a'} or $o->{'a'}
+				afterVariable = true
+			}
+			l.braces = l.braces[:n-1]
+		}
+	case token.PHPBegin:
+		statementStart = true
+	case token.VariableOperator:
+		// the braces after a declared property hold its hooks, as in
+		// public $a { get; }
+		switch l.lastToken {
+		case token.TypeHint, token.Identifier, token.Array, token.Self, token.Parent:
+			l.declaration = true
+		default:
+			l.declaration = isModifier(l.lastToken)
+		}
+	case token.Identifier:
+		switch l.lastToken {
+		case token.VariableOperator:
+			afterVariable = !l.declaration
+		case token.ObjectOperator, token.NullsafeObjectOperator:
+			afterVariable = true
+		default:
+			// a constant, as in FOO[0]
+			afterOperand = true
+		}
+	case token.This:
+		afterVariable = true
+	case token.SingleQuotedString, token.DoubleQuotedString, token.Heredoc, token.Nowdoc, token.MagicConstant:
+		afterOperand = true
+	case token.CurlyLookupOperatorLeft:
+		l.braces = append(l.braces, t)
+	case token.ArrayLookupOperatorLeft:
+		l.brackets = append(l.brackets, token.ArrayLookupOperatorRight)
+	case token.ShortArrayLeft:
+		l.brackets = append(l.brackets, token.ShortArrayRight)
+	case token.CurlyLookupOperatorRight, token.ArrayLookupOperatorRight, token.ShortArrayRight:
+		if t == token.CurlyLookupOperatorRight {
+			l.braces = l.braces[:len(l.braces)-1]
+		} else if n := len(l.brackets); n > 0 {
+			l.brackets = l.brackets[:n-1]
+		}
+		afterVariable = t != token.ShortArrayRight
+		afterOperand = true
+	}
+	// a & after the type of a parameter makes the parameter a reference
+	l.paramType = l.typeStart && l.signature && isParamType(t)
+	l.afterParams, l.typeStart, l.constType, l.catchType = afterParams, typeStart, constType, catchType
+	l.returnType = returnType
+	l.statementStart, l.afterCondition = statementStart, afterCondition
+	l.afterVariable, l.afterOperand = afterVariable, afterOperand || afterVariable
+	l.lastToken = t
 }
 
 func (l *lexer) currentLocation() token.Position {
-	return token.Position{Position: l.start, Line: l.line, File: l.file}
+	return token.Position{Position: l.start, Line: l.line, Column: l.start - l.lineStart + 1, File: l.file}
 }
 
 // nextItem returns the next token from the input.
@@ -92,7 +326,7 @@ func (l *lexer) Next() token.Item {
 	}
 
 	// lex a new item and return it
-	item := <-l.itemsCh
+	item := l.nextItem()
 	l.items = append(l.items, item)
 	l.itemPos++
 	return item
@@ -108,10 +342,13 @@ func (l *lexer) Previous() token.Item {
 	return l.items[l.itemPos]
 }
 
-// peek returns but does not consume the next rune in the input.
+// peek returns but does not consume the next rune in the input. It leaves
+// the width of the last rune read, so backup still steps back over it.
 func (l *lexer) peek() rune {
+	w := l.width
 	r := l.next()
 	l.backup()
+	l.width = w
 	return r
 }
 
@@ -160,23 +397,97 @@ func (l *lexer) skipSpace() {
 	l.backup()
 }
 
+// errorf emits an Error item located at the start of the item being lexed.
+// Its message is formatted from format and args and then followed by that
+// location, as in "unexpected character '#' at line 12, col 3".
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
+	if l.preserveTrivia {
+		l.skipToDelimiter()
+		l.emit(token.Error)
+		return lexPHP
+	}
+	loc := l.currentLocation()
 	i := token.Item{
 		Typ:   token.Error,
-		Begin: l.currentLocation(),
-		End:   l.currentLocation(),
-		Val:   fmt.Sprintf(format, args...),
+		Begin: loc,
+		End:   loc,
+		Val:   fmt.Sprintf(format, args...) + fmt.Sprintf(" at line %d, col %d", loc.Line, loc.Column),
 	}
 	l.incrementLines()
-	l.itemsCh <- i
+	l.pending = append(l.pending, i)
+	// the error ends whatever the signature flags expected next, as it does
+	// when it is emitted, so that a recovering lexer does not expect it again
+	l.trackSignature(token.Error)
+	if l.recover {
+		return lexResync
+	}
 	return nil
 }
 
+// expected emits an Error item saying that what was expected but the next
+// character, or the end of the input, was found instead.
+func (l *lexer) expected(what string) stateFn {
+	found := "end of file"
+	if r := l.peek(); r != eof {
+		found = fmt.Sprintf("%q", r)
+	}
+	return l.errorf("expected %s, found %s", what, found)
+}
+
+// delimiters are the characters at which lexing resumes after an error.
+const delimiters = ";,(){}[]"
+
+// lexResync discards input up to the next space or delimiter and then
+// resumes lexing PHP.
+func lexResync(l *lexer) stateFn {
+	l.skipToDelimiter()
+	l.start = l.pos
+	return lexPHP
+}
+
+// skipToDelimiter advances up to the next space or delimiter.
+func (l *lexer) skipToDelimiter() {
+	for {
+		r := l.next()
+		if r == eof || isSpace(r) || strings.ContainsRune(delimiters, r) {
+			l.backup()
+			return
+		}
+	}
+}
+
+// incrementLines counts the line breaks lexed since it was last called. A
+// line break is \n, \r or \r\n, which counts once even if it is split between
+// two items.
 func (l *lexer) incrementLines() {
-	l.line += strings.Count(l.input[l.lastStart:l.pos], "\n")
+	for i := l.lastStart; i < l.pos; i++ {
+		switch l.input[i] {
+		case '\r':
+			l.line++
+			l.lineStart = i + 1
+		case '\n':
+			if i == 0 || l.input[i-1] != '\r' {
+				l.line++
+			}
+			l.lineStart = i + 1
+		}
+	}
 	l.lastStart = l.pos
 }
 
+// lineEnd returns the index just past the first line break in s, or len(s) if
+// there is none.
+func lineEnd(s string) int {
+	i := strings.IndexAny(s, "\r\n")
+	switch {
+	case i < 0:
+		return len(s)
+	case strings.HasPrefix(s[i:], "\r\n"):
+		return i + 2
+	}
+	return i + 1
+}
+
 // isSpace reports whether r is a space character.
 func isSpace(r rune) bool {
 	return unicode.IsSpace(r)
@@ -202,7 +513,7 @@ func isNonAlphaOperator(s string) bool {
 var keywordMap = map[token.Token]bool{}
 
 func init() {
-	re := regexp.MustCompile("^[a-zA-Z]+")
+	re := regexp.MustCompile("^[a-zA-Z_]+")
 	for keyword, t := range token.TokenMap {
 		if re.MatchString(keyword) {
 			keywordMap[t] = true
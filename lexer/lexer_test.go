@@ -1,6 +1,11 @@
 package lexer
 
 import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stephens2424/php/token"
@@ -61,7 +66,7 @@ func TestPHPLexer(t *testing.T) {
 	i = assertNext(t, l, token.VariableOperator)
 	i = assertNext(t, l, token.Identifier)
 	i = assertNext(t, l, token.AssignmentOperator)
-	i = assertNext(t, l, token.StringLiteral)
+	i = assertNext(t, l, token.DoubleQuotedString)
 	i = assertNext(t, l, token.StatementEnd)
 
 	i = assertNext(t, l, token.VariableOperator)
@@ -127,7 +132,7 @@ func TestPHPLexer(t *testing.T) {
 	i = assertNext(t, l, token.Identifier)
 	i = assertNext(t, l, token.AssignmentOperator)
 	i = assertNext(t, l, token.VariableOperator)
-	i = assertNext(t, l, token.Identifier)
+	i = assertNext(t, l, token.This)
 	i = assertNext(t, l, token.ObjectOperator)
 	i = assertNext(t, l, token.Identifier)
 	i = assertNext(t, l, token.OpenParen)
@@ -137,7 +142,7 @@ func TestPHPLexer(t *testing.T) {
 	i = assertNext(t, l, token.VariableOperator)
 	i = assertNext(t, l, token.Identifier)
 	i = assertNext(t, l, token.AssignmentOperator)
-	i = assertNext(t, l, token.StringLiteral)
+	i = assertNext(t, l, token.Heredoc)
 	i = assertNext(t, l, token.StatementEnd)
 
 	i = assertNext(t, l, token.PHPEnd)
@@ -157,3 +162,1880 @@ func TestPHPLexer(t *testing.T) {
 
 	i = assertNext(t, l, token.EOF)
 }
+
+func TestNumberSeparators(t *testing.T) {
+	for _, lit := range []string{"1_000_000", "0xCA_FE", "0b1010_0101"} {
+		l := token.Subset(NewLexer("<?php "+lit+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, token.NumberLiteral)
+		assertItem(t, i, lit)
+		assertNext(t, l, token.StatementEnd)
+	}
+
+	l := token.Subset(NewLexer("<?php 1__0;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Error)
+}
+
+func TestIntegerBases(t *testing.T) {
+	for _, lit := range []string{"0x1A", "0XFF", "0755", "0o755", "0O17", "0b1010", "0B1"} {
+		l := token.Subset(NewLexer("<?php "+lit+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, token.NumberLiteral)
+		assertItem(t, i, lit)
+		assertNext(t, l, token.StatementEnd)
+	}
+
+	for _, lit := range []string{"0b102", "0o78", "089", "0xfg", "0x"} {
+		l := token.Subset(NewLexer("<?php "+lit+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, token.Error)
+		if i.Begin.Line != 1 || i.Begin.Position != len("<?php ") {
+			t.Errorf("error for %q reported at %+v", lit, i.Begin)
+		}
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := map[string]bool{
+		"9223372036854775807":   false,
+		"9223372036854775808":   true,
+		"0x7fff_ffff_ffff_ffff": false,
+		"0xffffffffffffffff":    true,
+	}
+	for lit, overflows := range tests {
+		l := token.Subset(NewLexer("<?php "+lit+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		if i := assertNext(t, l, token.NumberLiteral); i.Overflows() != overflows {
+			t.Errorf("%q overflows: %t, expected %t", lit, i.Overflows(), overflows)
+		}
+	}
+}
+
+func TestFloatLiterals(t *testing.T) {
+	tests := []struct {
+		src, lit string
+	}{
+		{"1.5e10", "1.5e10"},
+		{".5", ".5"},
+		{"1.", "1."},
+		{"1E-3", "1E-3"},
+		{"2e+8", "2e+8"},
+		{"3.14", "3.14"},
+		{"7E10", "7E10"},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer("<?php "+test.src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, token.NumberLiteral)
+		assertItem(t, i, test.lit)
+		assertNext(t, l, token.StatementEnd)
+	}
+
+	l := token.Subset(NewLexer("<?php 1.2.3;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Error)
+}
+
+func TestMagicConstants(t *testing.T) {
+	for _, c := range []string{"__LINE__", "__FILE__", "__DIR__", "__FUNCTION__", "__CLASS__", "__METHOD__", "__NAMESPACE__", "__TRAIT__", "__line__"} {
+		l := token.Subset(NewLexer("<?php\necho "+c+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.Echo)
+		i := assertNext(t, l, token.MagicConstant)
+		assertItem(t, i, c)
+		if i.Begin.Line != 2 {
+			t.Errorf("%s was lexed on line %d, expected 2", c, i.Begin.Line)
+		}
+		assertNext(t, l, token.StatementEnd)
+	}
+
+	for _, ident := range []string{"__custom__", "__LINE__x"} {
+		l := token.Subset(NewLexer("<?php echo "+ident+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.Echo)
+		i := assertNext(t, l, token.Identifier)
+		assertItem(t, i, ident)
+	}
+}
+
+func TestMixedCaseKeywords(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php While ($x) { IF (True) { Echo NULL; } } FUNCTION MyFunc() {} $While = new MyClass;`), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.While)
+	assertNext(t, l, token.OpenParen)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.CloseParen)
+	assertNext(t, l, token.BlockBegin)
+	assertNext(t, l, token.If)
+	assertNext(t, l, token.OpenParen)
+	assertNext(t, l, token.BooleanLiteral)
+	assertNext(t, l, token.CloseParen)
+	assertNext(t, l, token.BlockBegin)
+	assertNext(t, l, token.Echo)
+	assertNext(t, l, token.Null)
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.BlockEnd)
+	assertNext(t, l, token.BlockEnd)
+	assertNext(t, l, token.Function)
+	assertItem(t, assertNext(t, l, token.Identifier), "MyFunc")
+	assertNext(t, l, token.OpenParen)
+	assertNext(t, l, token.CloseParen)
+	assertNext(t, l, token.BlockBegin)
+	assertNext(t, l, token.BlockEnd)
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "While")
+	assertNext(t, l, token.AssignmentOperator)
+	assertNext(t, l, token.NewOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "MyClass")
+	assertNext(t, l, token.StatementEnd)
+}
+
+func TestNullsafeCoalesceAssignment(t *testing.T) {
+	l := token.Subset(NewLexer("<?php $a?->b ??= $c;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.NullsafeObjectOperator)
+	assertNext(t, l, token.Identifier)
+	i := assertNext(t, l, token.AssignmentOperator)
+	assertItem(t, i, "??=")
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.StatementEnd)
+}
+
+func TestReturnTypes(t *testing.T) {
+	tests := []struct {
+		src, typ string
+	}{
+		{"<?php function f(): int {}", "int"},
+		{"<?php function f($a = array(1)) : ?MyClass {}", "?MyClass"},
+		{"<?php function f(int $a):void {}", "void"},
+		{"<?php function f(): \\Foo\\Bar {}", "\\Foo\\Bar"},
+		{"<?php function f(): self {}", "self"},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer(test.src), token.Significant)
+		var i token.Item
+		for i = l.Next(); i.Typ != token.TernaryOperator2; i = l.Next() {
+			if i.Typ == token.EOF {
+				t.Fatalf("no return type in %q", test.src)
+			}
+		}
+		i = assertNext(t, l, token.TypeHint)
+		assertItem(t, i, test.typ)
+		assertNext(t, l, token.BlockBegin)
+	}
+
+	// a closure's return type follows its use list
+	l := token.Subset(NewLexer("<?php $f = function($a) use ($b): int {};"), token.Significant)
+	for i := l.Next(); i.Typ != token.TernaryOperator2; i = l.Next() {
+		if i.Typ == token.EOF {
+			t.Fatal("no return type for closure")
+		}
+	}
+	assertItem(t, assertNext(t, l, token.TypeHint), "int")
+
+	// a colon anywhere else is still part of a ternary
+	l = token.Subset(NewLexer("<?php $a = f($b) ? g($c) : d;"), token.Significant)
+	for i := l.Next(); i.Typ != token.TernaryOperator2; i = l.Next() {
+		if i.Typ == token.EOF {
+			t.Fatal("no ternary operator")
+		}
+	}
+	assertItem(t, assertNext(t, l, token.Identifier), "d")
+}
+
+func TestUnionAndIntersectionTypes(t *testing.T) {
+	tests := []struct {
+		src   string
+		types []string
+	}{
+		{"<?php function f(int|string $a): int|false {}", []string{"int|string", "int|false"}},
+		{"<?php function f(A&B $a): A&B {}", []string{"A&B", "A&B"}},
+		{"<?php function f(?int $a, $b): ?MyClass {}", []string{"?int", "?MyClass"}},
+		{"<?php function f((A&B)|null $a): (A&B)|null {}", []string{"(A&B)|null", "(A&B)|null"}},
+		{"<?php function f(int | string ...$a): \\Foo\\Bar | null {}", []string{"int | string", "\\Foo\\Bar | null"}},
+		{"<?php function f(public A|B $a) {}", []string{"A|B"}},
+		{"<?php function f(A|B &$a) {}", []string{"A|B"}},
+		{"<?php class A { protected int|string $id; var A & B $b; readonly (A&B)|null $c; }", []string{"int|string", "A & B", "(A&B)|null"}},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer(test.src), token.Significant)
+		var found []string
+		for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+			if i.Typ == token.Error {
+				t.Fatalf("error lexing %q: %s", test.src, i)
+			}
+			if i.Typ == token.TypeHint {
+				found = append(found, i.Val)
+			}
+		}
+		if !reflect.DeepEqual(found, test.types) {
+			t.Errorf("lexed types %q from %q, expected %q", found, test.src, test.types)
+		}
+	}
+
+	// a by-reference parameter with a plain type is not an intersection
+	l := token.Subset(NewLexer("<?php function f(A & $a) {}"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Function)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.OpenParen)
+	assertItem(t, assertNext(t, l, token.Identifier), "A")
+	assertNext(t, l, token.ReferenceOperator)
+
+	// outside of a type position | is still bitwise or
+	l = token.Subset(NewLexer("<?php $a | $b;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.BitwiseOrOperator)
+}
+
+func TestNullableTypes(t *testing.T) {
+	tests := []struct {
+		src   string
+		types []string
+	}{
+		{"<?php function f(?int $x) {}", []string{"?int"}},
+		{"<?php function f(?\\App\\Model $m = null) {}", []string{"?\\App\\Model"}},
+		{"<?php class A { public ?int $x; private static ?\\App\\Model $m; var ?B $b; }", []string{"?int", "?\\App\\Model", "?B"}},
+		{"<?php class A { function __construct(protected ?int $x) {} }", []string{"?int"}},
+		{"<?php $y = $x ? $a : $b;", nil},
+		{"<?php f($x ? int : $b);", nil},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer(test.src), token.Significant)
+		var found []string
+		for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+			if i.Typ == token.Error {
+				t.Fatalf("error lexing %q: %s", test.src, i)
+			}
+			if i.Typ == token.TypeHint {
+				found = append(found, i.Val)
+			}
+		}
+		if !reflect.DeepEqual(found, test.types) {
+			t.Errorf("lexed types %q from %q, expected %q", found, test.src, test.types)
+		}
+	}
+
+	l := token.Subset(NewLexer("<?php $y = $x ? $a : $b;"), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.VariableOperator, token.Identifier,
+		token.AssignmentOperator,
+		token.VariableOperator, token.Identifier,
+		token.TernaryOperator1,
+		token.VariableOperator, token.Identifier,
+		token.TernaryOperator2,
+		token.VariableOperator, token.Identifier,
+		token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestShortEcho(t *testing.T) {
+	l := token.Subset(NewLexer("<?=\n  $x\n?>"), token.Significant)
+	assertItem(t, assertNext(t, l, token.PHPBegin), "<?=")
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.PHPEnd)
+}
+
+func TestEllipsis(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []token.Token
+	}{
+		{"<?php function f(...$a) {}", []token.Token{token.PHPBegin, token.Function, token.Identifier, token.OpenParen, token.Ellipsis, token.VariableOperator, token.Identifier, token.CloseParen}},
+		{"<?php f(...$b);", []token.Token{token.PHPBegin, token.Identifier, token.OpenParen, token.Ellipsis, token.VariableOperator, token.Identifier, token.CloseParen}},
+		{"<?php [...$x];", []token.Token{token.PHPBegin, token.ShortArrayLeft, token.Ellipsis, token.VariableOperator, token.Identifier, token.ShortArrayRight}},
+		{"<?php $a . $b . .5;", []token.Token{token.PHPBegin, token.VariableOperator, token.Identifier, token.ConcatenationOperator, token.VariableOperator, token.Identifier, token.ConcatenationOperator, token.NumberLiteral}},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer(test.src), token.Significant)
+		for _, typ := range test.tokens {
+			assertNext(t, l, typ)
+		}
+	}
+}
+
+func TestRecoveringLexer(t *testing.T) {
+	src := "<?php $a = 1 \x01 2;\n$b = 0b12;\necho $a;"
+
+	l := token.Subset(NewLexer(src), token.Significant)
+	for _, typ := range []token.Token{token.PHPBegin, token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.Error} {
+		assertNext(t, l, typ)
+	}
+	if i := l.Next(); i.Typ != token.EOF && i.Typ != 0 {
+		t.Fatalf("lexing continued after an error: %s", i)
+	}
+
+	l = token.Subset(NewRecoveringLexer(src), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral,
+		token.Error,
+		token.NumberLiteral, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.Error,
+		token.StatementEnd,
+		token.Echo, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.EOF,
+	} {
+		i := assertNext(t, l, typ)
+		if typ == token.Echo && i.Begin.Line != 3 {
+			t.Errorf("echo lexed on line %d after recovering, expected 3", i.Begin.Line)
+		}
+	}
+}
+
+func TestRecoverMissingReturnType(t *testing.T) {
+	for _, src := range []string{
+		`<?php function f(): {}`,
+		`<?php $f = function () use ($x):`,
+	} {
+		errors := 0
+		l := NewRecoveringLexer(src)
+		for i, n := l.Next(), 0; i.Typ != token.EOF; i, n = l.Next(), n+1 {
+			if n > len(src) {
+				t.Fatalf("%q: lexing did not reach the end of the input", src)
+			}
+			if i.Typ == token.Error {
+				errors++
+			}
+		}
+		if errors != 1 {
+			t.Errorf("%q: found %d errors, expected 1", src, errors)
+		}
+	}
+}
+
+func TestYield(t *testing.T) {
+	tests := []struct {
+		src    string
+		tokens []token.Token
+		val    string
+	}{
+		{"<?php yield;", []token.Token{token.Yield, token.StatementEnd}, "yield"},
+		{"<?php yield $v;", []token.Token{token.Yield, token.VariableOperator, token.Identifier, token.StatementEnd}, "yield"},
+		{"<?php yield $k => $v;", []token.Token{token.Yield, token.VariableOperator, token.Identifier, token.ArrayKeyOperator, token.VariableOperator, token.Identifier, token.StatementEnd}, "yield"},
+		{"<?php yield from $gen;", []token.Token{token.YieldFrom, token.VariableOperator, token.Identifier, token.StatementEnd}, "yield from"},
+		{"<?php YIELD\n  FROM gen();", []token.Token{token.YieldFrom, token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd}, "YIELD\n  FROM"},
+		{"<?php yield fromage;", []token.Token{token.Yield, token.Identifier, token.StatementEnd}, "yield"},
+		{"<?php yields;", []token.Token{token.Identifier, token.StatementEnd}, "yields"},
+	}
+	for _, test := range tests {
+		l := token.Subset(NewLexer(test.src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, test.tokens[0])
+		assertItem(t, i, test.val)
+		for _, typ := range test.tokens[1:] {
+			assertNext(t, l, typ)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	l := token.Subset(NewLexer("<?php $b = clone $a; clone (new Foo); $cloned;"), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.Clone, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.Clone, token.OpenParen, token.NewOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php print "hi"; $x = print "hi"; print_r($arr); echo "hi";`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Print, token.DoubleQuotedString, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.Print, token.DoubleQuotedString, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.Identifier), "print_r")
+	for _, typ := range []token.Token{
+		token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		token.Echo, token.DoubleQuotedString, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestLanguageConstructs(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php isset($a); EMPTY($b); unset($c); list(, $d) = $e; issetFoo(); $empty;`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Isset, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		token.Empty, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		token.Unset, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		token.List, token.OpenParen, token.Comma, token.VariableOperator, token.Identifier, token.CloseParen,
+		token.AssignmentOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.Identifier), "issetFoo")
+	assertNext(t, l, token.OpenParen)
+	assertNext(t, l, token.CloseParen)
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "empty")
+}
+
+func TestExit(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php die; die("bye"); exit; exit(1); DIE();`), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertItem(t, assertNext(t, l, token.Exit), "die")
+	assertNext(t, l, token.StatementEnd)
+	assertItem(t, assertNext(t, l, token.Exit), "die")
+	for _, typ := range []token.Token{token.OpenParen, token.DoubleQuotedString, token.CloseParen, token.StatementEnd} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.Exit), "exit")
+	assertNext(t, l, token.StatementEnd)
+	assertItem(t, assertNext(t, l, token.Exit), "exit")
+	for _, typ := range []token.Token{token.OpenParen, token.NumberLiteral, token.CloseParen, token.StatementEnd} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.Exit), "DIE")
+}
+
+func TestPipeOperator(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php $x |> $f; $a | $b; $a |= $b; $a || $b;`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.VariableOperator, token.Identifier, token.PipeOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.BitwiseOrOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.VariableOperator, token.Identifier, token.OrOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestConst(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php const BAR = 2; class A { public const FOO = 1; const int|string BAZ = 3; }`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Const, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd,
+		token.Class, token.Identifier, token.BlockBegin,
+		token.Public, token.Const, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd,
+		token.Const,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.TypeHint), "int|string")
+	assertItem(t, assertNext(t, l, token.Identifier), "BAZ")
+}
+
+func TestFinalAndReadonly(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php final class A { final public function f() {} public readonly ?int $x; }`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Final, token.Class, token.Identifier, token.BlockBegin,
+		token.Final, token.Public, token.Function, token.Identifier, token.OpenParen, token.CloseParen, token.BlockBegin, token.BlockEnd,
+		token.Public, token.Readonly,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.TypeHint), "?int")
+	assertNext(t, l, token.VariableOperator)
+}
+
+func TestConstructorPromotion(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php function __construct(private int $x, public readonly ?string $y = null) {}`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Function, token.Identifier, token.OpenParen,
+		token.Private, token.Identifier, token.VariableOperator, token.Identifier, token.Comma,
+		token.Public, token.Readonly,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertItem(t, assertNext(t, l, token.TypeHint), "?string")
+	for _, typ := range []token.Token{
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.Null, token.CloseParen,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestAnonymousClass(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php new class(1) extends Base implements I {};`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.NewOperator, token.Class, token.OpenParen, token.NumberLiteral, token.CloseParen,
+		token.Extends, token.Identifier, token.Implements, token.Identifier,
+		token.BlockBegin, token.BlockEnd, token.StatementEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestDeclare(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php declare(strict_types=1); declare(ticks=1, encoding='UTF-8') {}`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Declare, token.OpenParen, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.CloseParen, token.StatementEnd,
+		token.Declare, token.OpenParen, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.Comma,
+		token.Identifier, token.AssignmentOperator, token.SingleQuotedString, token.CloseParen, token.BlockBegin, token.BlockEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestGotoAndLabels(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php goto end; end: $x ? a : b; switch ($x) { case A: B::c(); }`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.Goto, token.Identifier, token.StatementEnd,
+		token.Label, token.TernaryOperator2,
+		token.VariableOperator, token.Identifier, token.TernaryOperator1, token.Identifier, token.TernaryOperator2, token.Identifier, token.StatementEnd,
+		token.Switch, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.BlockBegin,
+		token.Case, token.Identifier, token.TernaryOperator2,
+		token.Identifier, token.ScopeResolutionOperator,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestAlternateSyntax(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php if ($a): $b ? 1 : 2; elseif (f($c)): else: endif; foreach ($d as $e): endforeach;`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.If, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.AlternateBlockBegin,
+		token.VariableOperator, token.Identifier, token.TernaryOperator1, token.NumberLiteral, token.TernaryOperator2, token.NumberLiteral, token.StatementEnd,
+		token.ElseIf, token.OpenParen, token.Identifier, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.CloseParen, token.AlternateBlockBegin,
+		token.Else, token.AlternateBlockBegin,
+		token.EndIf,
+		token.Foreach, token.OpenParen, token.VariableOperator, token.Identifier, token.AsOperator, token.VariableOperator, token.Identifier, token.CloseParen, token.AlternateBlockBegin,
+		token.EndForeach,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php $a =& $b; function f(array &$x) {} foreach ($a as $k => &$v) {} $c = $a & $b;`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.Function, token.Identifier, token.OpenParen, token.Array, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.CloseParen, token.BlockBegin, token.BlockEnd,
+		token.Foreach, token.OpenParen, token.VariableOperator, token.Identifier, token.AsOperator, token.VariableOperator, token.Identifier,
+		token.ArrayKeyOperator, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.CloseParen, token.BlockBegin, token.BlockEnd,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator, token.VariableOperator, token.Identifier, token.AmpersandOperator,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestParameterDefaults(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php function f(int $x = 5, ?string $name = null, array &$opts = [], $c = self::FOO) {}`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Function, token.Identifier, token.OpenParen,
+		token.Identifier, token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.Comma,
+		token.TypeHint, token.VariableOperator, token.Identifier, token.AssignmentOperator, token.Null, token.Comma,
+		token.Array, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.ShortArrayLeft, token.ShortArrayRight, token.Comma,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.Self, token.ScopeResolutionOperator, token.Identifier, token.CloseParen,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestBitwiseAndInDefaults(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php function f(Foo &$x, int $a = A & B, $b = [A & B], self &$c) {}`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Function, token.Identifier, token.OpenParen,
+		token.Identifier, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.Comma,
+		token.Identifier, token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.Identifier, token.AmpersandOperator, token.Identifier, token.Comma,
+		token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.ShortArrayLeft, token.Identifier, token.AmpersandOperator, token.Identifier, token.ShortArrayRight, token.Comma,
+		token.Self, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.CloseParen,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestPreserveTrivia(t *testing.T) {
+	tests := []string{
+		testFile,
+		`<html>
+  <?php   # a comment ?>
+	<?php
+    /**
+     * A doc comment.
+     */
+	function f( $a,	$b ) {  // trailing
+		return $a /* inline */ + $b;
+	}
+?>
+</html>
+`,
+		"<?php $a = 1 \x01 2;\n$b = 0b12;\n\techo $a;",
+	}
+	for _, src := range tests {
+		l := NewLexer(src, PreserveTrivia)
+		buf := &bytes.Buffer{}
+		for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+			if src[i.Begin.Position:i.End.Position] != i.Val {
+				t.Errorf("item %s does not hold the input it was lexed from, %q", i, src[i.Begin.Position:i.End.Position])
+			}
+			buf.WriteString(i.Val)
+		}
+		if buf.String() != src {
+			t.Errorf("reassembled input did not match\nFound\n\n%s\n\nExpected\n\n%s\n", buf.String(), src)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		src  string
+		typ  token.Token
+		name string
+	}{
+		{`'a\'b'`, token.SingleQuotedString, "single-quoted-string"},
+		{`"a\"$b"`, token.DoubleQuotedString, "double-quoted-string"},
+		{"<<<EOT\na $b\nEOT", token.Heredoc, "heredoc"},
+		{"<<<\"EOT\"\na $b\nEOT", token.Heredoc, "heredoc"},
+		{"<<<'EOT'\na $b\nEOT", token.Nowdoc, "nowdoc"},
+		{"<<<EOT\n  abc\n  EOT", token.Heredoc, "heredoc"},
+		{"<<<EOT\nEOTX\n\tEOT", token.Heredoc, "heredoc"},
+		{`b"bytes"`, token.DoubleQuotedString, "double-quoted-string"},
+		{`B'raw'`, token.SingleQuotedString, "single-quoted-string"},
+	}
+	for _, tt := range tests {
+		l := token.Subset(NewLexer("<?php "+tt.src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertItem(t, assertNext(t, l, tt.typ), tt.src)
+		if tt.typ.String() != tt.name {
+			t.Errorf("%s named %q, expected %q", tt.src, tt.typ, tt.name)
+		}
+	}
+}
+
+func TestUnterminatedDocs(t *testing.T) {
+	tests := map[string]string{
+		"<?php <<<EOT\nabc":   "unterminated heredoc at line 1, col 7",
+		"<?php <<<'EOT'\nabc": "unterminated nowdoc at line 1, col 7",
+	}
+	for src, expected := range tests {
+		if _, err := Tokens(src); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected %q, found %v", src, expected, err)
+		}
+		for _, l := range []token.Stream{NewLexer(src, PreserveTrivia), NewRecoveringLexer(src)} {
+			errors := 0
+			for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+				if i.Typ == token.Error {
+					errors++
+				}
+			}
+			if errors != 1 {
+				t.Errorf("%q: found %d errors, expected 1", src, errors)
+			}
+		}
+	}
+}
+
+func BenchmarkLexLargeFile(b *testing.B) {
+	files, err := filepath.Glob("testdata/tokenizer/*.php")
+	if err != nil {
+		b.Fatal(err)
+	}
+	src := &bytes.Buffer{}
+	for i := 0; i < 50; i++ {
+		for _, f := range files {
+			content, err := ioutil.ReadFile(f)
+			if err != nil {
+				b.Fatal(err)
+			}
+			src.Write(content)
+			// leave PHP mode, if in it, before the next file begins
+			src.WriteString("\n?>\n")
+		}
+	}
+	input := src.String()
+
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(input)
+		for item := l.Next(); item.Typ != token.EOF; item = l.Next() {
+			if item.Typ == token.Error {
+				b.Fatal(item)
+			}
+		}
+	}
+}
+
+func TestMultiCharacterOperators(t *testing.T) {
+	for s, typ := range token.TokenMap {
+		if !typ.IsOperator() || len(s) == 1 {
+			continue
+		}
+		l := token.Subset(NewLexer("<?php $a "+s+" $b;"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.VariableOperator)
+		assertNext(t, l, token.Identifier)
+		if i := l.Next(); i.Typ != typ || i.Val != s {
+			t.Errorf("%q lexed as %s", s, i)
+		}
+	}
+}
+
+func TestFileBegin(t *testing.T) {
+	l := NewLexer("\ufeff<?php echo 1;")
+	i := assertNext(t, l, token.Space)
+	assertItem(t, i, "\ufeff")
+	i = assertNext(t, l, token.PHPBegin)
+	if i.Begin.Position != 3 || i.Begin.Line != 1 {
+		t.Errorf("<?php at %v, expected byte 3 of line 1", i.Begin)
+	}
+
+	l = NewLexer("#!/usr/bin/env php\n<?php\necho 1;")
+	i = assertNext(t, l, token.CommentLine)
+	assertItem(t, i, "#!/usr/bin/env php\n")
+	i = assertNext(t, l, token.PHPBegin)
+	if i.Begin.Position != 19 || i.Begin.Line != 2 {
+		t.Errorf("<?php at %v, expected byte 19 of line 2", i.Begin)
+	}
+	assertNext(t, l, token.Space)
+	i = assertNext(t, l, token.Echo)
+	if i.Begin.Line != 3 {
+		t.Errorf("echo at %v, expected line 3", i.Begin)
+	}
+
+	// a shebang is only recognized at the beginning of a file
+	l = NewLexer("\n#!/usr/bin/env php\n")
+	assertItem(t, assertNext(t, l, token.HTML), "\n#!/usr/bin/env php\n")
+}
+
+func TestPHPIslands(t *testing.T) {
+	src := "<ul>\n<?php foreach ($a as $v): ?>\n  <li><?= $v ?></li><?php ?><?PHP endforeach; ?>\n</ul>\n<?xml?>"
+	l := NewLexer(src, NoShortTags)
+	expected := []token.Item{
+		{Typ: token.HTML, Val: "<ul>\n"},
+		{Typ: token.PHPBegin, Val: "<?php"},
+		{Typ: token.Foreach, Val: "foreach"},
+		{Typ: token.OpenParen, Val: "("},
+		{Typ: token.VariableOperator, Val: "$"},
+		{Typ: token.Identifier, Val: "a"},
+		{Typ: token.AsOperator, Val: "as"},
+		{Typ: token.VariableOperator, Val: "$"},
+		{Typ: token.Identifier, Val: "v"},
+		{Typ: token.CloseParen, Val: ")"},
+		{Typ: token.AlternateBlockBegin, Val: ":"},
+		{Typ: token.PHPEnd, Val: "?>"},
+		{Typ: token.HTML, Val: "\n  <li>"},
+		{Typ: token.PHPBegin, Val: "<?="},
+		{Typ: token.VariableOperator, Val: "$"},
+		{Typ: token.Identifier, Val: "v"},
+		{Typ: token.PHPEnd, Val: "?>"},
+		{Typ: token.HTML, Val: "</li>"},
+		{Typ: token.PHPBegin, Val: "<?php"},
+		{Typ: token.PHPEnd, Val: "?>"},
+		{Typ: token.PHPBegin, Val: "<?PHP"},
+		{Typ: token.EndForeach, Val: "endforeach;"},
+		{Typ: token.PHPEnd, Val: "?>"},
+		{Typ: token.HTML, Val: "\n</ul>\n<?xml?>"},
+	}
+	var found []token.Item
+	s := token.Subset(l, token.Significant)
+	for i := s.Next(); i.Typ != token.EOF; i = s.Next() {
+		found = append(found, i)
+	}
+	if !token.TokensEqual(found, expected) {
+		t.Errorf("unexpected items\nFound\n%v\nExpected\n%v", found, expected)
+	}
+
+	// with short tags, <? begins PHP code
+	l = NewLexer("<p><?xml?>")
+	assertNext(t, l, token.HTML)
+	assertItem(t, assertNext(t, l, token.PHPBegin), "<?")
+}
+
+func TestNamedArguments(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php f($a, flag: true, array: [], $x ? 1 : 2, B::C);`), token.Significant)
+	expected := []token.Item{
+		{Typ: token.PHPBegin, Val: "<?php"},
+		{Typ: token.Identifier, Val: "f"},
+		{Typ: token.OpenParen, Val: "("},
+		{Typ: token.VariableOperator, Val: "$"},
+		{Typ: token.Identifier, Val: "a"},
+		{Typ: token.Comma, Val: ","},
+		{Typ: token.ArgumentName, Val: "flag"},
+		{Typ: token.TernaryOperator2, Val: ":"},
+		{Typ: token.BooleanLiteral, Val: "true"},
+		{Typ: token.Comma, Val: ","},
+		{Typ: token.ArgumentName, Val: "array"},
+		{Typ: token.TernaryOperator2, Val: ":"},
+		{Typ: token.ShortArrayLeft, Val: "["},
+		{Typ: token.ShortArrayRight, Val: "]"},
+		{Typ: token.Comma, Val: ","},
+		{Typ: token.VariableOperator, Val: "$"},
+		{Typ: token.Identifier, Val: "x"},
+		{Typ: token.TernaryOperator1, Val: "?"},
+		{Typ: token.NumberLiteral, Val: "1"},
+		{Typ: token.TernaryOperator2, Val: ":"},
+		{Typ: token.NumberLiteral, Val: "2"},
+		{Typ: token.Comma, Val: ","},
+		{Typ: token.Identifier, Val: "B"},
+		{Typ: token.ScopeResolutionOperator, Val: "::"},
+		{Typ: token.Identifier, Val: "C"},
+		{Typ: token.CloseParen, Val: ")"},
+		{Typ: token.StatementEnd, Val: ";"},
+	}
+	var found []token.Item
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+		found = append(found, i)
+	}
+	if !token.TokensEqual(found, expected) {
+		t.Errorf("unexpected items\nFound\n%v\nExpected\n%v", found, expected)
+	}
+
+	l = token.Subset(NewLexer(`<?php f(X ? 1 : 2, name: 3); g(name: 1);`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Identifier, token.OpenParen,
+		token.Identifier, token.TernaryOperator1, token.NumberLiteral, token.TernaryOperator2, token.NumberLiteral, token.Comma,
+		token.ArgumentName, token.TernaryOperator2, token.NumberLiteral, token.CloseParen, token.StatementEnd,
+		token.Identifier, token.OpenParen, token.ArgumentName, token.TernaryOperator2, token.NumberLiteral, token.CloseParen,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := map[string][]token.Token{
+		`f(1, 2,)`: {
+			token.Identifier, token.OpenParen, token.NumberLiteral, token.Comma, token.NumberLiteral, token.Comma, token.CloseParen,
+		},
+		`[1, 2,]`: {
+			token.ShortArrayLeft, token.NumberLiteral, token.Comma, token.NumberLiteral, token.Comma, token.ShortArrayRight,
+		},
+		`function f($a, $b,) {}`: {
+			token.Function, token.Identifier, token.OpenParen,
+			token.VariableOperator, token.Identifier, token.Comma,
+			token.VariableOperator, token.Identifier, token.Comma,
+			token.CloseParen, token.BlockBegin, token.BlockEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestScopeResolution(t *testing.T) {
+	tests := map[string][]token.Token{
+		`Foo::$bar`:      {token.Identifier, token.ScopeResolutionOperator, token.VariableOperator, token.Identifier},
+		`Foo::BAZ`:       {token.Identifier, token.ScopeResolutionOperator, token.Identifier},
+		`Foo::DEFAULT`:   {token.Identifier, token.ScopeResolutionOperator, token.Identifier},
+		`Foo::method()`:  {token.Identifier, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`Foo::print()`:   {token.Identifier, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`Foo::class`:     {token.Identifier, token.ScopeResolutionOperator, token.Class},
+		`$foo::BAZ`:      {token.VariableOperator, token.Identifier, token.ScopeResolutionOperator, token.Identifier},
+		`self::$bar`:     {token.Self, token.ScopeResolutionOperator, token.VariableOperator, token.Identifier},
+		`parent::f()`:    {token.Parent, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`static::BAZ`:    {token.Static, token.ScopeResolutionOperator, token.Identifier},
+		`static::$bar`:   {token.Static, token.ScopeResolutionOperator, token.VariableOperator, token.Identifier},
+		`static::make()`: {token.Static, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`$o->list`:       {token.VariableOperator, token.Identifier, token.ObjectOperator, token.Identifier},
+		`$o?->class`:     {token.VariableOperator, token.Identifier, token.NullsafeObjectOperator, token.Identifier},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.StatementEnd)
+	}
+}
+
+func TestClassReferences(t *testing.T) {
+	tests := map[string][]token.Token{
+		`$this->x`:               {token.VariableOperator, token.This, token.ObjectOperator, token.Identifier},
+		`$thisOne->x`:            {token.VariableOperator, token.Identifier, token.ObjectOperator, token.Identifier},
+		`$o->this`:               {token.VariableOperator, token.Identifier, token.ObjectOperator, token.Identifier},
+		`self::CONST`:            {token.Self, token.ScopeResolutionOperator, token.Identifier},
+		`parent::__construct()`:  {token.Parent, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`static::create()`:       {token.Static, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen},
+		`new static`:             {token.NewOperator, token.Static},
+		`static function f() {}`: {token.Static, token.Function, token.Identifier, token.OpenParen, token.CloseParen, token.BlockBegin, token.BlockEnd},
+		`$f = static fn() => $a`: {token.VariableOperator, token.Identifier, token.AssignmentOperator, token.Static, token.Identifier, token.OpenParen, token.CloseParen, token.ArrayKeyOperator, token.VariableOperator, token.Identifier},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.StatementEnd)
+	}
+}
+
+func TestAbstractMethods(t *testing.T) {
+	tests := map[string][]token.Token{
+		`abstract class A { abstract public function f(): int; }`: {
+			token.Abstract, token.Class, token.Identifier, token.BlockBegin,
+			token.Abstract, token.Public, token.Function, token.Identifier, token.OpenParen, token.CloseParen,
+			token.TernaryOperator2, token.TypeHint, token.StatementEnd,
+			token.BlockEnd,
+		},
+		`interface I { function f(?int $a); }`: {
+			token.Interface, token.Identifier, token.BlockBegin,
+			token.Function, token.Identifier, token.OpenParen, token.TypeHint, token.VariableOperator, token.Identifier, token.CloseParen,
+			token.StatementEnd,
+			token.BlockEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestMultipleInheritance(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php interface A extends \B, C {} class D extends E implements A, F\G {}`), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	for _, typ := range []token.Token{
+		token.Interface, token.Identifier, token.Extends, token.Identifier, token.Comma, token.Identifier,
+		token.BlockBegin, token.BlockEnd,
+		token.Class, token.Identifier, token.Extends, token.Identifier,
+		token.Implements, token.Identifier, token.Comma, token.Identifier,
+		token.BlockBegin, token.BlockEnd,
+	} {
+		assertNext(t, l, typ)
+	}
+	assertNext(t, l, token.EOF)
+}
+
+func TestMemberConstants(t *testing.T) {
+	constants := []token.Token{
+		token.Const, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd,
+		token.Public, token.Const, token.TypeHint, token.Identifier, token.AssignmentOperator, token.NumberLiteral,
+		token.Comma, token.Identifier, token.AssignmentOperator, token.Self, token.ScopeResolutionOperator, token.Identifier,
+		token.StatementEnd,
+	}
+	body := `{ const X = 1; public const int Y = 2, Z = self::X; }`
+	tests := map[string][]token.Token{
+		`class C ` + body:     {token.Class, token.Identifier},
+		`interface I ` + body: {token.Interface, token.Identifier},
+		`trait T ` + body:     {token.Trait, token.Identifier},
+		`enum E: int ` + body: {token.Identifier, token.Identifier, token.TernaryOperator2, token.Identifier},
+	}
+	for src, declaration := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range declaration {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.BlockBegin)
+		for _, typ := range constants {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.BlockEnd)
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestUseImports(t *testing.T) {
+	tests := map[string][]token.Token{
+		`use Foo\{Bar, Baz as Qux};`: {
+			token.Use, token.Identifier, token.BlockBegin,
+			token.Identifier, token.Comma, token.Identifier, token.AsOperator, token.Identifier,
+			token.BlockEnd, token.StatementEnd,
+		},
+		`use Foo\Bar as B;`:     {token.Use, token.Identifier, token.AsOperator, token.Identifier, token.StatementEnd},
+		`use function Foo\bar;`: {token.Use, token.Function, token.Identifier, token.StatementEnd},
+		`use const Foo\BAR;`:    {token.Use, token.Const, token.Identifier, token.StatementEnd},
+		`use const Foo\BAR as BAZ;`: {
+			token.Use, token.Const, token.Identifier, token.AsOperator, token.Identifier, token.StatementEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	items, err := Tokens(`<?php echo 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []token.Token{token.PHPBegin, token.Space, token.Echo, token.Space, token.NumberLiteral, token.StatementEnd, token.EOF}
+	if len(items) != len(expected) {
+		t.Fatalf("expected %d items, found %d: %v", len(expected), len(items), items)
+	}
+	for i, typ := range expected {
+		if items[i].Typ != typ {
+			t.Errorf("item %d is %s, expected %s", i, items[i].Typ, typ)
+		}
+	}
+
+	items, err = Tokens(`<?php $a = 0x;`)
+	if err == nil {
+		t.Fatal("expected an error for a malformed number literal")
+	}
+	if len(items) == 0 || items[len(items)-1].Typ == token.EOF {
+		t.Errorf("expected the items before the error, found %v", items)
+	}
+}
+
+func TestWalkTokens(t *testing.T) {
+	src := "<?php\nfunction f($a) { return $a + 1; } // done\necho f(2);\n"
+	items, err := Tokens(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []token.Item
+	if err := WalkTokens(src, func(i token.Item) bool {
+		found = append(found, i)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, items) {
+		t.Fatalf("walked %v, expected %v", found, items)
+	}
+
+	calls := 0
+	err = WalkTokens(`<?php $a = 0x;`, func(i token.Item) bool {
+		calls++
+		return true
+	})
+	if err == nil {
+		t.Fatal("expected an error for a malformed number literal")
+	}
+	if calls == 0 {
+		t.Error("expected the items before the error to be walked")
+	}
+}
+
+func TestWalkTokensStops(t *testing.T) {
+	src := "<?php\n" + strings.Repeat("$a = $b . 'c';\n", 100000)
+	calls := 0
+	err := WalkTokens(src, func(i token.Item) bool {
+		calls++
+		return calls < 5
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 5 {
+		t.Errorf("expected 5 calls, found %d", calls)
+	}
+
+	// stopping must not lex the rest of the input
+	allocs := testing.AllocsPerRun(10, func() {
+		WalkTokens(src, func(token.Item) bool { return false })
+	})
+	if allocs > 50 {
+		t.Errorf("expected stopping after one item to allocate little, found %v allocations", allocs)
+	}
+}
+
+func TestFilterTrivia(t *testing.T) {
+	src := "<?php\n// add one\n$a = /* inline */ $b + 1; # done\n"
+	items, err := Tokens(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := token.FilterTrivia(items)
+
+	var all, significant []token.Token
+	for _, i := range items {
+		all = append(all, i.Typ)
+		if i.Typ.Type().Is(token.Significant) || i.Typ == token.EOF {
+			significant = append(significant, i.Typ)
+		}
+	}
+	var found []token.Token
+	for _, i := range filtered {
+		found = append(found, i.Typ)
+	}
+	expected := []token.Token{
+		token.PHPBegin, token.VariableOperator, token.Identifier, token.AssignmentOperator,
+		token.VariableOperator, token.Identifier, token.AdditionOperator, token.NumberLiteral,
+		token.StatementEnd, token.EOF,
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Fatalf("filtered %v\nto %v, expected %v", all, found, expected)
+	}
+	if !reflect.DeepEqual(found, significant) {
+		t.Errorf("filtered items %v differ from the significant items %v", found, significant)
+	}
+	if len(items) == len(filtered) {
+		t.Errorf("expected trivia in %v", all)
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	tests := map[string]string{
+		"<?php\n$a = 'abc\ndef": "unterminated single-quoted string at line 2, col 6",
+		"<?php\n$a = \"abc\\\"": "unterminated double-quoted string at line 2, col 6",
+		"<?php\n$a = `ls \\`":   "unterminated shell command at line 2, col 6",
+	}
+	for src, message := range tests {
+		items, err := Tokens(src)
+		if err == nil || err.Error() != message {
+			t.Errorf("lexing %q: expected error %q, found %v", src, message, err)
+			continue
+		}
+		l := NewLexer(src)
+		i := l.Next()
+		for ; i.Typ != token.Error && i.Typ != token.EOF; i = l.Next() {
+		}
+		quote := strings.IndexAny(src, "'\"`")
+		if i.Typ != token.Error || i.Begin.Position != quote || i.Begin.Line != 2 {
+			t.Errorf("lexing %q: expected an error at the opening quote, found %v at %v", src, i, i.Begin)
+		}
+		if last := items[len(items)-1]; last.Typ != token.AssignmentOperator && last.Typ != token.Space {
+			t.Errorf("lexing %q: expected the items before the string, found %v", src, items)
+		}
+
+		var buf bytes.Buffer
+		l = NewLexer(src, PreserveTrivia)
+		for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+			buf.WriteString(i.Val)
+		}
+		if buf.String() != src {
+			t.Errorf("lexing %q with trivia: found %q", src, buf.String())
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	src := "<?php\necho 1; /* never\nclosed"
+	items, err := Tokens(src)
+	if err == nil || err.Error() != "unterminated block comment at line 2, col 9" {
+		t.Fatalf("expected an unterminated block comment error, found %v", err)
+	}
+	if last := items[len(items)-1]; last.Typ != token.Space {
+		t.Errorf("expected the items before the comment, found %v", items)
+	}
+	l := NewLexer(src)
+	i := l.Next()
+	for ; i.Typ != token.Error && i.Typ != token.EOF; i = l.Next() {
+	}
+	if i.Begin.Position != strings.Index(src, "/*") || i.Begin.Line != 2 {
+		t.Errorf("expected an error at the start of the comment, found %v at %v", i, i.Begin)
+	}
+
+	var buf bytes.Buffer
+	l = NewLexer(src, PreserveTrivia)
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+		buf.WriteString(i.Val)
+	}
+	if buf.String() != src {
+		t.Errorf("lexing with trivia: found %q", buf.String())
+	}
+
+	items, err = Tokens("<?php /* closed\n*/ echo 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[2].Typ != token.CommentBlock || items[2].Val != "/* closed\n*/" {
+		t.Errorf("expected a block comment, found %v", items[2])
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := map[string]string{
+		"<?php\n\n  $a = 1 \u00a7;":            `unexpected character '§' at line 3, col 10`,
+		"<?php\nfunction f():\n  {}":           `expected return type, found '{' at line 3, col 3`,
+		"<?php\nfunction f(): ":                `expected return type, found end of file at line 2, col 15`,
+		"<?php $a = 0b102;":                    `invalid digit '2' in binary literal "0b102" at line 1, col 12`,
+		"<?php\n$s = 'x';\n$t = 'never closed": `unterminated single-quoted string at line 3, col 6`,
+	}
+	for src, expected := range tests {
+		_, err := Tokens(src)
+		if err == nil || err.Error() != expected {
+			t.Errorf("lexing %q: expected error %q, found %v", src, expected, err)
+		}
+	}
+}
+
+func TestInstanceof(t *testing.T) {
+	tests := map[string][]token.Token{
+		`Foo`:           {token.Identifier},
+		`\Foo\Bar`:      {token.Identifier},
+		`namespace\Foo`: {token.Identifier},
+		`$class`:        {token.VariableOperator, token.Identifier},
+		`self`:          {token.Self},
+		`static`:        {token.Static},
+		`parent`:        {token.Parent},
+	}
+	for operand, expected := range tests {
+		l := token.Subset(NewLexer("<?php $x instanceof "+operand+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.VariableOperator)
+		assertNext(t, l, token.Identifier)
+		assertNext(t, l, token.InstanceofOperator)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.StatementEnd)
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	tests := map[string][]token.Token{
+		`$a[1][2]['k'];`: {
+			token.VariableOperator, token.Identifier,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.ArrayLookupOperatorLeft, token.SingleQuotedString, token.ArrayLookupOperatorRight,
+			token.StatementEnd,
+		},
+		`$s{0};`: {
+			token.VariableOperator, token.Identifier,
+			token.CurlyLookupOperatorLeft, token.NumberLiteral, token.CurlyLookupOperatorRight,
+			token.StatementEnd,
+		},
+		`$s{$i{1}}[2]{3};`: {
+			token.VariableOperator, token.Identifier, token.CurlyLookupOperatorLeft,
+			token.VariableOperator, token.Identifier, token.CurlyLookupOperatorLeft, token.NumberLiteral, token.CurlyLookupOperatorRight,
+			token.CurlyLookupOperatorRight,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.CurlyLookupOperatorLeft, token.NumberLiteral, token.CurlyLookupOperatorRight,
+			token.StatementEnd,
+		},
+		`if ($s) { $s{0}; }`: {
+			token.If, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.BlockBegin,
+			token.VariableOperator, token.Identifier, token.CurlyLookupOperatorLeft, token.NumberLiteral, token.CurlyLookupOperatorRight,
+			token.StatementEnd, token.BlockEnd,
+		},
+		`${'a'}; $o->{'b'};`: {
+			token.VariableOperator, token.BlockBegin, token.SingleQuotedString, token.BlockEnd, token.StatementEnd,
+			token.VariableOperator, token.Identifier, token.ObjectOperator, token.BlockBegin, token.SingleQuotedString, token.BlockEnd,
+			token.StatementEnd,
+		},
+		`class A { public int $a { get; } }`: {
+			token.Class, token.Identifier, token.BlockBegin,
+			token.Public, token.Identifier, token.VariableOperator, token.Identifier, token.BlockBegin, token.Identifier, token.StatementEnd, token.BlockEnd,
+			token.BlockEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestShortArrays(t *testing.T) {
+	tests := map[string][]token.Token{
+		`$x = [1, 2];`: {
+			token.VariableOperator, token.Identifier, token.AssignmentOperator,
+			token.ShortArrayLeft, token.NumberLiteral, token.Comma, token.NumberLiteral, token.ShortArrayRight,
+			token.StatementEnd,
+		},
+		`$x[0] = 1;`: {
+			token.VariableOperator, token.Identifier,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.AssignmentOperator, token.NumberLiteral, token.StatementEnd,
+		},
+		`[$a[0], [$b]] = f()[1];`: {
+			token.ShortArrayLeft,
+			token.VariableOperator, token.Identifier, token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.Comma, token.ShortArrayLeft, token.VariableOperator, token.Identifier, token.ShortArrayRight,
+			token.ShortArrayRight, token.AssignmentOperator,
+			token.Identifier, token.OpenParen, token.CloseParen, token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.StatementEnd,
+		},
+		`['a'][0] . 'b'[0] . FOO[0];`: {
+			token.ShortArrayLeft, token.SingleQuotedString, token.ShortArrayRight,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.ConcatenationOperator,
+			token.SingleQuotedString, token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.ConcatenationOperator,
+			token.Identifier, token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.StatementEnd,
+		},
+		`if ($a) [$b] = $c;`: {
+			token.If, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen,
+			token.ShortArrayLeft, token.VariableOperator, token.Identifier, token.ShortArrayRight,
+			token.AssignmentOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestGlobalAndStaticDeclarations(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php function f() { global $a, $b; static $n = 0, $m, $k = [1]; }`), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Function, token.Identifier, token.OpenParen, token.CloseParen, token.BlockBegin,
+		token.Global, token.VariableOperator, token.Identifier, token.Comma, token.VariableOperator, token.Identifier, token.StatementEnd,
+		token.Static, token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral,
+		token.Comma, token.VariableOperator, token.Identifier,
+		token.Comma, token.VariableOperator, token.Identifier, token.AssignmentOperator, token.ShortArrayLeft, token.NumberLiteral, token.ShortArrayRight,
+		token.StatementEnd,
+		token.BlockEnd, token.EOF,
+	} {
+		assertNext(t, l, typ)
+	}
+}
+
+func TestBinaryStringPrefix(t *testing.T) {
+	l := token.Subset(NewLexer(`<?php echo b "x", $b, $o->b, b'y';`), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Echo)
+	assertItem(t, assertNext(t, l, token.Identifier), "b")
+	assertItem(t, assertNext(t, l, token.DoubleQuotedString), `"x"`)
+	assertNext(t, l, token.Comma)
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "b")
+	assertNext(t, l, token.Comma)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.ObjectOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "b")
+	assertNext(t, l, token.Comma)
+	assertItem(t, assertNext(t, l, token.SingleQuotedString), "b'y'")
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.EOF)
+}
+
+func TestLineEndings(t *testing.T) {
+	src := "<html>\n<?php\n// a comment\n$a = 1;\n/* a\nblock */\necho \"a\nb\";\n?>\n</html>\n<?php\nfoo();"
+	lines := func(src string) []int {
+		var lines []int
+		l := NewLexer(src)
+		for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+			if i.Typ == token.Error {
+				t.Fatal(i.Val)
+			}
+			if i.Typ != token.Space {
+				lines = append(lines, i.Begin.Line)
+			}
+		}
+		return lines
+	}
+	expected := lines(src)
+	if last := expected[len(expected)-1]; last != 12 {
+		t.Fatalf("last token on line %d, expected 12", last)
+	}
+	for _, nl := range []string{"\r\n", "\r"} {
+		if found := lines(strings.Replace(src, "\n", nl, -1)); !reflect.DeepEqual(found, expected) {
+			t.Errorf("%q line endings: lexed on lines %v, expected %v", nl, found, expected)
+		}
+	}
+
+	_, err := Tokens("<?php\r\n\r\n  'x")
+	if err == nil || err.Error() != "unterminated single-quoted string at line 3, col 3" {
+		t.Errorf("error %v, expected it at line 3, col 3", err)
+	}
+}
+
+func TestUnicodeNames(t *testing.T) {
+	l := token.Subset(NewLexer("<?php $café = functïon(Ñ $ü);\u00a0echoé; echo déjà\\Ä::class;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "café")
+	assertNext(t, l, token.AssignmentOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "functïon")
+	assertNext(t, l, token.OpenParen)
+	assertItem(t, assertNext(t, l, token.Identifier), "Ñ")
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "ü")
+	assertNext(t, l, token.CloseParen)
+	assertNext(t, l, token.StatementEnd)
+	assertItem(t, assertNext(t, l, token.Identifier), "echoé")
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.Echo)
+	assertItem(t, assertNext(t, l, token.Identifier), "déjà\\Ä")
+	assertNext(t, l, token.ScopeResolutionOperator)
+	assertNext(t, l, token.Class)
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.EOF)
+
+	// a non-breaking space is whitespace, not part of a name
+	l = NewLexer("<?php $a\u00a0= 1;")
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Space)
+	assertNext(t, l, token.VariableOperator)
+	assertItem(t, assertNext(t, l, token.Identifier), "a")
+	assertItem(t, assertNext(t, l, token.Space), "\u00a0")
+	assertNext(t, l, token.AssignmentOperator)
+}
+
+func TestElvisOperator(t *testing.T) {
+	for _, src := range []string{"$a ?: $b;", "$a ? : $b;", "$a ?\n: $b;"} {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.VariableOperator)
+		assertNext(t, l, token.Identifier)
+		assertNext(t, l, token.ElvisOperator)
+		assertNext(t, l, token.VariableOperator)
+		if i := assertNext(t, l, token.Identifier); i.Val != "b" {
+			t.Errorf("%q: lexed %s after the short ternary", src, i)
+		}
+	}
+
+	l := token.Subset(NewLexer("<?php $a ? $b : $c; $a ? A::B : C::D;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.TernaryOperator1)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.TernaryOperator2)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.TernaryOperator1)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.ScopeResolutionOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.TernaryOperator2)
+}
+
+func TestCastOperators(t *testing.T) {
+	for _, cast := range []string{"(int)", "( int )", "(string)", "(\tbool  )", "(INTEGER)"} {
+		l := token.Subset(NewLexer("<?php "+cast+"$x;"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		i := assertNext(t, l, token.CastOperator)
+		assertItem(t, i, cast)
+		if name := i.PHPTokenName(); !strings.HasSuffix(name, "_CAST") {
+			t.Errorf("%q named %s", cast, name)
+		}
+		assertNext(t, l, token.VariableOperator)
+	}
+
+	for _, src := range []string{"($int)", "( foo )", "(int $x)"} {
+		l := token.Subset(NewLexer("<?php "+src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		assertNext(t, l, token.OpenParen)
+	}
+}
+
+func TestMethodChains(t *testing.T) {
+	call := []token.Token{token.ObjectOperator, token.Identifier, token.OpenParen, token.CloseParen}
+	var fiveDeep []token.Token
+	for i := 0; i < 5; i++ {
+		fiveDeep = append(fiveDeep, call...)
+	}
+	tests := map[string][]token.Token{
+		`$o->a()->b()->c()->d()->e()`: append([]token.Token{token.VariableOperator, token.Identifier}, fiveDeep...),
+		`$o->list()->class->print()`: {
+			token.VariableOperator, token.Identifier,
+			token.ObjectOperator, token.Identifier, token.OpenParen, token.CloseParen,
+			token.ObjectOperator, token.Identifier,
+			token.ObjectOperator, token.Identifier, token.OpenParen, token.CloseParen,
+		},
+		`Foo::bar()->baz?->qux()::$s->c`: {
+			token.Identifier, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen,
+			token.ObjectOperator, token.Identifier,
+			token.NullsafeObjectOperator, token.Identifier, token.OpenParen, token.CloseParen,
+			token.ScopeResolutionOperator, token.VariableOperator, token.Identifier,
+			token.ObjectOperator, token.Identifier,
+		},
+		`static::make()?->a()[0]->b`: {
+			token.Static, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen,
+			token.NullsafeObjectOperator, token.Identifier, token.OpenParen, token.CloseParen,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight,
+			token.ObjectOperator, token.Identifier,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src+";"), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.StatementEnd)
+	}
+}
+
+func TestDeprecatedCurlyOffset(t *testing.T) {
+	deprecated := func(src string) []token.Item {
+		items, err := Tokens(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []token.Item
+		for _, i := range items {
+			if i.Typ.IsDeprecated() {
+				found = append(found, i)
+			}
+		}
+		return found
+	}
+
+	found := deprecated("<?php\necho $s{0};")
+	if len(found) != 2 || found[0].Typ != token.CurlyLookupOperatorLeft || found[0].Begin.Line != 2 || found[0].Begin.Position != 13 {
+		t.Errorf("found deprecated %v in $s{0}", found)
+	}
+	if found := deprecated("<?php if (x) { } function f() { $s = 1; }"); len(found) != 0 {
+		t.Errorf("found deprecated %v in blocks", found)
+	}
+}
+
+func TestMatchToken(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		match string
+		typ   token.Token
+	}{
+		{"$a <=> $b", 3, "<=>", token.ComparisonOperator},
+		{"$a <= $b", 3, "<=", token.ComparisonOperator},
+		{"$a <<= 1", 3, "<<=", token.AssignmentOperator},
+		{"$a << 1", 3, "<<", token.BitwiseShiftOperator},
+		{"$a === $b", 3, "===", token.ComparisonOperator},
+		{"$a => $b", 3, "=>", token.ArrayKeyOperator},
+		{"$a?->b", 2, "?->", token.NullsafeObjectOperator},
+		{"...$a", 0, "...", token.Ellipsis},
+		{".5", 0, ".", token.ConcatenationOperator},
+		{"(int)$a", 0, "(int)", token.CastOperator},
+		{"(INT)$a", 0, "(INT)", token.CastOperator},
+		{"include_once 'a';", 0, "include_once", token.Include},
+		{"ElseIf ($a)", 0, "ElseIf", token.ElseIf},
+		{"endforeach ?>", 0, "endforeach", token.EndForeach},
+		{"endforeach;", 0, "endforeach;", token.EndForeach},
+		{"classes", 0, "class", token.Class},
+	}
+	for _, tt := range tests {
+		match, typ, ok := MatchToken(tt.input, tt.pos)
+		if !ok || match != tt.match || typ != tt.typ {
+			t.Errorf("MatchToken(%q, %d) = %q, %s, %t, expected %q, %s", tt.input, tt.pos, match, typ, ok, tt.match, tt.typ)
+		}
+	}
+
+	for _, input := range []string{"", "1", "Foo", "\\"} {
+		if match, typ, ok := MatchToken(input, 0); ok {
+			t.Errorf("MatchToken(%q, 0) = %q, %s", input, match, typ)
+		}
+	}
+}
+
+func TestThrowExpression(t *testing.T) {
+	newE := []token.Token{token.Throw, token.NewOperator, token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd}
+	tests := map[string][]token.Token{
+		`$x ?? throw new E();`:   append([]token.Token{token.VariableOperator, token.Identifier, token.CoalesceOperator}, newE...),
+		`$x ?: throw new E();`:   append([]token.Token{token.VariableOperator, token.Identifier, token.ElvisOperator}, newE...),
+		`fn() => throw new E();`: append([]token.Token{token.Identifier, token.OpenParen, token.CloseParen, token.ArrayKeyOperator}, newE...),
+		`throw new E();`:         newE,
+		`$x ??= 1;`:              {token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestCatchTypes(t *testing.T) {
+	tests := map[string][]token.Token{
+		`catch (TypeError | ValueError $e) {}`: {token.Catch, token.OpenParen, token.TypeHint, token.VariableOperator, token.Identifier, token.CloseParen, token.BlockBegin, token.BlockEnd},
+		`catch (A|B|C) {}`:                     {token.Catch, token.OpenParen, token.TypeHint, token.CloseParen, token.BlockBegin, token.BlockEnd},
+		`catch (Throwable) {}`:                 {token.Catch, token.OpenParen, token.Identifier, token.CloseParen, token.BlockBegin, token.BlockEnd},
+		`f(A | B);`:                            {token.Identifier, token.OpenParen, token.Identifier, token.BitwiseOrOperator, token.Identifier, token.CloseParen, token.StatementEnd},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestIgnoreErrorOperator(t *testing.T) {
+	call := []token.Token{token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd}
+	tests := map[string][]token.Token{
+		`@foo();`:  append([]token.Token{token.IgnoreErrorOperator}, call...),
+		`@@foo();`: append([]token.Token{token.IgnoreErrorOperator, token.IgnoreErrorOperator}, call...),
+		`$x = @$a[0];`: {
+			token.VariableOperator, token.Identifier, token.AssignmentOperator,
+			token.IgnoreErrorOperator, token.VariableOperator, token.Identifier,
+			token.ArrayLookupOperatorLeft, token.NumberLiteral, token.ArrayLookupOperatorRight, token.StatementEnd,
+		},
+		`f(@$a, !@$b);`: {
+			token.Identifier, token.OpenParen,
+			token.IgnoreErrorOperator, token.VariableOperator, token.Identifier, token.Comma,
+			token.NegationOperator, token.IgnoreErrorOperator, token.VariableOperator, token.Identifier,
+			token.CloseParen, token.StatementEnd,
+		},
+		"#[Attr]\n@foo();": append([]token.Token{token.IgnoreErrorOperator}, call...),
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	for _, keyword := range []string{"include", "include_once", "require", "require_once", "Require_Once"} {
+		tests := map[string][]token.Token{
+			keyword + " 'f.php';":     {token.Include, token.SingleQuotedString, token.StatementEnd},
+			keyword + "('f.php');":    {token.Include, token.OpenParen, token.SingleQuotedString, token.CloseParen, token.StatementEnd},
+			keyword + " ($f).'.php';": {token.Include, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen, token.ConcatenationOperator, token.SingleQuotedString, token.StatementEnd},
+			"$x = " + keyword + " __DIR__ . '/f.php';": {
+				token.VariableOperator, token.Identifier, token.AssignmentOperator,
+				token.Include, token.MagicConstant, token.ConcatenationOperator, token.SingleQuotedString, token.StatementEnd,
+			},
+			"$o->" + keyword + "('f.php');": {
+				token.VariableOperator, token.Identifier, token.ObjectOperator,
+				token.Identifier, token.OpenParen, token.SingleQuotedString, token.CloseParen, token.StatementEnd,
+			},
+		}
+		for src, expected := range tests {
+			l := token.Subset(NewLexer("<?php "+src), token.Significant)
+			assertNext(t, l, token.PHPBegin)
+			for _, typ := range expected {
+				i := assertNext(t, l, typ)
+				if typ == token.Include {
+					assertItem(t, i, keyword)
+				}
+			}
+			assertNext(t, l, token.EOF)
+		}
+	}
+}
+
+func TestNewOperator(t *testing.T) {
+	tests := map[string][]token.Token{
+		`new Foo();`:    {token.NewOperator, token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd},
+		`new \A\Foo;`:   {token.NewOperator, token.Identifier, token.StatementEnd},
+		`new $class();`: {token.NewOperator, token.VariableOperator, token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd},
+		`new (static::class)();`: {
+			token.NewOperator, token.OpenParen, token.Static, token.ScopeResolutionOperator, token.Class, token.CloseParen,
+			token.OpenParen, token.CloseParen, token.StatementEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestClassNameConstant(t *testing.T) {
+	tests := map[string][]token.Token{
+		`Foo::class;`:     {token.Identifier, token.ScopeResolutionOperator, token.Class, token.StatementEnd},
+		`\A\Foo::CLASS;`:  {token.Identifier, token.ScopeResolutionOperator, token.Class, token.StatementEnd},
+		`self::class;`:    {token.Self, token.ScopeResolutionOperator, token.Class, token.StatementEnd},
+		`static::class;`:  {token.Static, token.ScopeResolutionOperator, token.Class, token.StatementEnd},
+		`$obj::class;`:    {token.VariableOperator, token.Identifier, token.ScopeResolutionOperator, token.Class, token.StatementEnd},
+		`Foo::classes();`: {token.Identifier, token.ScopeResolutionOperator, token.Identifier, token.OpenParen, token.CloseParen, token.StatementEnd},
+		`$obj->class;`:    {token.VariableOperator, token.Identifier, token.ObjectOperator, token.Identifier, token.StatementEnd},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestEmbeddedComments(t *testing.T) {
+	tests := map[string][]token.Token{
+		`$a = /* note */ 1;`: {token.VariableOperator, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd},
+		`$a ? /* c */ : $b;`: {
+			token.VariableOperator, token.Identifier, token.TernaryOperator1, token.TernaryOperator2,
+			token.VariableOperator, token.Identifier, token.StatementEnd,
+		},
+		"f(name /* c */ : 1, # c\n $b);": {
+			token.Identifier, token.OpenParen, token.ArgumentName, token.TernaryOperator2, token.NumberLiteral, token.Comma,
+			token.VariableOperator, token.Identifier, token.CloseParen, token.StatementEnd,
+		},
+		`function f(/* x */ int $a) {}`: {
+			token.Function, token.Identifier, token.OpenParen, token.Identifier, token.VariableOperator, token.Identifier,
+			token.CloseParen, token.BlockBegin, token.BlockEnd,
+		},
+		"function f(?int /* x */ $a, /* y */ A|B $b): // r\n ?int {}": {
+			token.Function, token.Identifier, token.OpenParen,
+			token.TypeHint, token.VariableOperator, token.Identifier, token.Comma,
+			token.TypeHint, token.VariableOperator, token.Identifier, token.CloseParen,
+			token.TernaryOperator2, token.TypeHint, token.BlockBegin, token.BlockEnd,
+		},
+		`class A { public /* c */ ?int /* d */ $x; const /* c */ int /* d */ X = 1; }`: {
+			token.Class, token.Identifier, token.BlockBegin,
+			token.Public, token.TypeHint, token.VariableOperator, token.Identifier, token.StatementEnd,
+			token.Const, token.TypeHint, token.Identifier, token.AssignmentOperator, token.NumberLiteral, token.StatementEnd,
+			token.BlockEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+
+	// the comments are kept as items of their own
+	src := "<?php function f(?int /* x */ $a): /* r */ ?int {}"
+	items, err := Tokens(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments, types []string
+	for _, i := range items {
+		switch i.Typ {
+		case token.CommentBlock:
+			comments = append(comments, i.Val)
+		case token.TypeHint:
+			types = append(types, i.Val)
+		}
+	}
+	if !reflect.DeepEqual(comments, []string{"/* x */", "/* r */"}) || !reflect.DeepEqual(types, []string{"?int", "?int"}) {
+		t.Errorf("lexed comments %q and types %q from %q", comments, types, src)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	l := token.Subset(NewLexer("<?php $a = `echo $x`; `echo \\`date\\``;"), token.Significant)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.AssignmentOperator)
+	assertItem(t, assertNext(t, l, token.ShellCommand), "`echo $x`")
+	assertNext(t, l, token.StatementEnd)
+	assertItem(t, assertNext(t, l, token.ShellCommand), "`echo \\`date\\``")
+	assertNext(t, l, token.StatementEnd)
+	assertNext(t, l, token.EOF)
+
+	items, err := Tokens("<?php `ls")
+	if err == nil || err.Error() != "unterminated shell command at line 1, col 7" {
+		t.Errorf("expected an unterminated shell command, found %v", err)
+	}
+	for _, i := range items {
+		if i.Typ == token.ShellCommand {
+			t.Errorf("lexed %v from an unterminated shell command", i)
+		}
+	}
+}
+
+func TestReturnByReference(t *testing.T) {
+	tests := map[string][]token.Token{
+		`function &f() {}`: {token.Function, token.ReferenceOperator, token.Identifier, token.OpenParen, token.CloseParen, token.BlockBegin, token.BlockEnd},
+		`function &() use (&$x) {};`: {
+			token.Function, token.ReferenceOperator, token.OpenParen, token.CloseParen,
+			token.Use, token.OpenParen, token.ReferenceOperator, token.VariableOperator, token.Identifier, token.CloseParen,
+			token.BlockBegin, token.BlockEnd, token.StatementEnd,
+		},
+		`fn&($x) => $x;`: {
+			token.Identifier, token.ReferenceOperator, token.OpenParen, token.VariableOperator, token.Identifier, token.CloseParen,
+			token.ArrayKeyOperator, token.VariableOperator, token.Identifier, token.StatementEnd,
+		},
+		`$fn & ($x);`: {
+			token.VariableOperator, token.Identifier, token.AmpersandOperator, token.OpenParen, token.VariableOperator, token.Identifier,
+			token.CloseParen, token.StatementEnd,
+		},
+		`$o->fn & (1);`: {
+			token.VariableOperator, token.Identifier, token.ObjectOperator, token.Identifier, token.AmpersandOperator,
+			token.OpenParen, token.NumberLiteral, token.CloseParen, token.StatementEnd,
+		},
+	}
+	for src, expected := range tests {
+		l := token.Subset(NewLexer("<?php "+src), token.Significant)
+		assertNext(t, l, token.PHPBegin)
+		for _, typ := range expected {
+			assertNext(t, l, typ)
+		}
+		assertNext(t, l, token.EOF)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	l := NewLexer(`<?php function f(#[SensitiveParameter] $p, #[A(['x' => "]"])] $b) {}`)
+	assertNext(t, l, token.PHPBegin)
+	assertNext(t, l, token.Space)
+	assertNext(t, l, token.Function)
+	assertNext(t, l, token.Space)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.OpenParen)
+	assertItem(t, assertNext(t, l, token.Attribute), "#[SensitiveParameter]")
+	assertNext(t, l, token.Space)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.Comma)
+	assertNext(t, l, token.Space)
+	assertItem(t, assertNext(t, l, token.Attribute), `#[A(['x' => "]"])]`)
+	assertNext(t, l, token.Space)
+	assertNext(t, l, token.VariableOperator)
+	assertNext(t, l, token.Identifier)
+	assertNext(t, l, token.CloseParen)
+
+	l = token.Subset(NewLexer("<?php #[Pure] function g() {} echo 1;"), token.Significant)
+	for _, typ := range []token.Token{
+		token.PHPBegin, token.Function, token.Identifier, token.OpenParen, token.CloseParen,
+		token.BlockBegin, token.BlockEnd, token.Echo, token.NumberLiteral, token.StatementEnd, token.EOF,
+	} {
+		assertNext(t, l, typ)
+	}
+
+	if _, err := Tokens("<?php #[A(1)\nfunction g() {}"); err == nil || err.Error() != "unterminated attribute at line 1, col 7" {
+		t.Errorf("expected an unterminated attribute, found %v", err)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	l := token.Subset(NewLexer("<?php\r\n  echo\n\t$a;"), token.Significant)
+	for _, expected := range []token.Position{
+		{Line: 1, Column: 1, Position: 0},
+		{Line: 2, Column: 3, Position: 9},
+		{Line: 3, Column: 2, Position: 15},
+		{Line: 3, Column: 3, Position: 16},
+		{Line: 3, Column: 4, Position: 17},
+	} {
+		if i := l.Next(); i.Begin != expected {
+			t.Errorf("%v begins at %+v, expected %+v", i, i.Begin, expected)
+		}
+	}
+}
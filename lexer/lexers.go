@@ -1,35 +1,97 @@
 package lexer
 
 import (
-	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/stephens2424/php/token"
 )
 
-// longestToken is length of the longest token string
-var longestToken = 0
+// tokensByByte buckets the strings in token.TokenMap by their first byte,
+// longest first, so that matching a token only considers the strings that
+// could begin at the current position.
+var tokensByByte [256][]string
 
+const byteOrderMark = "\ufeff"
+const shebang = "#!"
 const shortPHPBegin = "<?"
 const longPHPBegin = "<?php"
+const shortEchoBegin = "<?="
 const phpEnd = "?>"
 
 const eof = -1
 
 func init() {
-	for k := range token.TokenMap {
-		if len(k) > longestToken {
-			longestToken = len(k)
+	for _, k := range token.TokenList {
+		tokensByByte[k[0]] = append(tokensByByte[k[0]], k)
+	}
+}
+
+// MatchToken returns the longest operator or keyword in token.TokenMap that
+// begins at byte pos of input, as it appears there, and its token. Case is
+// ignored, and so is what follows a keyword, so "classes" begins with class.
+// It returns false if no token begins at pos.
+func MatchToken(input string, pos int) (string, token.Token, bool) {
+	s, ok := matchToken(input[pos:])
+	if !ok {
+		return "", 0, false
+	}
+	return input[pos : pos+len(s)], token.TokenMap[s], true
+}
+
+// matchToken returns the longest string in token.TokenMap that input begins
+// with, ignoring case.
+func matchToken(input string) (string, bool) {
+	if input == "" {
+		return "", false
+	}
+	for _, s := range tokensByByte[toLower(input[0])] {
+		if len(s) <= len(input) && equalFold(input[:len(s)], s) {
+			return s, true
 		}
 	}
+	return "", false
+}
+
+// equalFold reports whether s equals the lowercase ASCII string lower,
+// ignoring the case of s.
+func equalFold(s, lower string) bool {
+	for i := 0; i < len(s); i++ {
+		if toLower(s[i]) != lower[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func toLower(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// lexFileBegin lexes a UTF-8 byte order mark and a shebang line, as in
+// #!/usr/bin/env php, at the beginning of a file. They are emitted as space and
+// a line comment rather than HTML, as PHP does not output them.
+func lexFileBegin(l *lexer) stateFn {
+	if strings.HasPrefix(l.input, byteOrderMark) {
+		l.pos += len(byteOrderMark)
+		l.emit(token.Space)
+	}
+	if strings.HasPrefix(l.input[l.pos:], shebang) {
+		l.pos += lineEnd(l.input[l.pos:])
+		l.emit(token.CommentLine)
+	}
+	return lexHTML
 }
 
 // lexHTML consumes and emits an html t until it
 // finds a php begin
 func lexHTML(l *lexer) stateFn {
 	for {
-		if strings.HasPrefix(l.input[l.pos:], shortPHPBegin) {
+		if l.phpBeginLength() > 0 {
 			if l.pos > l.start {
 				l.emit(token.HTML)
 			}
@@ -47,16 +109,26 @@ func lexHTML(l *lexer) stateFn {
 }
 
 func lexPHPBegin(l *lexer) stateFn {
-	if strings.HasPrefix(l.input[l.pos:], longPHPBegin) {
-		l.pos += len(longPHPBegin)
-	}
-	if strings.HasPrefix(l.input[l.pos:], shortPHPBegin) {
-		l.pos += len(shortPHPBegin)
-	}
+	l.pos += l.phpBeginLength()
 	l.emit(token.PHPBegin)
 	return lexPHP
 }
 
+// phpBeginLength returns the length of the PHP open tag at the current
+// position, or 0 if there is none.
+func (l *lexer) phpBeginLength() int {
+	input := l.input[l.pos:]
+	switch {
+	case len(input) >= len(longPHPBegin) && strings.EqualFold(input[:len(longPHPBegin)], longPHPBegin):
+		return len(longPHPBegin)
+	case strings.HasPrefix(input, shortEchoBegin):
+		return len(shortEchoBegin)
+	case strings.HasPrefix(input, shortPHPBegin) && !l.noShortTags:
+		return len(shortPHPBegin)
+	}
+	return 0
+}
+
 func lexPHP(l *lexer) stateFn {
 	l.skipSpace()
 
@@ -79,6 +151,10 @@ func lexPHP(l *lexer) stateFn {
 		return lexPHPEnd
 	}
 
+	if strings.HasPrefix(l.input[l.pos:], "#[") {
+		return lexAttribute
+	}
+
 	if strings.HasPrefix(l.input[l.pos:], "#") {
 		return lexLineComment
 	}
@@ -91,6 +167,16 @@ func lexPHP(l *lexer) stateFn {
 		return lexBlockComment
 	}
 
+	if l.returnType {
+		n := typeLength(l.input[l.pos:])
+		if n == 0 {
+			return l.expected("return type")
+		}
+		l.pos += n
+		l.emit(token.TypeHint)
+		return lexPHP
+	}
+
 	if l.peek() == eof {
 		l.emit(token.EOF)
 		return nil
@@ -100,6 +186,11 @@ func lexPHP(l *lexer) stateFn {
 		return lexShellCommand
 	}
 
+	if l.isBinaryString() {
+		// the b prefix is part of the string literal, as in b"bytes"
+		l.next()
+	}
+
 	if l.peek() == '\'' {
 		return lexSingleQuotedStringLiteral
 	}
@@ -108,72 +199,532 @@ func lexPHP(l *lexer) stateFn {
 		return lexDoubleQuotedStringLiteral
 	}
 
-	tokenString := l.input[l.pos:]
-	if len(tokenString) > longestToken {
-		tokenString = tokenString[:longestToken]
+	if l.afterParams && l.peek() == ':' {
+		return lexReturnType
 	}
-	tokenString = strings.ToLower(tokenString)
-	for ; tokenString != ""; tokenString = tokenString[:len(tokenString)-1] {
-		if t, ok := token.TokenMap[tokenString]; ok {
-			isKw := IsKeyword(t, tokenString)
-			if isKw && l.previous() == '$' {
-				break
-			}
+
+	if l.peek() == '&' && l.isReference() {
+		l.next()
+		l.emit(token.ReferenceOperator)
+		return lexPHP
+	}
+
+	if t, ok := l.bracket(); ok {
+		l.next()
+		l.emit(t)
+		return lexPHP
+	}
+
+	if l.afterCondition && l.peek() == ':' {
+		l.next()
+		l.emit(token.AlternateBlockBegin)
+		return lexPHP
+	}
+
+	if l.typeStart {
+		if n := declaredTypeLength(l.input[l.pos:]); n > 0 {
+			l.pos += n
+			l.emit(token.TypeHint)
+			return lexPHP
+		}
+	}
+
+	if l.constType {
+		if n := constTypeLength(l.input[l.pos:]); n > 0 {
+			l.pos += n
+			l.emit(token.TypeHint)
+			return lexPHP
+		}
+	}
+
+	if l.catchType {
+		if n := catchTypeLength(l.input[l.pos:]); n > 0 {
+			l.pos += n
+			l.emit(token.TypeHint)
+			return lexPHP
+		}
+	}
+
+	if n := castLength(l.input[l.pos:]); n > 0 {
+		l.pos += n
+		l.emit(token.CastOperator)
+		return lexPHP
+	}
+
+	if l.peek() == '?' && isLabelColon(l.input[l.pos+1:]) {
+		// a short ternary, which may have space between its ? and :
+		l.pos = len(l.input) - len(strings.TrimLeftFunc(l.input[l.pos+1:], isSpace)) + 1
+		l.emit(token.ElvisOperator)
+		return lexPHP
+	}
+
+	if n := l.argumentNameLength(); n > 0 {
+		l.pos += n
+		l.emit(token.ArgumentName)
+		return lexPHP
+	}
+
+	if tokenString, ok := matchToken(l.input[l.pos:]); ok {
+		t := token.TokenMap[tokenString]
+		// a keyword is only a prefix of a variable name or longer identifier,
+		// or of a name relative to the current namespace, as in namespace\Foo
+		after := l.input[l.pos+len(tokenString):]
+		next, _ := utf8.DecodeRuneInString(after)
+		partOfName := l.previous() == '$' || isNameChar(next) ||
+			t == token.Namespace && strings.HasPrefix(after, "\\")
+		if !IsKeyword(t, tokenString) || !(partOfName || l.isMemberName(t)) {
 			l.pos += len(tokenString)
-			if isKw && l.accept(alphabet+underscore+digits) {
-				l.backup() // to account for the character consumed by accept
-				l.pos -= len(tokenString)
-				break
+			if t == token.Yield {
+				t = l.acceptFrom()
 			}
 			l.emit(t)
 			return lexPHP
 		}
 	}
 
-	l.acceptRun(alphabet + underscore + digits + "\\")
+	for r := l.next(); isNameChar(r) || r == '\\'; r = l.next() {
+	}
+	l.backup()
+	if l.pos == l.start {
+		r := l.next()
+		return l.errorf("unexpected character %q", r)
+	}
+	if l.statementStart && isLabelColon(skipTrivia(l.input[l.pos:])) {
+		l.emit(token.Label)
+		return lexPHP
+	}
+	if l.lastToken == token.VariableOperator && l.input[l.start:l.pos] == "this" {
+		l.emit(token.This)
+		return lexPHP
+	}
 	l.emit(token.Identifier)
 	return lexPHP
 }
 
+// isBinaryString reports whether the current position begins a quoted string
+// with the b or B prefix PHP allows for binary strings, as in b'raw'.
+func (l *lexer) isBinaryString() bool {
+	if l.lastToken == token.VariableOperator || l.lastToken == token.ObjectOperator || l.lastToken == token.NullsafeObjectOperator {
+		return false
+	}
+	s := l.input[l.pos:]
+	return len(s) > 1 && (s[0] == 'b' || s[0] == 'B') && (s[1] == '\'' || s[1] == '"')
+}
+
+// isReference reports whether the & at the current position marks a
+// reference, as in $a = &$b, function f(&$x), or foreach ($a as &$v), rather
+// than a bitwise and. Only the token before it tells them apart.
+func (l *lexer) isReference() bool {
+	if next := l.input[l.pos+1:]; strings.HasPrefix(next, "&") || strings.HasPrefix(next, "=") {
+		return false
+	}
+	switch l.lastToken {
+	case token.AssignmentOperator, token.OpenParen, token.Comma, token.AsOperator,
+		token.ArrayKeyOperator, token.ShortArrayLeft, token.Function:
+		return true
+	case token.Identifier:
+		// the type of a by-reference parameter, or the fn of an arrow
+		// function returning a reference
+		return l.paramType || l.followsFn()
+	case token.TypeHint, token.Array, token.Self, token.Parent:
+		// the type of a by-reference parameter
+		return l.paramType
+	}
+	return false
+}
+
+// isParamType reports whether t may be the type of a parameter.
+func isParamType(t token.Token) bool {
+	switch t {
+	case token.Identifier, token.TypeHint, token.Array, token.Self, token.Parent:
+		return true
+	}
+	return false
+}
+
+// followsFn reports whether the & at the current position follows the fn of
+// an arrow function returning a reference, as in fn&($x) => $x.
+func (l *lexer) followsFn() bool {
+	if !strings.HasPrefix(skipTrivia(l.input[l.pos+1:]), "(") {
+		return false
+	}
+	prev := strings.TrimRightFunc(l.input[:l.pos], isSpace)
+	if len(prev) < 2 || !strings.EqualFold(prev[len(prev)-2:], "fn") {
+		return false
+	}
+	// not a variable, member or namespaced name ending in fn
+	r, _ := utf8.DecodeLastRuneInString(prev[:len(prev)-2])
+	return !isNameChar(r) && !strings.ContainsRune(`$>:\`, r)
+}
+
+// isMemberName reports whether the keyword t at the current position is the
+// name of a member, as in Foo::DEFAULT or $o->list, rather than a keyword.
+// The class in Foo::class remains a keyword.
+func (l *lexer) isMemberName(t token.Token) bool {
+	switch l.lastToken {
+	case token.ObjectOperator, token.NullsafeObjectOperator:
+		return true
+	case token.ScopeResolutionOperator:
+		return t != token.Class
+	}
+	return false
+}
+
+// argumentNameLength returns the length of the name of a named argument, as
+// in f(name: $value), at the current position, or 0 if there is none. The
+// name may be a keyword.
+func (l *lexer) argumentNameLength() int {
+	if (l.lastToken != token.OpenParen && l.lastToken != token.Comma) || l.signature {
+		return 0
+	}
+	n := identifierLength(l.input[l.pos:])
+	if n == 0 || !isLabelColon(skipTrivia(l.input[l.pos+n:])) {
+		return 0
+	}
+	return n
+}
+
+// identifierLength returns the length of the identifier at the start of s, or
+// 0 if s does not begin with one.
+func identifierLength(s string) int {
+	if r, _ := utf8.DecodeRuneInString(s); !isNameStart(r) {
+		return 0
+	}
+	n := 0
+	for n < len(s) {
+		r, w := utf8.DecodeRuneInString(s[n:])
+		if !isNameChar(r) {
+			break
+		}
+		n += w
+	}
+	return n
+}
+
+// bracket returns the token for the bracket or brace at the current position
+// if it depends on the context: a [ begins an offset after an operand and an
+// array anywhere else, a ] closes whichever [ is open, and a { after a variable
+// or offset begins a curly brace offset, as in $s{0}, closed by the matching }.
+// It returns false for a brace delimiting a block.
+func (l *lexer) bracket() (token.Token, bool) {
+	switch l.peek() {
+	case '[':
+		if l.afterOperand {
+			return token.ArrayLookupOperatorLeft, true
+		}
+		return token.ShortArrayLeft, true
+	case ']':
+		if n := len(l.brackets); n > 0 {
+			return l.brackets[n-1], true
+		}
+		return token.ArrayLookupOperatorRight, true
+	case '{':
+		return token.CurlyLookupOperatorLeft, l.afterVariable
+	case '}':
+		n := len(l.braces)
+		return token.CurlyLookupOperatorRight, n > 0 && l.braces[n-1] == token.CurlyLookupOperatorLeft
+	}
+	return 0, false
+}
+
+// castLength returns the length of the cast operator at the start of s, as in
+// (int) or ( string ), or 0 if s does not begin with one. Like PHP, spaces and
+// tabs are allowed inside the parentheses.
+func castLength(s string) int {
+	if !strings.HasPrefix(s, "(") {
+		return 0
+	}
+	i := len(s) - len(strings.TrimLeft(s[1:], " \t"))
+	n := identifierLength(s[i:])
+	end := len(s) - len(strings.TrimLeft(s[i+n:], " \t"))
+	if n == 0 || !strings.HasPrefix(s[end:], ")") || token.TokenMap["("+strings.ToLower(s[i:i+n])+")"] != token.CastOperator {
+		return 0
+	}
+	return end + 1
+}
+
+// isLabelColon reports whether s begins with the colon ending a goto label,
+// as opposed to a scope resolution operator.
+func isLabelColon(s string) bool {
+	s = strings.TrimLeftFunc(s, isSpace)
+	return strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "::")
+}
+
+// lexReturnType lexes the colon following a parameter list. The return type
+// after it, which may follow spaces and comments, is then emitted as a single
+// TypeHint, including the leading ? of a nullable type and the separators of
+// a union or intersection type.
+func lexReturnType(l *lexer) stateFn {
+	l.next()
+	l.emit(token.TernaryOperator2)
+	return lexPHP
+}
+
+// skipTrivia returns s without its leading spaces and comments.
+func skipTrivia(s string) string {
+	for {
+		s = strings.TrimLeftFunc(s, isSpace)
+		switch {
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				return ""
+			}
+			s = s[end+len("*/"):]
+		case strings.HasPrefix(s, "#["):
+			n := attributeLength(s)
+			if n < 0 {
+				return ""
+			}
+			s = s[n:]
+		case strings.HasPrefix(s, "//"), strings.HasPrefix(s, "#"):
+			n := lineEnd(s)
+			if i := strings.Index(s[:n], phpEnd); i >= 0 {
+				n = i
+			}
+			s = s[n:]
+		default:
+			return s
+		}
+	}
+}
+
+// declaredTypeLength returns the length of the type at the start of s if it
+// is a nullable, union, intersection, or DNF type followed by a parameter or
+// property, and 0 otherwise. A plain named type is left to be lexed as an
+// identifier. Requiring the variable keeps a ? that begins a ternary from
+// being taken for a nullable marker.
+func declaredTypeLength(s string) int {
+	n := typeLength(s)
+	if n == 0 || !strings.ContainsAny(s[:n], "?|&(") {
+		return 0
+	}
+	switch rest := skipTrivia(s[n:]); {
+	case strings.HasPrefix(rest, "$"), strings.HasPrefix(rest, "&"), strings.HasPrefix(rest, "..."):
+		return n
+	}
+	return 0
+}
+
+// constTypeLength returns the length of the type at the start of s if it is
+// followed by the name of a typed constant, as in const int FOO = 1, and 0
+// otherwise. Any type is lexed as a TypeHint here, since a constant's name
+// is never followed by another name. The one exception is the as of an
+// aliased import, as in use const Foo\BAR as BAZ, which is no constant name
+// unless a value is assigned to it.
+func constTypeLength(s string) int {
+	n := typeLength(s)
+	if n == 0 {
+		return 0
+	}
+	rest := skipTrivia(s[n:])
+	m := identifierLength(rest)
+	if m == 0 {
+		return 0
+	}
+	if m == len("as") && equalFold(rest[:m], "as") && !strings.HasPrefix(skipTrivia(rest[m:]), "=") {
+		return 0
+	}
+	return n
+}
+
+// catchTypeLength returns the length of the list of exception types at the
+// start of s if it joins several classes with |, as in
+// catch (TypeError | ValueError $e), and 0 otherwise. A single class is left
+// to be lexed as an identifier.
+func catchTypeLength(s string) int {
+	n := typeLength(s)
+	if n == 0 || !strings.Contains(s[:n], "|") || strings.ContainsAny(s[:n], "?&(") {
+		return 0
+	}
+	return n
+}
+
+// typeLength returns the length of the type declaration at the start of s,
+// such as "int", "?Foo", "int|string", "A&B", or "(A&B)|null", or 0 if s does
+// not begin with one. An & followed by a variable is a by-reference marker
+// rather than part of an intersection type, and so ends the type.
+func typeLength(s string) int {
+	i, end := 0, 0
+	if strings.HasPrefix(s, "?") {
+		i++
+	}
+	inGroup := false
+	for {
+		if !inGroup && i < len(s) && s[i] == '(' {
+			inGroup = true
+			i = skipSpaces(s, i+1)
+		}
+		n := 0
+		for n < len(s)-i {
+			r, w := utf8.DecodeRuneInString(s[i+n:])
+			if !isNameChar(r) && r != '\\' {
+				break
+			}
+			n += w
+		}
+		if n == 0 {
+			return end
+		}
+		i += n
+		if j := skipSpaces(s, i); inGroup && j < len(s) && s[j] == ')' {
+			inGroup = false
+			i = j + 1
+		}
+		if !inGroup {
+			end = i
+		}
+
+		j := skipSpaces(s, i)
+		if j >= len(s) {
+			return end
+		}
+		switch s[j] {
+		case '|':
+			if inGroup {
+				return end
+			}
+		case '&':
+			if k := skipSpaces(s, j+1); k < len(s) && (s[k] == '$' || s[k] == '.' || s[k] == '&') {
+				return end
+			}
+		default:
+			return end
+		}
+		i = skipSpaces(s, j+1)
+	}
+}
+
+// skipSpaces returns the index of the first non-space character in s at or
+// after i.
+func skipSpaces(s string, i int) int {
+	return len(s) - len(strings.TrimLeftFunc(s[i:], isSpace))
+}
+
+// acceptFrom extends a yield keyword that was just lexed over a following
+// from keyword, returning YieldFrom if it did and Yield otherwise. Like PHP's
+// own tokenizer, the combined token includes the space between the words.
+func (l *lexer) acceptFrom() token.Token {
+	rest := l.input[l.pos:]
+	from := strings.TrimLeftFunc(rest, isSpace)
+	if len(from) == len(rest) || len(from) < len("from") || !strings.EqualFold(from[:len("from")], "from") {
+		return token.Yield
+	}
+	if r, _ := utf8.DecodeRuneInString(from[len("from"):]); isNameChar(r) {
+		return token.Yield
+	}
+	l.pos += len(rest) - len(from) + len("from")
+	return token.YieldFrom
+}
+
 func lexNumberLiteral(l *lexer) stateFn {
 	if l.accept("0") {
-		// binary?
-		if l.accept("b") {
-			l.acceptRun("01")
-			l.emit(token.NumberLiteral)
-			return lexPHP
-		}
-		// hexadecimal?
-		if l.accept("xX") {
-			l.acceptRun(digits + "abcdefABCDEF")
-			l.emit(token.NumberLiteral)
-			return lexPHP
+		switch {
+		case l.accept("bB"):
+			return lexPrefixedInteger(l, "binary", "01")
+		case l.accept("xX"):
+			return lexPrefixedInteger(l, "hexadecimal", hexDigits)
+		case l.accept("oO"):
+			return lexPrefixedInteger(l, "octal", octalDigits)
 		}
 	}
 	// is decimal?
-	l.acceptRun(digits)
+	valid := l.acceptDigitRun(digits)
+	isFloat := false
 	if l.accept(".") {
-		l.acceptRun(digits)
+		isFloat = true
+		valid = l.acceptDigitRun(digits) && valid
 	}
 
-	if l.accept("eE") {
+	// an exponent needs digits after the e and its optional sign, otherwise
+	// the e begins the next token
+	if exponent := l.pos; l.accept("eE") {
+		l.accept("+-")
+		if unicode.IsDigit(l.peek()) {
+			isFloat = true
+			valid = l.acceptDigitRun(digits) && valid
+		} else {
+			l.pos = exponent
+		}
+	}
+
+	// a second decimal point, as in 1.2.3, is malformed
+	if rest := l.input[l.pos:]; isFloat && len(rest) > 1 && rest[0] == '.' && unicode.IsDigit(rune(rest[1])) {
+		l.pos++
 		l.acceptRun(digits)
+		return l.errorf("malformed number literal %q", l.input[l.start:l.pos])
+	}
+
+	// a leading zero makes an integer octal, e.g. 0755
+	if lit := l.input[l.start:l.pos]; !isFloat && len(lit) > 1 && lit[0] == '0' {
+		if i := strings.IndexAny(lit, "89"); i >= 0 {
+			return l.errorf("invalid digit %q in octal literal %q", lit[i], lit)
+		}
 	}
 
+	return l.emitNumber(valid)
+}
+
+// lexPrefixedInteger lexes the digits of a binary, octal, or hexadecimal
+// integer after its 0b, 0o, or 0x prefix has been consumed.
+func lexPrefixedInteger(l *lexer, base, valid string) stateFn {
+	prefixEnd := l.pos
+	ok := l.acceptDigitRun(valid)
+	if r := l.peek(); strings.IndexRune(alphabet+digits, r) >= 0 {
+		l.next()
+		return l.errorf("invalid digit %q in %s literal %q", r, base, l.input[l.start:l.pos])
+	}
+	if l.pos == prefixEnd {
+		return l.errorf("%s literal %q has no digits", base, l.input[l.start:l.pos])
+	}
+	return l.emitNumber(ok)
+}
+
+// emitNumber emits the number literal lexed so far, or an error if its digit
+// separators were misplaced.
+func (l *lexer) emitNumber(valid bool) stateFn {
+	if !valid {
+		return l.errorf("invalid numeric literal separator in %q", l.input[l.start:l.pos])
+	}
 	l.emit(token.NumberLiteral)
 	return lexPHP
 }
 
+// acceptDigitRun consumes a run of digits from the valid set. Digits may be
+// separated by single underscores, as in 1_000_000. It reports whether every
+// underscore consumed sat between two digits.
+func (l *lexer) acceptDigitRun(valid string) bool {
+	ok := true
+	afterDigit := l.pos > l.start && strings.IndexByte(valid, l.input[l.pos-1]) >= 0
+	for {
+		r := l.next()
+		switch {
+		case r != eof && strings.IndexRune(valid, r) >= 0:
+			afterDigit = true
+		case r == '_':
+			ok = ok && afterDigit
+			afterDigit = false
+		default:
+			l.backup()
+			return ok && (afterDigit || l.input[l.pos-1] != '_')
+		}
+	}
+}
+
+// lexShellCommand lexes a command between backticks, which like a
+// double-quoted string may hold escaped characters and variables.
 func lexShellCommand(l *lexer) stateFn {
 	l.next()
 	for {
 		switch l.next() {
+		case '\\':
+			l.next()
+			continue
 		case '`':
 			l.emit(token.ShellCommand)
 			return lexPHP
 		case eof:
-			l.emit(token.ShellCommand)
-			return nil
+			return l.errorf("unterminated shell command")
 		}
 	}
 }
@@ -186,8 +737,10 @@ func lexSingleQuotedStringLiteral(l *lexer) stateFn {
 			l.next()
 			continue
 		case '\'':
-			l.emit(token.StringLiteral)
+			l.emit(token.SingleQuotedString)
 			return lexPHP
+		case eof:
+			return l.errorf("unterminated single-quoted string")
 		}
 	}
 }
@@ -200,20 +753,40 @@ func lexDoubleQuotedStringLiteral(l *lexer) stateFn {
 			l.next()
 			continue
 		case '"':
-			l.emit(token.StringLiteral)
+			l.emit(token.DoubleQuotedString)
 			return lexPHP
+		case eof:
+			return l.errorf("unterminated double-quoted string")
 		}
 	}
 }
 
 const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const digits = "0123456789"
+const octalDigits = "01234567"
+const hexDigits = digits + "abcdefABCDEF"
 const underscore = "_"
 
+// isNameStart reports whether r may begin a name. Like PHP, non-ASCII letters
+// are allowed as well as ASCII ones and the underscore.
+func isNameStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+	}
+	return unicode.IsLetter(r)
+}
+
+// isNameChar reports whether r may continue a name.
+func isNameChar(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isNameStart(r) || '0' <= r && r <= '9'
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
 func lexIdentifier(l *lexer) stateFn {
 	l.accept("$")
-	l.accept(underscore + alphabet)
-	l.acceptRun(underscore + alphabet + digits)
+	l.pos += identifierLength(l.input[l.pos:])
 	l.emit(token.VariableOperator)
 	return lexPHP
 }
@@ -226,11 +799,7 @@ func lexPHPEnd(l *lexer) stateFn {
 }
 
 func lexLineComment(l *lexer) stateFn {
-	lineLength := strings.Index(l.input[l.pos:], "\n") + 1
-	if lineLength == 0 {
-		// this is the last line, so lex until the end
-		lineLength = len(l.input[l.pos:])
-	}
+	lineLength := lineEnd(l.input[l.pos:])
 	// don't lex php end
 	if phpEndLength := strings.Index(l.input[l.pos:l.pos+lineLength], phpEnd); phpEndLength >= 0 && phpEndLength < lineLength {
 		lineLength = phpEndLength
@@ -244,13 +813,53 @@ func lexBlockComment(l *lexer) stateFn {
 	commentLength := strings.Index(l.input[l.pos:], "*/") + 2
 	if commentLength == 1 {
 		// the file ends before we find */
-		commentLength = len(l.input[l.pos:])
+		l.pos = len(l.input)
+		return l.errorf("unterminated block comment")
 	}
 	l.pos += commentLength
 	l.emit(token.CommentBlock)
 	return lexPHP
 }
 
+// lexAttribute lexes an attribute, as in #[Route("/")], up to the bracket
+// that closes it.
+func lexAttribute(l *lexer) stateFn {
+	n := attributeLength(l.input[l.pos:])
+	if n < 0 {
+		l.pos = len(l.input)
+		return l.errorf("unterminated attribute")
+	}
+	l.pos += n
+	l.emit(token.Attribute)
+	return lexPHP
+}
+
+// attributeLength returns the length of the attribute at the start of s,
+// through its matching closing bracket, or -1 if s ends first. Brackets
+// within quoted strings are not counted.
+func attributeLength(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"':
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
+
 func lexDoc(l *lexer) stateFn {
 	var nowDoc bool
 	l.pos += len("<<<")
@@ -264,17 +873,38 @@ func lexDoc(l *lexer) stateFn {
 	labelPos := l.pos
 	l.accept(underscore + alphabet)
 	l.acceptRun(underscore + alphabet + digits)
-	endMarker := fmt.Sprintf("\n%s", l.input[labelPos:l.pos])
+	label := l.input[labelPos:l.pos]
+	if label == "" {
+		return l.expected("a heredoc label")
+	}
 	if nowDoc {
 		l.accept("'")
 	} else if l.peek() == '"' {
 		l.next()
 	}
-	l.accept("\n")
-	for !strings.HasPrefix(l.input[l.pos:], endMarker) {
-		l.next()
+	// the closing label is the first line to begin with it, after any
+	// indentation, where it is not the start of a longer name
+	for {
+		if l.pos == len(l.input) {
+			if nowDoc {
+				return l.errorf("unterminated nowdoc")
+			}
+			return l.errorf("unterminated heredoc")
+		}
+		l.pos += lineEnd(l.input[l.pos:])
+		line := l.input[l.pos:]
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if rest := line[indent:]; strings.HasPrefix(rest, label) {
+			if r, _ := utf8.DecodeRuneInString(rest[len(label):]); !isNameChar(r) {
+				l.pos += indent + len(label)
+				break
+			}
+		}
+	}
+	if nowDoc {
+		l.emit(token.Nowdoc)
+	} else {
+		l.emit(token.Heredoc)
 	}
-	l.pos += len(endMarker)
-	l.emit(token.StringLiteral)
 	return lexPHP
 }
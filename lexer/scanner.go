@@ -0,0 +1,92 @@
+package lexer
+
+import "github.com/stephens2424/php/token"
+
+// A Scanner lexes its input on demand, one item per call to Next. Unlike the
+// stream returned by NewLexer it cannot step back with Previous, and so does
+// not keep the items it has returned.
+type Scanner struct {
+	l    *lexer
+	eof  token.Item
+	done bool
+}
+
+// NewScanner returns a Scanner of input, configured by options as NewLexer is.
+func NewScanner(input string, options ...Option) *Scanner {
+	return &Scanner{l: newLexer(input, options...)}
+}
+
+// Next returns the next item and true, or the EOF item and false once the
+// input is exhausted. Every call after that also returns the EOF item and
+// false. An Error item is returned like any other; unless the scanner
+// recovers from errors, the EOF item follows it.
+func (s *Scanner) Next() (token.Item, bool) {
+	if s.done {
+		return s.eof, false
+	}
+	i := s.l.nextItem()
+	if i.Typ == token.EOF {
+		s.eof, s.done = i, true
+		return i, false
+	}
+	return i, true
+}
+
+// Violations returns the violations of the targeted version found so far.
+func (s *Scanner) Violations() []Violation {
+	return s.l.violations
+}
+
+// A Checkpoint is the state of a Scanner between two items, from which
+// lexing can be resumed with Restore. It lets an editor re-lex only the input
+// following a checkpoint taken before an edit.
+type Checkpoint struct {
+	l    lexer
+	eof  token.Item
+	done bool
+}
+
+// Offset returns how far into its input the scanner had lexed when c was
+// taken. That may be past the items it had returned so far, since some are
+// lexed together.
+func (c Checkpoint) Offset() int {
+	return c.l.start
+}
+
+// Checkpoint returns the current state of s.
+func (s *Scanner) Checkpoint() Checkpoint {
+	c := Checkpoint{l: *s.l, eof: s.eof, done: s.done}
+	c.l.detach()
+	return c
+}
+
+// Restore returns s to the state recorded by c, so that it next returns the
+// item following the checkpoint. The checkpoint may come from a scanner of
+// different input, such as the text before an edit, as long as the two
+// inputs agree up to c.Offset(). Since an item may be lexed by looking ahead
+// at the input following it, an edit should not immediately follow the
+// checkpoint preceding it.
+func (s *Scanner) Restore(c Checkpoint) {
+	input := s.l.input
+	*s.l = c.l
+	s.l.input = input
+	s.l.detach()
+	s.eof, s.done = c.eof, c.done
+}
+
+// detach copies the state l shares with the lexer it was copied from, so
+// that lexing with either one leaves the other unchanged.
+func (l *lexer) detach() {
+	l.pending = append([]token.Item(nil), l.pending...)
+	l.braces = append([]token.Token(nil), l.braces...)
+	l.brackets = append([]token.Token(nil), l.brackets...)
+	l.matchHeads = append([]matchHead(nil), l.matchHeads...)
+	l.violations = append([]Violation(nil), l.violations...)
+	if l.features != nil {
+		features := FeatureSet{}
+		for f := range l.features {
+			features[f] = true
+		}
+		l.features = features
+	}
+}
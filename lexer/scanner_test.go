@@ -0,0 +1,133 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephens2424/php/token"
+)
+
+func TestScanner(t *testing.T) {
+	l := NewLexer(testFile)
+	s := NewScanner(testFile)
+	for {
+		expected := l.Next()
+		i, ok := s.Next()
+		if !i.EqualPosition(expected) {
+			t.Fatalf("scanned %v at %v, expected %v at %v", i, i.Begin, expected, expected.Begin)
+		}
+		if ok != (expected.Typ != token.EOF) {
+			t.Fatalf("scanned %v with %t", i, ok)
+		}
+		if !ok {
+			break
+		}
+	}
+	eof, _ := s.Next()
+	for n := 0; n < 3; n++ {
+		if i, ok := s.Next(); ok || i != eof || i.Typ != token.EOF {
+			t.Errorf("scanned %v, %t after EOF", i, ok)
+		}
+	}
+}
+
+func TestScannerError(t *testing.T) {
+	s := NewScanner(`<?php $a = 0x;`)
+	var i token.Item
+	ok := true
+	for ok && i.Typ != token.Error {
+		i, ok = s.Next()
+	}
+	if !ok || i.Typ != token.Error {
+		t.Fatalf("expected an error, found %v", i)
+	}
+	if i, ok = s.Next(); ok || i.Typ != token.EOF {
+		t.Errorf("expected EOF after the error, found %v, %t", i, ok)
+	}
+}
+
+// scanFrom returns the items s returns until EOF.
+func scanFrom(s *Scanner) []token.Item {
+	var items []token.Item
+	for i, ok := s.Next(); ok; i, ok = s.Next() {
+		items = append(items, i)
+	}
+	return items
+}
+
+func assertItems(t *testing.T, found, expected []token.Item) {
+	t.Helper()
+	if len(found) != len(expected) {
+		t.Fatalf("scanned %d items, expected %d", len(found), len(expected))
+	}
+	for n := range found {
+		if !found[n].EqualPosition(expected[n]) {
+			t.Fatalf("scanned %v at %v, expected %v at %v", found[n], found[n].Begin, expected[n], expected[n].Begin)
+		}
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	s := NewScanner(testFile)
+	var checkpoints []Checkpoint
+	for _, ok := s.Next(); ok; _, ok = s.Next() {
+		checkpoints = append(checkpoints, s.Checkpoint())
+	}
+	n := len(checkpoints) / 2
+	c := checkpoints[n-1]
+	all := scanFrom(NewScanner(testFile))
+
+	// resuming the same scanner, and a new one, from the middle of the file
+	s.Restore(c)
+	assertItems(t, scanFrom(s), all[n:])
+	s = NewScanner(testFile)
+	s.Restore(c)
+	assertItems(t, scanFrom(s), all[n:])
+
+	// resuming after an edit following the checkpoint
+	edit := strings.Index(testFile[c.Offset():], "\n") + c.Offset() + 1
+	edited := testFile[:edit] + "$edited = array(1, 2);\n" + testFile[edit:]
+	s = NewScanner(edited)
+	s.Restore(c)
+	assertItems(t, scanFrom(s), scanFrom(NewScanner(edited))[n:])
+}
+
+// concatenation returns a statement concatenating n variables, like those
+// found in generated code.
+func concatenation(n int) string {
+	return "<?php $s = $a" + strings.Repeat(" . $a", n-1) + ";"
+}
+
+func TestScanConcatenation(t *testing.T) {
+	const n = 10000
+	src := concatenation(n)
+	s := NewScanner(src)
+	counts := map[token.Token]int{}
+	for i, ok := s.Next(); ok; i, ok = s.Next() {
+		counts[i.Typ]++
+	}
+	if counts[token.ConcatenationOperator] != n-1 || counts[token.VariableOperator] != n+1 || counts[token.Identifier] != n+1 {
+		t.Errorf("scanned %d concatenations of %d variables and %d names, expected %d of %d", counts[token.ConcatenationOperator], counts[token.VariableOperator], counts[token.Identifier], n-1, n+1)
+	}
+
+	// scanning needs a fixed number of allocations however long the input is
+	allocs := testing.AllocsPerRun(5, func() {
+		s := NewScanner(src)
+		for _, ok := s.Next(); ok; _, ok = s.Next() {
+		}
+	})
+	if allocs > 20 {
+		t.Errorf("scanning %d concatenations took %v allocations", n, allocs)
+	}
+}
+
+func BenchmarkScanConcatenation(b *testing.B) {
+	src := concatenation(10000)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := NewScanner(src)
+		for _, ok := s.Next(); ok; _, ok = s.Next() {
+		}
+	}
+}
@@ -0,0 +1,25 @@
+<?php
+namespace App\Models;
+
+/**
+ * A doc comment.
+ */
+abstract class Model extends \Base\Model implements \JsonSerializable
+{
+    const TABLE = 'models';
+    private static $instances = array();
+    protected $attributes = [];
+
+    public function __construct(array $attributes = [])
+    {
+        $this->attributes = $attributes;
+        self::$instances[] = $this;
+    }
+
+    abstract protected function validate($value);
+
+    public function jsonSerialize()
+    {
+        return static::TABLE . ':' . json_encode($this->attributes);
+    }
+}
@@ -0,0 +1,7 @@
+<html>
+<body>
+<?php if ($show): ?>
+  <p><?php echo htmlspecialchars($message); ?></p>
+<?php endif ?>
+</body>
+</html>
@@ -0,0 +1,10 @@
+<?php
+$numbers = [1, 0x1A, 0755, 0b1010, 1.5, .5, 1e10, 2.5E-3];
+$strings = ['single', "double", "with $var inside", "escaped \" quote"];
+$heredoc = <<<EOT
+Hello $name
+EOT;
+$nowdoc = <<<'EOT'
+Hello $name
+EOT;
+$bools = [true, false, null, TRUE];
@@ -0,0 +1,17 @@
+<?php
+$total = 0;
+foreach ($items as $key => $item) {
+    if ($item->price >= 10 && !$item->discounted) {
+        $total += $item->price * 0.9;
+    } elseif ($item->price != 0) {
+        $total += (int)$item->price;
+    } else {
+        continue;
+    }
+}
+// a comment
+while ($total > 100 || $total === null) {
+    $total = $total >> 1;
+    $total--;
+}
+echo "Total: $total\n", 'done';
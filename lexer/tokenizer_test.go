@@ -0,0 +1,205 @@
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stephens2424/php/token"
+)
+
+// phpToken is a token as PHP's tokenizer reports it, by name and source text.
+type phpToken struct {
+	Name, Text string
+}
+
+// tokenGetAll prints the tokens of the file named by its argument as a JSON
+// list of [name, text] pairs. Single character tokens are named by themselves.
+const tokenGetAll = `
+$out = array();
+foreach (token_get_all(file_get_contents($argv[1])) as $t) {
+	$out[] = is_array($t) ? array(token_name($t[0]), $t[1]) : array($t, $t);
+}
+echo json_encode($out);
+`
+
+// TestPHPTokenizer lexes each fixture and compares the result to the tokens
+// PHP itself finds. It only runs when PHP_TOKENIZER_TEST=1 is set in the
+// environment, and then requires php (version 8 or later, whose token names
+// are assumed) on the PATH.
+func TestPHPTokenizer(t *testing.T) {
+	if os.Getenv("PHP_TOKENIZER_TEST") != "1" {
+		t.Skip("set PHP_TOKENIZER_TEST=1 to compare with php")
+	}
+	php, err := exec.LookPath("php")
+	if err != nil {
+		t.Fatal("PHP_TOKENIZER_TEST is set, but php is not on the PATH")
+	}
+	files, err := filepath.Glob("testdata/tokenizer/*.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command(php, "-r", tokenGetAll, file).Output()
+		if err != nil {
+			t.Fatalf("%s: running php: %s", file, err)
+		}
+		var pairs [][2]string
+		if err := json.Unmarshal(out, &pairs); err != nil {
+			t.Fatalf("%s: reading php tokens: %s", file, err)
+		}
+		raw := make([]phpToken, len(pairs))
+		for i, pair := range pairs {
+			raw[i] = phpToken{Name: pair[0], Text: pair[1]}
+		}
+
+		ours, err := lexedPHPTokens(string(src))
+		if err != nil {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+		if err := compareTokens(ours, normalizePHPTokens(raw)); err != nil {
+			t.Errorf("%s: %s", file, err)
+		}
+	}
+}
+
+// lexedPHPTokens lexes src and names each significant token the way PHP
+// would. A $ followed by a name becomes a single T_VARIABLE as it is in PHP.
+func lexedPHPTokens(src string) ([]phpToken, error) {
+	var toks []phpToken
+	var prev token.Token
+	l := NewLexer(src)
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+		t := phpToken{Name: i.PHPTokenName(), Text: i.Val}
+		switch i.Typ {
+		case token.Error:
+			return toks, fmt.Errorf("lexing: %s", i.Val)
+		case token.Space, token.CommentLine, token.CommentBlock:
+			continue
+		case token.Identifier, token.This:
+			if prev == token.VariableOperator {
+				toks[len(toks)-1] = phpToken{Name: "T_VARIABLE", Text: "$" + i.Val}
+				prev = i.Typ
+				continue
+			}
+		case token.ElvisOperator:
+			// PHP lexes the ? and : of a short ternary separately
+			toks = append(toks, phpToken{Name: "?", Text: "?"}, phpToken{Name: ":", Text: ":"})
+			prev = i.Typ
+			continue
+		case token.HTML:
+			// PHP includes a single line break after ?> in the close tag.
+			if prev == token.PHPEnd {
+				for _, nl := range []string{"\r\n", "\n", "\r"} {
+					if strings.HasPrefix(t.Text, nl) {
+						t.Text = t.Text[len(nl):]
+						break
+					}
+				}
+				if t.Text == "" {
+					continue
+				}
+			}
+		}
+		toks = append(toks, t)
+		prev = i.Typ
+	}
+	return toks, nil
+}
+
+// normalizePHPTokens drops whitespace and comments from the tokens PHP reports
+// and joins the pieces of interpolated strings, heredocs, and shell commands
+// into one token each, as this package lexes them.
+func normalizePHPTokens(raw []phpToken) []phpToken {
+	var toks []phpToken
+	for i := 0; i < len(raw); i++ {
+		t := raw[i]
+		var end string
+		switch t.Name {
+		case "T_WHITESPACE", "T_COMMENT", "T_DOC_COMMENT":
+			continue
+		case "T_OPEN_TAG", "T_CLOSE_TAG":
+			t.Text = strings.TrimSpace(t.Text)
+		case `"`:
+			t.Name, end = "T_CONSTANT_ENCAPSED_STRING", `"`
+		case "`":
+			end = "`"
+		case "T_START_HEREDOC":
+			end = "T_END_HEREDOC"
+		}
+		for end != "" && i+1 < len(raw) {
+			i++
+			t.Text += raw[i].Text
+			if raw[i].Name == end {
+				break
+			}
+		}
+		toks = append(toks, t)
+	}
+	return toks
+}
+
+// compareTokens returns an error describing the first difference between the
+// tokens this package lexed and those PHP reported.
+func compareTokens(ours, theirs []phpToken) error {
+	for i := 0; i < len(ours) && i < len(theirs); i++ {
+		if ours[i] != theirs[i] {
+			return fmt.Errorf("token %d: lexed %s %q, php found %s %q", i, ours[i].Name, ours[i].Text, theirs[i].Name, theirs[i].Text)
+		}
+	}
+	if len(ours) != len(theirs) {
+		return fmt.Errorf("lexed %d tokens, php found %d", len(ours), len(theirs))
+	}
+	return nil
+}
+
+func TestNormalizePHPTokens(t *testing.T) {
+	src := "<?php\n$x = \"hi $name\";\necho <<<EOT\nbody\nEOT;\n?>\n<p>\n"
+
+	// the output of token_get_all for src
+	raw := []phpToken{
+		{"T_OPEN_TAG", "<?php\n"},
+		{"T_VARIABLE", "$x"},
+		{"T_WHITESPACE", " "},
+		{"=", "="},
+		{"T_WHITESPACE", " "},
+		{`"`, `"`},
+		{"T_ENCAPSED_AND_WHITESPACE", "hi "},
+		{"T_VARIABLE", "$name"},
+		{`"`, `"`},
+		{";", ";"},
+		{"T_WHITESPACE", "\n"},
+		{"T_ECHO", "echo"},
+		{"T_WHITESPACE", " "},
+		{"T_START_HEREDOC", "<<<EOT\n"},
+		{"T_ENCAPSED_AND_WHITESPACE", "body\n"},
+		{"T_END_HEREDOC", "EOT"},
+		{";", ";"},
+		{"T_WHITESPACE", "\n"},
+		{"T_CLOSE_TAG", "?>\n"},
+		{"T_INLINE_HTML", "<p>\n"},
+	}
+
+	ours, err := lexedPHPTokens(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compareTokens(ours, normalizePHPTokens(raw)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw[1].Text = "$y"
+	if err := compareTokens(ours, normalizePHPTokens(raw)); err == nil {
+		t.Fatal("differing token streams compared equal")
+	}
+}
@@ -0,0 +1,269 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stephens2424/php/token"
+)
+
+// A Version is a version of PHP, as major*100 + minor.
+type Version int
+
+const (
+	PHP72 Version = 702
+	PHP73 Version = 703
+	PHP74 Version = 704
+	PHP80 Version = 800
+	PHP81 Version = 801
+	PHP85 Version = 805
+)
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v/100, v%100)
+}
+
+// A Feature is a language construct introduced after PHP 7.3.
+type Feature string
+
+const (
+	ArrowFunction            Feature = "arrow function"
+	NullCoalescingAssignment Feature = "null coalescing assignment"
+	TypedProperty            Feature = "typed property"
+	Attribute                Feature = "attribute"
+	MatchExpression          Feature = "match expression"
+	NamedArgument            Feature = "named argument"
+	NullsafeOperator         Feature = "nullsafe operator"
+	PropertyPromotion        Feature = "constructor property promotion"
+	ThrowExpression          Feature = "throw expression"
+	Enum                     Feature = "enum"
+	FirstClassCallable       Feature = "first-class callable"
+	Readonly                 Feature = "readonly"
+	PipeOperator             Feature = "pipe operator"
+)
+
+// Since returns the first version of PHP supporting f.
+func (f Feature) Since() Version {
+	switch f {
+	case ArrowFunction, NullCoalescingAssignment, TypedProperty:
+		return PHP74
+	case Attribute, MatchExpression, NamedArgument, NullsafeOperator, PropertyPromotion, ThrowExpression:
+		return PHP80
+	case Enum, FirstClassCallable, Readonly:
+		return PHP81
+	case PipeOperator:
+		return PHP85
+	}
+	return 0
+}
+
+// TargetVersion makes the lexer record a Violation wherever the input uses
+// syntax that v does not support. Such syntax is lexed as usual. By default
+// no version is targeted and no violations are recorded.
+func TargetVersion(v Version) Option {
+	return func(l *lexer) {
+		l.version = v
+	}
+}
+
+// A Violation is a use of syntax that is newer than the targeted version.
+type Violation struct {
+	Feature Feature
+	Since   Version // Since is the first version supporting the feature.
+	Begin   token.Position
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s requires PHP %s", v.Feature, v.Since)
+}
+
+// Violations returns the violations of the targeted version found by a lexer
+// returned by NewLexer, or by the lexer under a stream wrapping one, such as
+// a token.Subset. It must only be called once s has returned EOF.
+func Violations(s token.Stream) []Violation {
+	for {
+		switch v := s.(type) {
+		case interface{ Violations() []Violation }:
+			return v.Violations()
+		case interface{ Unwrap() token.Stream }:
+			s = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// Violations returns the violations of the targeted version found so far.
+func (l *lexer) Violations() []Violation {
+	return l.violations
+}
+
+// A FeatureSet is the set of features used by a file.
+type FeatureSet map[Feature]bool
+
+// MinVersion returns the first version of PHP supporting every feature in s,
+// or 0 if s is empty.
+func (s FeatureSet) MinVersion() Version {
+	v := Version(0)
+	for f := range s {
+		if f.Since() > v {
+			v = f.Since()
+		}
+	}
+	return v
+}
+
+// Features lexes input and returns the features it uses. If input cannot be
+// lexed, the features found before the error are returned with it.
+func Features(input string) (FeatureSet, error) {
+	l := newLexer(input, func(l *lexer) { l.features = FeatureSet{} })
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+		if i.Typ == token.Error {
+			return l.features, errors.New(i.Val)
+		}
+	}
+	return l.features, nil
+}
+
+// trackFeatures records the feature used by i, the item being emitted, if
+// features are being collected or it is newer than the targeted version.
+func (l *lexer) trackFeatures(i token.Item) {
+	if l.version == 0 && l.features == nil {
+		return
+	}
+	l.trackMatch(i)
+	if f := l.feature(i); f != "" {
+		l.recordFeature(f, i.Begin)
+	}
+}
+
+// recordFeature records the use of f at begin.
+func (l *lexer) recordFeature(f Feature, begin token.Position) {
+	if l.features != nil {
+		l.features[f] = true
+	}
+	if l.version != 0 && f.Since() > l.version {
+		l.violations = append(l.violations, Violation{Feature: f, Since: f.Since(), Begin: begin})
+	}
+}
+
+// trackMatch follows the head of each possible match expression, a match
+// followed by a parenthesized subject, and records a MatchExpression if a
+// block follows the subject's closing paren.
+func (l *lexer) trackMatch(i token.Item) {
+	if i.Typ.Type().Is(token.Trivia) {
+		return
+	}
+	for n := len(l.matchHeads); n > 0; n = len(l.matchHeads) {
+		h := &l.matchHeads[n-1]
+		if h.closed {
+			if i.Typ == token.BlockBegin {
+				l.recordFeature(MatchExpression, h.begin)
+			}
+			l.matchHeads = l.matchHeads[:n-1]
+			continue
+		}
+		if i.Typ == token.OpenParen {
+			h.depth++
+		} else if h.depth == 0 {
+			// match is not followed by a paren
+			l.matchHeads = l.matchHeads[:n-1]
+			continue
+		} else if i.Typ == token.CloseParen {
+			h.depth--
+			h.closed = h.depth == 0
+		}
+		break
+	}
+	if i.Typ == token.Identifier && strings.EqualFold(i.Val, "match") && !isMemberPrefix(l.lastToken) {
+		l.matchHeads = append(l.matchHeads, matchHead{begin: i.Begin})
+	}
+}
+
+// A matchHead is the head of a possible match expression.
+type matchHead struct {
+	begin  token.Position
+	depth  int  // depth is the paren depth within the subject.
+	closed bool // closed is true just past the paren closing the subject.
+}
+
+// feature returns the feature i, the item being emitted, begins, if any.
+func (l *lexer) feature(i token.Item) Feature {
+	switch i.Typ {
+	case token.AssignmentOperator:
+		if i.Val == "??=" {
+			return NullCoalescingAssignment
+		}
+	case token.NullsafeObjectOperator:
+		return NullsafeOperator
+	case token.Readonly:
+		return Readonly
+	case token.PipeOperator:
+		return PipeOperator
+	case token.Attribute:
+		return Attribute
+	case token.Public, token.Protected, token.Private:
+		if l.signature {
+			return PropertyPromotion
+		}
+	case token.ArgumentName:
+		return NamedArgument
+	case token.Throw:
+		switch l.lastToken {
+		case token.CoalesceOperator, token.ElvisOperator, token.TernaryOperator1, token.ArrayKeyOperator,
+			token.AssignmentOperator, token.Comma, token.OpenParen,
+			token.AndOperator, token.OrOperator, token.WrittenAndOperator, token.WrittenOrOperator:
+			// a throw within an expression rather than a statement
+			return ThrowExpression
+		}
+	case token.Ellipsis:
+		if l.lastToken == token.OpenParen && strings.HasPrefix(skipTrivia(l.input[l.pos:]), ")") {
+			return FirstClassCallable
+		}
+	case token.TypeHint:
+		if isModifier(l.lastToken) && !l.signature {
+			return TypedProperty
+		}
+	case token.Identifier:
+		after := skipTrivia(l.input[l.pos:])
+		if isMemberPrefix(l.lastToken) {
+			// the name of a function, variable or member
+			return ""
+		}
+		if isModifier(l.lastToken) && !l.signature && strings.HasPrefix(after, "$") {
+			return TypedProperty
+		}
+		switch strings.ToLower(i.Val) {
+		case "fn":
+			if strings.HasPrefix(skipTrivia(strings.TrimPrefix(after, "&")), "(") {
+				return ArrowFunction
+			}
+		case "enum":
+			if l.statementStart && identifierLength(after) > 0 {
+				return Enum
+			}
+		}
+	}
+	return ""
+}
+
+// isModifier returns true if t may precede the type of a property.
+func isModifier(t token.Token) bool {
+	switch t {
+	case token.Public, token.Protected, token.Private, token.Var, token.Static, token.Readonly:
+		return true
+	}
+	return false
+}
+
+// isMemberPrefix returns true if t precedes the name of a function,
+// variable or member, which is never a keyword.
+func isMemberPrefix(t token.Token) bool {
+	switch t {
+	case token.Function, token.VariableOperator, token.ObjectOperator,
+		token.NullsafeObjectOperator, token.ScopeResolutionOperator:
+		return true
+	}
+	return false
+}
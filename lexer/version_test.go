@@ -0,0 +1,150 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stephens2424/php/token"
+)
+
+func violations(src string, v Version) []Violation {
+	l := NewLexer(src, TargetVersion(v))
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+	}
+	return Violations(l)
+}
+
+func TestTargetVersion(t *testing.T) {
+	src := "<?php\n$f = fn($x) => $x * 2;"
+	found := violations(src, PHP73)
+	if len(found) != 1 {
+		t.Fatalf("expected a violation, found %v", found)
+	}
+	expected := Violation{Feature: "arrow function", Since: PHP74, Begin: token.Position{Line: 2, Column: 6, Position: 11}}
+	if found[0] != expected {
+		t.Errorf("found %v, expected %v", found[0], expected)
+	}
+	if found[0].Error() != "arrow function requires PHP 7.4" {
+		t.Errorf("unexpected message %q", found[0].Error())
+	}
+	if found := violations(src, PHP74); len(found) != 0 {
+		t.Errorf("expected no violations, found %v", found)
+	}
+	if found := Violations(NewLexer(src)); len(found) != 0 {
+		t.Errorf("expected no violations without a target, found %v", found)
+	}
+}
+
+func TestWrappedViolations(t *testing.T) {
+	src := "<?php\n$f = fn($x) => $x * 2;"
+	l := token.Subset(NewLexer(src, TargetVersion(PHP73)), token.Significant)
+	for i := l.Next(); i.Typ != token.EOF; i = l.Next() {
+	}
+	if found := Violations(l); len(found) != 1 || found[0].Feature != ArrowFunction {
+		t.Errorf("found %v in a subset, expected an arrow function", found)
+	}
+
+	s := NewScanner(src, TargetVersion(PHP73))
+	for _, ok := s.Next(); ok; _, ok = s.Next() {
+	}
+	if found := s.Violations(); len(found) != 1 || found[0].Feature != ArrowFunction {
+		t.Errorf("found %v in a scanner, expected an arrow function", found)
+	}
+}
+
+func TestTargetVersionFeatures(t *testing.T) {
+	tests := []struct {
+		src     string
+		feature Feature
+		since   Version
+	}{
+		{"$a ??= 1;", "null coalescing assignment", PHP74},
+		{"$a?->b;", "nullsafe operator", PHP80},
+		{"$a = $b ?? throw new E();", "throw expression", PHP80},
+		{"$f = fn() => throw new E();", "throw expression", PHP80},
+		{"$f = fn&($x) => $x;", "arrow function", PHP74},
+		{"echo match ($a) { 1 => 2 };", "match expression", PHP80},
+		{`echo match (f(")")) { 1 => 2 };`, "match expression", PHP80},
+		{"echo match (match ($a) { 1 => 2 }) { 2 => 3 };", "match expression", PHP80},
+		{"enum Suit { case Hearts; }", "enum", PHP81},
+		{"$f = strlen(...);", "first-class callable", PHP81},
+		{"class A { public readonly int $a; }", "readonly", PHP81},
+		{"$a |> f(...);", "pipe operator", PHP85},
+	}
+	for _, tt := range tests {
+		found := violations("<?php "+tt.src, PHP73)
+		violated := false
+		for _, v := range found {
+			violated = violated || (v.Feature == tt.feature && v.Since == tt.since)
+		}
+		if !violated {
+			t.Errorf("%s: found %v, expected a violation of %s since %s", tt.src, found, tt.feature, tt.since)
+		}
+		if found := violations("<?php "+tt.src, tt.since); len(found) != 0 {
+			t.Errorf("%s: found %v targeting %s", tt.src, found, tt.since)
+		}
+	}
+
+	for _, src := range []string{
+		"function fn() {}",
+		"match($a, $b);",
+		"$o->match($a) {};",
+		`match(")") ;{}`,
+		"match;{}",
+		"$enum = 1;",
+		"$fn = 1;",
+		"$fn(1);",
+		"$fn & ($a);",
+		"throw new E();",
+		"if ($a) throw new E();",
+		"switch ($a) { case 1: throw new E(); }",
+	} {
+		if found := violations("<?php "+src, PHP73); len(found) != 0 {
+			t.Errorf("%s: found %v", src, found)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	src := `<?php
+#[Entity]
+class Point {
+	public int $x;
+	public ?int $y = null;
+
+	public function __construct(private string $name) {}
+}
+
+enum Suit {
+	case Hearts;
+}
+
+$double = fn($x) => $x * 2;
+$p = new Point(name: "a");
+echo $p?->name, $a ? $b : $c, static::X;
+`
+	found, err := Features(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := FeatureSet{
+		Attribute:         true,
+		TypedProperty:     true,
+		PropertyPromotion: true,
+		Enum:              true,
+		ArrowFunction:     true,
+		NamedArgument:     true,
+		NullsafeOperator:  true,
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("found features %v, expected %v", found, expected)
+	}
+	if v := found.MinVersion(); v != PHP81 {
+		t.Errorf("minimum version %s, expected 8.1", v)
+	}
+
+	found, err = Features("<?php echo 1;")
+	if err != nil || len(found) != 0 || found.MinVersion() != 0 {
+		t.Errorf("found features %v, %v in a file using none", found, err)
+	}
+}
@@ -6,38 +6,51 @@ import (
 )
 
 func (p *Parser) parseArrayLookup(e ast.Expr) ast.Expr {
-	p.expectCurrent(token.ArrayLookupOperatorLeft, token.BlockBegin)
-	switch Typ := p.peek().Typ; Typ {
-	case token.ArrayLookupOperatorRight, token.BlockBegin:
-		p.expect(token.ArrayLookupOperatorRight, token.BlockEnd)
-		return ast.ArrayAppendExpr{Array: e}
+	p.expectCurrent(token.ArrayLookupOperatorLeft, token.CurlyLookupOperatorLeft)
+	end := token.ArrayLookupOperatorRight
+	if p.current.Typ == token.CurlyLookupOperatorLeft {
+		end = token.CurlyLookupOperatorRight
+	}
+	if p.accept(end) {
+		return ast.ArrayAppendExpr{Begin: begin(e), Array: e}
 	}
 	p.next()
 	expr := &ast.ArrayLookupExpr{
+		Begin: begin(e),
 		Array: e,
 		Index: p.parseExpression(),
 	}
-	p.expect(token.ArrayLookupOperatorRight, token.BlockEnd)
+	p.expect(end)
 	return expr
 }
 
 func (p *Parser) parseArrayDeclaration() ast.Expr {
 	var endType token.Token
 	var pairs []ast.ArrayPair
-	p.expectCurrent(token.Array, token.ArrayLookupOperatorLeft)
+	p.expectCurrent(token.Array, token.ShortArrayLeft)
+	begin := p.current.Begin
 	switch p.current.Typ {
 	case token.Array:
 		p.expect(token.OpenParen)
 		endType = token.CloseParen
-	case token.ArrayLookupOperatorLeft:
-		endType = token.ArrayLookupOperatorRight
+	case token.ShortArrayLeft:
+		endType = token.ShortArrayRight
 	}
+	skipLine := 0
 ArrayLoop:
 	for {
 		var key, Val ast.Expr
 		switch p.peek().Typ {
 		case endType:
 			break ArrayLoop
+		case token.Comma:
+			// a skipped element, as in a destructuring [, $b] = $arr
+			p.expect(token.Comma)
+			if skipLine == 0 {
+				skipLine = p.current.Begin.Line
+			}
+			pairs = append(pairs, ast.ArrayPair{})
+			continue
 		default:
 			Val = p.parseNextExpression()
 		}
@@ -63,37 +76,84 @@ ArrayLoop:
 		pairs = append(pairs, ast.ArrayPair{Key: key, Value: Val})
 	}
 	p.expect(endType)
-	return &ast.ArrayExpr{Pairs: pairs}
+	array := &ast.ArrayExpr{Begin: begin, Pairs: pairs}
+	if skipLine > 0 {
+		p.skipped = append(p.skipped, skippedElements{array: array, line: skipLine})
+	}
+	return array
+}
+
+// destructure marks the arrays nested in the destructuring pattern a, and a
+// itself, as patterns, in which elements may be skipped.
+func (p *Parser) destructure(a *ast.ArrayExpr) {
+	for i, s := range p.skipped {
+		if s.array == a {
+			p.skipped = append(p.skipped[:i], p.skipped[i+1:]...)
+			break
+		}
+	}
+	for _, pair := range a.Pairs {
+		if nested, ok := pair.Value.(*ast.ArrayExpr); ok {
+			p.destructure(nested)
+		}
+	}
 }
 
 func (p *Parser) parseList() ast.Expr {
 	l := &ast.ListStatement{
+		Begin:     p.current.Begin,
 		Assignees: make([]ast.Assignable, 0),
 	}
-	p.expect(token.OpenParen)
-	for {
-		if p.accept(token.Comma) {
-			continue
-		}
-		if p.peek().Typ == token.CloseParen {
-			break
-		}
-		p.next()
-		op, ok := p.parseOperand().(ast.Assignable)
-		if ok {
-			l.Assignees = append(l.Assignees, op)
-		} else {
-			p.errorf("%v list element is not assignable", op)
-		}
-		if p.peek().Typ != token.Comma {
-			break
+	pattern := p.parseListPattern()
+	p.destructure(pattern)
+	keyed := false
+	for _, pair := range pattern.Pairs {
+		keyed = keyed || pair.Key != nil
+	}
+	for _, pair := range pattern.Pairs {
+		assignee, _ := pair.Value.(ast.Assignable)
+		l.Assignees = append(l.Assignees, assignee)
+		if keyed {
+			l.Keys = append(l.Keys, pair.Key)
 		}
-		p.expect(token.Comma)
 	}
-	p.expect(token.CloseParen)
 	p.expect(token.AssignmentOperator)
 	l.Operator = p.current.Val
 	l.Value = p.parseNextExpression()
 	return l
+}
+
+// parseListPattern parses the parenthesized elements of a list() pattern,
+// which may themselves be list() or [] patterns nested to any depth. The
+// pattern is returned as an array whose values are the assignees. An element
+// that is skipped, as in list(, $b), has a nil value.
+func (p *Parser) parseListPattern() *ast.ArrayExpr {
+	pattern := &ast.ArrayExpr{Begin: p.current.Begin}
+	p.expect(token.OpenParen)
+	for p.peek().Typ != token.CloseParen {
+		var pair ast.ArrayPair
+		if p.peek().Typ != token.Comma {
+			pair.Value = p.parseListElement()
+			if p.accept(token.ArrayKeyOperator) {
+				pair.Key = pair.Value
+				pair.Value = p.parseListElement()
+			}
+			if _, ok := pair.Value.(ast.Assignable); !ok {
+				p.errorf("%v list element is not assignable", pair.Value)
+			}
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+		if !p.accept(token.Comma) {
+			break
+		}
+	}
+	p.expect(token.CloseParen)
+	return pattern
+}
 
+func (p *Parser) parseListElement() ast.Expr {
+	if p.accept(token.List) {
+		return p.parseListPattern()
+	}
+	return p.parseNextExpression()
 }
@@ -82,3 +82,180 @@ func TestArrayBracket(t *testing.T) {
 		t.Fatalf("Array bracked did not parse correctly")
 	}
 }
+
+func TestNestedDestructuring(t *testing.T) {
+	testStr := `<?
+    list($a, "inner" => list(, [$b, "c" => $c])) = $data;
+    [$x, ["y" => [$y, $z]]] = f();`
+
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatalf("Did not parse nested destructuring correctly: %s", err)
+	}
+
+	tree := []ast.Statement{
+		ast.ExprStmt{&ast.ListStatement{
+			Operator: "=",
+			Assignees: []ast.Assignable{
+				ast.NewVariable("a"),
+				&ast.ArrayExpr{Pairs: []ast.ArrayPair{
+					{},
+					{Value: &ast.ArrayExpr{Pairs: []ast.ArrayPair{
+						{Value: ast.NewVariable("b")},
+						{Key: &ast.Literal{Type: ast.String, Value: `"c"`}, Value: ast.NewVariable("c")},
+					}}},
+				}},
+			},
+			Keys:  []ast.Expr{nil, &ast.Literal{Type: ast.String, Value: `"inner"`}},
+			Value: ast.NewVariable("data"),
+		}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Operator: "=",
+			Assignee: &ast.ArrayExpr{Pairs: []ast.ArrayPair{
+				{Value: ast.NewVariable("x")},
+				{Value: &ast.ArrayExpr{Pairs: []ast.ArrayPair{
+					{
+						Key: &ast.Literal{Type: ast.String, Value: `"y"`},
+						Value: &ast.ArrayExpr{Pairs: []ast.ArrayPair{
+							{Value: ast.NewVariable("y")},
+							{Value: ast.NewVariable("z")},
+						}},
+					},
+				}}},
+			}},
+			Value: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "f"},
+				Arguments:    []ast.Expr{},
+			},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Nested destructuring did not parse correctly")
+		}
+	}
+
+	p = NewParser()
+	p.disableScoping = true
+	if _, err := p.Parse("test.php", `<? list($a, list(1)) = $data;`); err == nil {
+		t.Fatal("expected an error destructuring into a literal")
+	}
+}
+
+func TestSkippedElements(t *testing.T) {
+	for _, src := range []string{
+		`[$a, , $b] = $c;`,
+		`[[, $x], [$y, , ]] = $d;`,
+		`list($a, [, $b]) = $e;`,
+	} {
+		p := NewParser()
+		p.disableScoping = true
+		if _, err := p.Parse("test.php", "<?php "+src); err != nil {
+			t.Errorf("%s: %s", src, err)
+		}
+	}
+
+	for _, src := range []string{
+		`$x = [1, , 2];`,
+		`f([, $a]);`,
+		`[$a, [1, , 2]] + 1;`,
+		`[, $a] += $b;`,
+	} {
+		p := NewParser()
+		p.disableScoping = true
+		_, err := p.Parse("test.php", "<?php "+src)
+		if err == nil || err.Error() != "test.php:1: cannot use empty array elements in arrays" {
+			t.Errorf("%s: expected an error for the skipped element, found %v", src, err)
+		}
+	}
+}
+
+func TestChainedLookups(t *testing.T) {
+	testStr := `<?php
+    $a[1][2]['k'];
+    $s{0};`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.ArrayLookupExpr{
+			Array: &ast.ArrayLookupExpr{
+				Array: &ast.ArrayLookupExpr{
+					Array: ast.NewVariable("a"),
+					Index: &ast.Literal{Type: ast.Float, Value: "1"},
+				},
+				Index: &ast.Literal{Type: ast.Float, Value: "2"},
+			},
+			Index: &ast.Literal{Type: ast.String, Value: `'k'`},
+		}},
+		ast.ExprStmt{&ast.ArrayLookupExpr{
+			Array: ast.NewVariable("s"),
+			Index: &ast.Literal{Type: ast.Float, Value: "0"},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Chained lookup did not parse correctly")
+		}
+	}
+}
+
+func TestShortArrayLookup(t *testing.T) {
+	testStr := `<?php
+    $x = [1, 2];
+    $x[0] = 1;
+    ['a'][0];
+    'ab'[1];`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value: &ast.ArrayExpr{
+				Pairs: []ast.ArrayPair{
+					{Value: &ast.Literal{Type: ast.Float, Value: "1"}},
+					{Value: &ast.Literal{Type: ast.Float, Value: "2"}},
+				},
+			},
+		}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: &ast.ArrayLookupExpr{
+				Array: ast.NewVariable("x"),
+				Index: &ast.Literal{Type: ast.Float, Value: "0"},
+			},
+			Operator: "=",
+			Value:    &ast.Literal{Type: ast.Float, Value: "1"},
+		}},
+		ast.ExprStmt{&ast.ArrayLookupExpr{
+			Array: &ast.ArrayExpr{
+				Pairs: []ast.ArrayPair{{Value: &ast.Literal{Type: ast.String, Value: `'a'`}}},
+			},
+			Index: &ast.Literal{Type: ast.Float, Value: "0"},
+		}},
+		ast.ExprStmt{&ast.ArrayLookupExpr{
+			Array: &ast.Literal{Type: ast.String, Value: `'ab'`},
+			Index: &ast.Literal{Type: ast.Float, Value: "1"},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Short array or lookup did not parse correctly")
+		}
+	}
+}
@@ -40,8 +40,10 @@ func (p *Parser) parseStatementsUntil(endTokens ...token.Token) *ast.Block {
 	return block
 }
 
+// parseExpressionsUntil parses a list of expressions delimited by separator
+// up to one of endTokens. It returns nil if the list is empty.
 func (p *Parser) parseExpressionsUntil(separator token.Token, endTokens ...token.Token) []ast.Expr {
-	exprs := make([]ast.Expr, 0, 1)
+	var exprs []ast.Expr
 	breakTypes := map[token.Token]bool{}
 	for _, Typ := range endTokens {
 		breakTypes[Typ] = true
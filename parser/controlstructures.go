@@ -6,107 +6,128 @@ import (
 )
 
 func (p *Parser) parseIf() *ast.IfStmt {
-	n := &ast.IfStmt{Branches: make([]ast.IfBranch, 0, 1)}
+	n := &ast.IfStmt{Begin: p.current.Begin, Branches: make([]ast.IfBranch, 0, 1)}
 
-	n.Branches = append(n.Branches, p.parseIfBranch())
+	branch, alternate := p.parseIfBranch()
+	n.Branches = append(n.Branches, branch)
+	n.Alternate = alternate
 
 	for {
 		switch p.current.Typ {
 		case token.ElseIf:
-			n.Branches = append(n.Branches, p.parseIfBranch())
+			branch, _ := p.parseIfBranch()
+			n.Branches = append(n.Branches, branch)
 		case token.Else:
 			p.next()
 			if p.current.Typ == token.If {
-				n.Branches = append(n.Branches, p.parseIfBranch())
+				branch, _ := p.parseIfBranch()
+				n.Branches = append(n.Branches, branch)
 			} else {
-				n.ElseBlock = p.parseControlBlock(token.EndIf)
+				n.ElseBlock, _ = p.parseControlBlock(token.EndIf)
+				p.endControlBlock(n.Alternate, token.EndIf)
 				return n
 			}
 		default:
-			if p.current.Typ != token.EndIf {
-				p.backup()
-			}
+			p.endControlBlock(n.Alternate, token.EndIf)
 			return n
 		}
 	}
 }
 
-func (p *Parser) parseIfBranch() ast.IfBranch {
+func (p *Parser) parseIfBranch() (ast.IfBranch, bool) {
 	b := ast.IfBranch{}
 	p.expect(token.OpenParen)
 	b.Condition = p.parseNextExpression()
 	p.expect(token.CloseParen)
 
 	p.next()
-	b.Block = p.parseControlBlock(token.EndIf, token.ElseIf, token.Else)
-	return b
+	block, alternate := p.parseControlBlock(token.EndIf, token.ElseIf, token.Else)
+	b.Block = block
+	return b, alternate
 }
 
 func (p *Parser) parseWhile() ast.Statement {
+	begin := p.current.Begin
 	p.expect(token.OpenParen)
 	term := p.parseNextExpression()
 	p.expect(token.CloseParen)
 	p.next()
-	block := p.parseControlBlock(token.EndWhile)
+	block, alternate := p.parseControlBlock(token.EndWhile)
+	p.endControlBlock(alternate, token.EndWhile)
 	return &ast.WhileStmt{
+		Begin:       begin,
 		Termination: term,
 		LoopBlock:   block,
+		Alternate:   alternate,
 	}
 }
 
 func (p *Parser) parseForeach() ast.Statement {
-	stmt := &ast.ForeachStmt{}
+	stmt := &ast.ForeachStmt{Begin: p.current.Begin}
 	p.expect(token.OpenParen)
 	stmt.Source = p.parseNextExpression()
 	p.expect(token.AsOperator)
-	if p.peek().Typ == token.AmpersandOperator {
-		p.expect(token.AmpersandOperator)
-	}
+	stmt.ByRef = p.accept(token.ReferenceOperator)
 	p.expect(token.VariableOperator)
-	p.next()
-	first := ast.NewVariable(p.current.Val)
+	first := p.parseVariableName()
 	if p.peek().Typ == token.ArrayKeyOperator {
 		stmt.Key = first
 		p.expect(token.ArrayKeyOperator)
-		if p.peek().Typ == token.AmpersandOperator {
-			p.expect(token.AmpersandOperator)
+		if stmt.ByRef {
+			p.errorf("foreach key cannot be a reference")
 		}
+		stmt.ByRef = p.accept(token.ReferenceOperator)
 		p.expect(token.VariableOperator)
-		p.next()
-		stmt.Value = ast.NewVariable(p.current.Val)
+		stmt.Value = p.parseVariableName()
 	} else {
 		stmt.Value = first
 	}
 	p.expect(token.CloseParen)
 	p.next()
-	stmt.LoopBlock = p.parseControlBlock(token.EndForeach)
+	stmt.LoopBlock, stmt.Alternate = p.parseControlBlock(token.EndForeach)
+	p.endControlBlock(stmt.Alternate, token.EndForeach)
 	return stmt
 }
 
-func (p *Parser) parseControlBlock(end ...token.Token) ast.Statement {
+// parseControlBlock parses the statement controlled by a control structure,
+// or the statements up to one of end if it is written in the alternative
+// syntax, as in while ($a): ... endwhile; It reports whether the alternative
+// syntax was used.
+func (p *Parser) parseControlBlock(end ...token.Token) (ast.Statement, bool) {
 	// try to parse this in bash style, but it requires an end token
-	if len(end) > 0 && p.current.Typ == token.TernaryOperator2 {
-		return p.parseStatementsUntil(end...)
+	if len(end) > 0 && p.current.Typ == token.AlternateBlockBegin {
+		return p.parseStatementsUntil(end...), true
 	}
 	stmt := p.parseStmt()
 	p.next()
-	return stmt
+	return stmt, false
+}
+
+// endControlBlock leaves the parser on the last token of a control
+// structure once its controlled statements have been parsed. Only a
+// structure written in the alternative syntax ends with the end token, so
+// that an if in braces within an alternative if leaves the endif to it.
+func (p *Parser) endControlBlock(alternate bool, end token.Token) {
+	if !alternate || p.current.Typ != end {
+		p.backup()
+	}
 }
 
 func (p *Parser) parseFor() ast.Statement {
-	stmt := &ast.ForStmt{}
+	stmt := &ast.ForStmt{Begin: p.current.Begin}
 	p.expect(token.OpenParen)
 	stmt.Initialization = p.parseExpressionsUntil(token.Comma, token.StatementEnd)
 	stmt.Termination = p.parseExpressionsUntil(token.Comma, token.StatementEnd)
 	stmt.Iteration = p.parseExpressionsUntil(token.Comma, token.CloseParen)
 	p.expectCurrent(token.CloseParen)
 	p.next()
-	stmt.LoopBlock = p.parseControlBlock(token.EndFor)
-	p.backup()
+	stmt.LoopBlock, stmt.Alternate = p.parseControlBlock(token.EndFor)
+	p.endControlBlock(stmt.Alternate, token.EndFor)
 	return stmt
 }
 
 func (p *Parser) parseDo() ast.Statement {
+	begin := p.current.Begin
 	block := p.parseBlock()
 	p.expect(token.While)
 	p.expect(token.OpenParen)
@@ -114,17 +135,19 @@ func (p *Parser) parseDo() ast.Statement {
 	p.expect(token.CloseParen)
 	p.expectStmtEnd()
 	return &ast.DoWhileStmt{
+		Begin:       begin,
 		Termination: term,
 		LoopBlock:   block,
 	}
 }
 
 func (p *Parser) parseSwitch() ast.Statement {
-	stmt := ast.SwitchStmt{}
+	stmt := ast.SwitchStmt{Begin: p.current.Begin}
 	p.expect(token.OpenParen)
 	stmt.Expr = p.parseExpression()
 	p.expectCurrent(token.CloseParen)
-	p.expect(token.BlockBegin, token.TernaryOperator2)
+	p.expect(token.BlockBegin, token.AlternateBlockBegin)
+	stmt.Alternate = p.current.Typ == token.AlternateBlockBegin
 	p.next()
 	for {
 		switch p.current.Typ {
@@ -203,6 +226,9 @@ func (p *Parser) parseDeclareBlock() *ast.DeclareBlock {
 
 	if p.peek().Typ == token.BlockBegin {
 		declare.Statements = p.parseBlock()
+	} else if p.accept(token.AlternateBlockBegin) {
+		declare.Statements = p.parseStatementsUntil(token.EndDeclare)
+		declare.Alternate = true
 	} else {
 		p.expect(token.StatementEnd)
 	}
@@ -8,81 +8,55 @@ import (
 	"github.com/stephens2424/php/token"
 )
 
-var operatorPrecedence = map[token.Token]int{
-	token.ArrayLookupOperatorLeft: 19,
-	token.UnaryOperator:           18,
-	token.BitwiseNotOperator:      18,
-	token.CastOperator:            18,
-	token.InstanceofOperator:      17,
-	token.NegationOperator:        16,
-	token.MultOperator:            15,
-	token.AdditionOperator:        14,
-	token.SubtractionOperator:     14,
-	token.ConcatenationOperator:   14,
-
-	token.BitwiseShiftOperator: 13,
-	token.ComparisonOperator:   12,
-	token.EqualityOperator:     11,
-
-	token.AmpersandOperator:  10,
-	token.BitwiseXorOperator: 9,
-	token.BitwiseOrOperator:  8,
-	token.AndOperator:        7,
-	token.OrOperator:         6,
-	token.TernaryOperator1:   5,
-	token.TernaryOperator2:   5,
-
-	/*
-	   PHP's documentation would have this operator be at 4, but it also notes:
-
-	       Although = has a lower precedence than most other operators, PHP will
-	       still allow expressions similar to the following: if (!$a = foo()), in
-	       which case the return value of foo() is put into $a.
-
-	   Thus, we put it at 17, pending further testing.
-	*/
-	token.AssignmentOperator: 17,
-	token.WrittenAndOperator: 3,
-	token.WrittenXorOperator: 2,
-	token.WrittenOrOperator:  1,
-}
-
 func (p *Parser) parseExpression() (expr ast.Expr) {
 	originalParenLev := p.parenLevel
 
 	switch p.current.Typ {
-	case token.IgnoreErrorOperator:
-		return p.parseNextExpression()
 	case token.List:
 		expr = p.parseList()
-	case token.AmpersandOperator, token.SubtractionOperator:
-		expr := p.parseUnaryExpressionRight(p.parseOperand(), p.current)
-		return p.parseOperation(originalParenLev, expr)
+	case token.AmpersandOperator, token.ReferenceOperator, token.SubtractionOperator:
+		// parseOperand applies the operator to the operand following it
+		return p.parseOperation(originalParenLev, p.parseOperand(), 0)
 	case token.UnaryOperator,
 		token.NegationOperator,
 		token.CastOperator,
 		token.BitwiseNotOperator,
-		token.ArrayLookupOperatorLeft,
+		token.IgnoreErrorOperator,
+		token.ShortArrayLeft,
 		token.Function,
 		token.NewOperator,
 		token.VariableOperator,
 		token.Array,
 		token.Identifier,
-		token.StringLiteral,
+		token.SingleQuotedString,
+		token.DoubleQuotedString,
+		token.Heredoc,
+		token.Nowdoc,
 		token.NumberLiteral,
 		token.BooleanLiteral,
+		token.MagicConstant,
 		token.Null,
 		token.Self,
 		token.Static,
 		token.Parent,
 		token.Include,
 		token.Exit,
+		token.Yield,
+		token.YieldFrom,
+		token.Clone,
+		token.Print,
+		token.Throw,
+		token.Isset,
+		token.Empty,
+		token.Unset,
 		token.ShellCommand:
-		expr = p.parseOperation(originalParenLev, p.parseOperand())
+		expr = p.parseOperation(originalParenLev, p.parseOperand(), 0)
 	case token.OpenParen:
 		// check for a cast operator that happens to have had spaces in it, and was thus lexed incorrectly
 		if op := p.checkForCast(); op != nil {
-			expr = p.parseUnaryExpressionRight(p.parseNextExpression(), *op)
+			p.next()
+			expr = p.parseUnaryExpressionRight(p.parseUnaryOperand(*op), *op)
+			expr = p.parseOperation(originalParenLev, expr, 0)
 			break
 		}
 		p.parenLevel++
@@ -90,7 +64,7 @@ func (p *Parser) parseExpression() (expr ast.Expr) {
 		expr = p.parseExpression()
 		p.expect(token.CloseParen)
 		p.parenLevel--
-		expr = p.parseOperation(originalParenLev, expr)
+		expr = p.parseOperation(originalParenLev, expr, 0)
 	default:
 		p.errorf("Expected expression. Found %s", p.current)
 	}
@@ -132,11 +106,19 @@ func (p *Parser) isCastType(s string) bool {
 	return true
 }
 
-func (p *Parser) parseOperation(originalParenLevel int, lhs ast.Expr) (expr ast.Expr) {
+// parseOperation applies the operators following lhs to it, for as long as
+// they bind more tightly than the precedence level min. A min of 0 applies
+// every operator up to the end of the expression.
+func (p *Parser) parseOperation(originalParenLevel int, lhs ast.Expr, min int) (expr ast.Expr) {
 	p.next()
+	if level, _, ok := p.current.Typ.Precedence(); ok && level <= min && isOperator(p.current.Typ) {
+		// the operator applies to an expression that includes lhs
+		p.backup()
+		return lhs
+	}
 	switch operationTypeForToken(p.current.Typ) {
 	case ignoreErrorOperation:
-		return p.parseOperation(originalParenLevel, lhs)
+		return p.parseOperation(originalParenLevel, lhs, min)
 	case unaryOperation:
 		expr = p.parseUnaryExpressionLeft(lhs, p.current)
 	case assignmentOperation, binaryOperation:
@@ -164,15 +146,21 @@ func (p *Parser) parseOperation(originalParenLevel int, lhs ast.Expr) (expr ast.
 		return lhs
 	}
 
-	return p.parseOperation(originalParenLevel, expr)
+	return p.parseOperation(originalParenLevel, expr, min)
 }
 
 func (p *Parser) parseAssignmentOperation(lhs, rhs ast.Expr, operator token.Item) (expr ast.Expr) {
 	assignee, ok := lhs.(ast.Assignable)
 	if !ok {
 		p.errorf("%s is not assignable", lhs)
+	} else if isNullsafe(lhs) {
+		p.errorf("%s uses the nullsafe operator and is not assignable", lhs)
+	}
+	if pattern, ok := lhs.(*ast.ArrayExpr); ok && operator.Val == "=" {
+		p.destructure(pattern)
 	}
 	expr = ast.AssignmentExpr{
+		Begin:    begin(lhs),
 		Assignee: assignee,
 		Operator: operator.Val,
 		Value:    rhs,
@@ -180,6 +168,22 @@ func (p *Parser) parseAssignmentOperation(lhs, rhs ast.Expr, operator token.Item
 	return expr
 }
 
+// isNullsafe reports whether e is a property or array lookup that follows a
+// nullsafe operator anywhere in its chain, e.g. $a?->b->c.
+func isNullsafe(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.PropertyCallExpr:
+		return e.Nullsafe || isNullsafe(e.Receiver)
+	case *ast.MethodCallExpr:
+		return e.Nullsafe || isNullsafe(e.Receiver)
+	case *ast.ArrayLookupExpr:
+		return isNullsafe(e.Array)
+	case ast.ArrayAppendExpr:
+		return isNullsafe(e.Array)
+	}
+	return false
+}
+
 // parseOperand takes the current token and returns it as the simplest
 // expression for that token. That means an expression with no operators
 // except for the object operator.
@@ -187,61 +191,100 @@ func (p *Parser) parseOperand() (expr ast.Expr) {
 
 	// These cases must come first and not repeat
 	switch p.current.Typ {
-	case token.IgnoreErrorOperator:
-		p.next()
-		return p.parseOperand()
 	case
 		token.UnaryOperator,
 		token.NegationOperator,
 		token.CastOperator,
 		token.SubtractionOperator,
 		token.AmpersandOperator,
-		token.BitwiseNotOperator:
+		token.ReferenceOperator,
+		token.BitwiseNotOperator,
+		token.IgnoreErrorOperator:
 		op := p.current
+		if op.Typ == token.CastOperator {
+			// the spaces allowed inside a cast, as in ( int ), are not significant
+			op.Val = strings.Join(strings.Fields(op.Val), "")
+		}
 		p.next()
-		return p.parseUnaryExpressionRight(p.parseExpression(), op)
+		return p.parseUnaryExpressionRight(p.parseUnaryOperand(op), op)
 	case token.OpenParen:
 		if op := p.checkForCast(); op != nil {
 			p.next()
-			return p.parseUnaryExpressionRight(p.parseExpression(), *op)
+			return p.parseUnaryExpressionRight(p.parseUnaryOperand(*op), *op)
 		}
+		// a parenthesized expression is a single operand of any operation
+		// around it
+		p.next()
+		expr = p.parseExpression()
+		p.expect(token.CloseParen)
+		p.next()
+		return p.parseOperandComponent(expr)
 	case token.Include:
 		return p.parseInclude()
+	case token.Yield, token.YieldFrom:
+		return p.parseYield()
+	case token.Clone:
+		return p.parseClone()
+	case token.Print:
+		// print takes everything up to the end of the expression as its
+		// argument, like an operator of very low precedence
+		return &ast.PrintExpr{Begin: p.current.Begin, Expr: p.parseNextExpression()}
+	case token.Throw:
+		// throw is an expression since PHP 8.0, as in $a ?? throw new E(), and
+		// like print takes everything up to the end of the expression
+		return &ast.ThrowExpr{Begin: p.current.Begin, Expr: p.parseNextExpression()}
 	case token.Function:
 		return p.parseAnonymousFunction()
+	case token.Static:
+		if p.peek().Typ == token.Function {
+			begin := p.current.Begin
+			p.next()
+			f := p.parseAnonymousFunction()
+			f.Begin = begin
+			f.Static = true
+			return f
+		}
 	case token.NewOperator:
 		return p.parseInstantiation()
-	case token.ArrayLookupOperatorLeft:
-		return p.parseArrayDeclaration()
 	}
 
 	switch p.current.Typ {
 	case token.ShellCommand:
-		return &ast.ShellCommand{Command: p.current.Val}
+		return &ast.ShellCommand{Begin: p.current.Begin, Command: p.current.Val}
 	case
-		token.StringLiteral,
+		token.SingleQuotedString,
+		token.DoubleQuotedString,
+		token.Heredoc,
+		token.Nowdoc,
 		token.BooleanLiteral,
 		token.NumberLiteral,
 		token.Null:
-		return p.parseLiteral()
+		expr = p.parseLiteral()
+		p.next()
 	case token.UnaryOperator:
 		expr = p.parseUnaryExpressionLeft(expr, p.current)
 		p.next()
 		return
 
-	case token.Array:
+	case token.Array, token.ShortArrayLeft:
 		expr = p.parseArrayDeclaration()
 		p.next()
 	case token.VariableOperator:
 		expr = p.parseVariableOperand()
-	case token.ObjectOperator:
+	case token.ObjectOperator, token.NullsafeObjectOperator:
 		expr = p.parseObjectLookup(expr)
 		p.next()
-	case token.ArrayLookupOperatorLeft, token.BlockBegin:
+	case token.ArrayLookupOperatorLeft, token.CurlyLookupOperatorLeft:
 		expr = p.parseArrayLookup(expr)
 		p.next()
-	case token.Identifier, token.Exit:
+	case token.Identifier, token.Exit, token.MagicConstant:
 		expr = p.parseIdentifier()
+	case token.Isset, token.Empty, token.Unset:
+		expr = p.parseConstructCall()
+	case token.Class:
+		// the class of Foo::class, which names the class as a string
+		expr = &ast.Identifier{Begin: p.current.Begin, Value: p.current.Val}
+		p.next()
 	case token.Self, token.Static, token.Parent:
 		expr = p.parseScopeResolutionFromKeyword()
 	default:
@@ -252,17 +295,28 @@ func (p *Parser) parseOperand() (expr ast.Expr) {
 	return p.parseOperandComponent(expr)
 }
 
+// parseUnaryOperand parses the operand of the prefix operator op, starting
+// on the operand. The operand includes any operations that bind more tightly
+// than op.
+func (p *Parser) parseUnaryOperand(op token.Item) ast.Expr {
+	level, _, _ := token.UnaryOperator.Precedence()
+	if op.Typ == token.NegationOperator {
+		level, _, _ = op.Typ.Precedence()
+	}
+	return p.parseOperation(p.parenLevel, p.parseOperand(), level)
+}
+
 func (p *Parser) parseOperandComponent(lhs ast.Expr) (expr ast.Expr) {
 	expr = lhs
 	for {
 		switch p.current.Typ {
 		case token.UnaryOperator:
-			expr = p.parseUnaryExpressionRight(expr, p.current)
+			expr = p.parseUnaryExpressionLeft(expr, p.current)
 			return
-		case token.ObjectOperator:
+		case token.ObjectOperator, token.NullsafeObjectOperator:
 			expr = p.parseObjectLookup(expr)
 			p.next()
-		case token.ArrayLookupOperatorLeft, token.BlockBegin:
+		case token.ArrayLookupOperatorLeft, token.CurlyLookupOperatorLeft:
 			expr = p.parseArrayLookup(expr)
 			p.next()
 		case token.OpenParen:
@@ -281,19 +335,19 @@ func (p *Parser) parseOperandComponent(lhs ast.Expr) (expr ast.Expr) {
 
 func (p *Parser) parseLiteral() ast.Expr {
 	switch p.current.Typ {
-	case token.StringLiteral:
-		return &ast.Literal{Type: ast.String, Value: p.current.Val}
+	case token.SingleQuotedString, token.DoubleQuotedString, token.Heredoc, token.Nowdoc:
+		return &ast.Literal{Begin: p.current.Begin, Type: ast.String, Value: p.current.Val}
 	case token.BooleanLiteral:
-		return &ast.Literal{Type: ast.Boolean, Value: p.current.Val}
+		return &ast.Literal{Begin: p.current.Begin, Type: ast.Boolean, Value: p.current.Val}
 	case token.NumberLiteral:
-		return &ast.Literal{Type: ast.Float, Value: p.current.Val}
+		return &ast.Literal{Begin: p.current.Begin, Type: ast.Float, Value: p.current.Val}
 	case token.Null:
 		if p.peek().Typ == token.OpenParen {
 			expr := p.parseIdentifier()
 			p.backup()
 			return expr
 		}
-		return &ast.Literal{Type: ast.Null, Value: p.current.Val}
+		return &ast.Literal{Begin: p.current.Begin, Type: ast.Null, Value: p.current.Val}
 	}
 	p.errorf("Unknown literal type")
 	return nil
@@ -302,17 +356,18 @@ func (p *Parser) parseLiteral() ast.Expr {
 func (p *Parser) parseVariable() ast.Expr {
 	var expr *ast.Variable
 	p.expectCurrent(token.VariableOperator)
+	begin := p.current.Begin
 	switch p.next(); {
 	case lexer.IsKeyword(p.current.Typ, p.current.Val):
 		// keywords are all valid variable names
 		fallthrough
-	case p.current.Typ == token.Identifier:
-		expr = ast.NewVariable(p.current.Val)
+	case p.current.Typ == token.Identifier, p.current.Typ == token.This:
+		expr = p.newVariable(begin)
 	case p.current.Typ == token.BlockBegin:
-		expr = &ast.Variable{Name: p.parseNextExpression()}
+		expr = &ast.Variable{Begin: begin, Name: p.parseNextExpression()}
 		p.expect(token.BlockEnd)
 	case p.current.Typ == token.VariableOperator:
-		expr = &ast.Variable{Name: p.parseVariable()}
+		expr = &ast.Variable{Begin: begin, Name: p.parseVariable()}
 	default:
 		p.errorf("unexpected variable operand %s", p.current)
 		return nil
@@ -322,8 +377,25 @@ func (p *Parser) parseVariable() ast.Expr {
 	return expr
 }
 
+// parseVariableName parses a variable that is a plain name, as a parameter
+// of a function is, starting on its $.
+func (p *Parser) parseVariableName() *ast.Variable {
+	begin := p.current.Begin
+	p.next()
+	return p.newVariable(begin)
+}
+
+// newVariable returns the variable named by the current token, whose first
+// token begins at begin.
+func (p *Parser) newVariable(begin token.Position) *ast.Variable {
+	v := ast.NewVariable(p.current.Val)
+	v.Begin = begin
+	v.Name.(*ast.Identifier).Begin = p.current.Begin
+	return v
+}
+
 func (p *Parser) parseInclude() ast.Expr {
-	inc := ast.Include{Expressions: make([]ast.Expr, 0)}
+	inc := ast.Include{Begin: p.current.Begin, Keyword: p.current.Val, Expressions: make([]ast.Expr, 0)}
 	for {
 		inc.Expressions = append(inc.Expressions, p.parseNextExpression())
 		if p.peek().Typ != token.Comma {
@@ -334,14 +406,69 @@ func (p *Parser) parseInclude() ast.Expr {
 	return inc
 }
 
-func (p *Parser) parseIgnoreError() ast.Expr {
+func (p *Parser) parseYield() ast.Expr {
+	y := &ast.YieldExpr{Begin: p.current.Begin, From: p.current.Typ == token.YieldFrom}
+	switch p.peek().Typ {
+	case token.StatementEnd, token.PHPEnd, token.CloseParen, token.Comma, token.ArrayLookupOperatorRight, token.ShortArrayRight:
+		// a bare yield produces null
+		if y.From {
+			p.errorf("yield from requires an expression")
+		}
+		return y
+	}
+	y.Value = p.parseNextExpression()
+	if !y.From && p.accept(token.ArrayKeyOperator) {
+		y.Key = y.Value
+		y.Value = p.parseNextExpression()
+	}
+	return y
+}
+
+func (p *Parser) parseClone() ast.Expr {
+	begin := p.current.Begin
 	p.next()
-	return p.parseExpression()
+	if p.current.Typ == token.OpenParen {
+		p.next()
+		expr := p.parseExpression()
+		p.expect(token.CloseParen)
+		return &ast.CloneExpr{Begin: begin, Expr: expr}
+	}
+	operand := p.parseOperand()
+	if operand == nil {
+		p.errorf("expected an object to clone, found %s", p.current)
+		return nil
+	}
+	return &ast.CloneExpr{Begin: begin, Expr: operand}
+}
+
+// parseConstructCall parses isset, empty, or unset. They are written like
+// function calls, but isset and unset only accept variables, and empty
+// accepts exactly one expression.
+func (p *Parser) parseConstructCall() ast.Expr {
+	construct := p.current
+	call := p.parseFunctionCall(&ast.Identifier{Begin: construct.Begin, Value: construct.Val})
+	switch construct.Typ {
+	case token.Empty:
+		if len(call.Arguments) != 1 {
+			p.errorf("%s takes exactly one argument", construct.Val)
+		}
+	default:
+		if len(call.Arguments) == 0 {
+			p.errorf("%s requires at least one variable", construct.Val)
+		}
+		for _, arg := range call.Arguments {
+			if _, ok := arg.(ast.Assignable); !ok {
+				p.errorf("%s only accepts variables, found %s", construct.Val, arg)
+			}
+		}
+	}
+	p.next()
+	return call
 }
 
 func (p *Parser) parseNew(originalParenLev int) ast.Expr {
 	expr := p.parseInstantiation()
-	expr = p.parseOperation(originalParenLev, expr)
+	expr = p.parseOperation(originalParenLev, expr, 0)
 	return expr
 }
 
@@ -350,20 +477,27 @@ func (p *Parser) parseIdentifier() (expr ast.Expr) {
 	case typ == token.OpenParen && !p.instantiation:
 		// Function calls are okay here because we know they came with
 		// a non-dynamic identifier.
-		expr = p.parseFunctionCall(&ast.Identifier{Value: p.current.Val})
+		name := p.current.Val
+		call := p.parseFunctionCall(&ast.Identifier{Begin: p.current.Begin, Value: name})
+		if strings.EqualFold(name, "match") && p.peek().Typ == token.BlockBegin && len(call.Arguments) == 1 {
+			// the call is the head of a match expression
+			expr = p.parseMatch(call.Arguments[0])
+		} else {
+			expr = call
+		}
 		p.next()
 	case typ == token.ScopeResolutionOperator:
-		classIdent := p.current.Val
+		classIdent := p.current
 		p.next() // get onto ::, then we get to the next expr
 		p.next()
-		expr = ast.NewClassExpression(classIdent, p.parseOperand())
+		expr = p.newClassExpression(classIdent, p.parseOperand())
 		p.next()
 	case p.instantiation:
 		defer p.next()
-		return &ast.Identifier{Value: p.current.Val}
+		return &ast.Identifier{Begin: p.current.Begin, Value: p.current.Val}
 	default:
 		name := p.current.Val
-		v := ast.NewVariable(p.current.Val)
+		v := p.newVariable(p.current.Begin)
 		expr = ast.ConstantExpr{
 			Variable: v,
 		}
@@ -373,34 +507,90 @@ func (p *Parser) parseIdentifier() (expr ast.Expr) {
 	return expr
 }
 
+// parseMatch parses the arms of a match expression on subject, starting on
+// the closing paren of the subject.
+func (p *Parser) parseMatch(subject ast.Expr) *ast.MatchExpr {
+	m := &ast.MatchExpr{Begin: begin(subject), Subject: subject}
+	p.expect(token.BlockBegin)
+	for p.peek().Typ != token.BlockEnd {
+		arm := &ast.MatchArm{Begin: p.peek().Begin}
+		if !p.accept(token.Default) {
+			arm.Conditions = append(arm.Conditions, p.parseNextExpression())
+			for p.accept(token.Comma) && p.peek().Typ != token.ArrayKeyOperator {
+				arm.Conditions = append(arm.Conditions, p.parseNextExpression())
+			}
+		}
+		p.expect(token.ArrayKeyOperator)
+		arm.Body = p.parseNextExpression()
+		m.Arms = append(m.Arms, arm)
+		if !p.accept(token.Comma) {
+			break
+		}
+	}
+	p.expect(token.BlockEnd)
+	return m
+}
+
 // parseScopeResolutionFromKeyword specifically parses self::, static::, and parent::
 func (p *Parser) parseScopeResolutionFromKeyword() ast.Expr {
 	if p.peek().Typ == token.ScopeResolutionOperator {
-		r := p.current.Val
+		r := p.current
 		p.expect(token.ScopeResolutionOperator)
 		p.next()
-		expr := ast.NewClassExpression(r, p.parseOperand())
+		expr := p.newClassExpression(r, p.parseOperand())
 		p.next()
 		return expr
 	}
-	// TODO Error
+	if p.instantiation {
+		// new self, new static or new parent
+		defer p.next()
+		return &ast.Identifier{Begin: p.current.Begin, Value: p.current.Val}
+	}
+	p.errorf("Found %s, expected ::", p.peek())
 	p.next()
 	return nil
 }
 
+// newClassExpression returns the lookup of e on the class named by the
+// receiver token r, as in Foo::bar().
+func (p *Parser) newClassExpression(r token.Item, e ast.Expr) *ast.ClassExpr {
+	expr := ast.NewClassExpression(r.Val, e)
+	expr.Begin = r.Begin
+	expr.Receiver.(*ast.Identifier).Begin = r.Begin
+	return expr
+}
+
+// begin returns the position of the first token of e, or the zero position
+// if e does not record one.
+func begin(e ast.Expr) token.Position {
+	if n, ok := e.(ast.Positioner); ok {
+		return n.Position()
+	}
+	return token.Position{}
+}
+
 func (p *Parser) parseVariableOperand() ast.Expr {
 	expr := p.parseVariable()
 	p.next()
 	// Array lookup with curly braces is a special case that is only supported by PHP in
 	// simple contexts.
 	switch p.current.Typ {
-	case token.BlockBegin:
+	case token.CurlyLookupOperatorLeft:
 		expr = p.parseArrayLookup(expr)
 		p.next()
 	case token.ScopeResolutionOperator:
-		expr = &ast.ClassExpr{Receiver: expr, Expr: p.parseNextExpression()}
+		if p.peek().Typ == token.Class {
+			p.next()
+			expr = &ast.ClassExpr{Begin: begin(expr), Receiver: expr, Expr: p.parseOperand()}
+		} else {
+			expr = &ast.ClassExpr{Begin: begin(expr), Receiver: expr, Expr: p.parseNextExpression()}
+		}
 		p.next()
 	case token.OpenParen:
+		if p.instantiation {
+			// the arguments of new $class(), left for parseInstantiation
+			break
+		}
 		p.backup()
 		expr = p.parseFunctionCall(expr)
 		p.next()
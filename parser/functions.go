@@ -19,11 +19,8 @@ func (p *Parser) parseFunctionStmt(inMethod bool) *ast.FunctionStmt {
 }
 
 func (p *Parser) parseFunctionDefinition() *ast.FunctionDefinition {
-	def := &ast.FunctionDefinition{}
-	if p.peek().Typ == token.AmpersandOperator {
-		// This is a function returning a reference ... ignore this for now
-		p.next()
-	}
+	def := &ast.FunctionDefinition{Begin: p.current.Begin}
+	def.ByRef = p.accept(token.ReferenceOperator)
 	if !p.accept(token.Identifier) {
 		p.next()
 		if !lexer.IsKeyword(p.current.Typ, p.current.Val) {
@@ -35,6 +32,7 @@ func (p *Parser) parseFunctionDefinition() *ast.FunctionDefinition {
 	p.expect(token.OpenParen)
 	if p.peek().Typ == token.CloseParen {
 		p.expect(token.CloseParen)
+		def.ReturnType = p.parseReturnType()
 		return def
 	}
 	def.Arguments = append(def.Arguments, p.parseFunctionArgument())
@@ -42,9 +40,14 @@ func (p *Parser) parseFunctionDefinition() *ast.FunctionDefinition {
 		switch p.peek().Typ {
 		case token.Comma:
 			p.expect(token.Comma)
+			if p.peek().Typ == token.CloseParen {
+				// a trailing comma
+				continue
+			}
 			def.Arguments = append(def.Arguments, p.parseFunctionArgument())
 		case token.CloseParen:
 			p.expect(token.CloseParen)
+			def.ReturnType = p.parseReturnType()
 			return def
 		default:
 			p.errorf("unexpected argument separator: %s", p.current)
@@ -53,19 +56,24 @@ func (p *Parser) parseFunctionDefinition() *ast.FunctionDefinition {
 	}
 }
 
-func (p *Parser) parseFunctionArgument() *ast.FunctionArgument {
-	arg := &ast.FunctionArgument{}
-	switch p.peek().Typ {
-	case token.Identifier, token.Array, token.Self:
-		p.next()
-		arg.TypeHint = p.current.Val
-	}
-	if p.peek().Typ == token.AmpersandOperator {
-		p.next()
+// parseReturnType parses the optional return type that follows a parameter
+// list, e.g. ": ?int". The lexer emits the type as a single TypeHint.
+func (p *Parser) parseReturnType() string {
+	if !p.accept(token.TernaryOperator2) {
+		return ""
 	}
+	p.expect(token.TypeHint)
+	return p.current.Val
+}
+
+func (p *Parser) parseFunctionArgument() *ast.FunctionArgument {
+	arg := &ast.FunctionArgument{Begin: p.peek().Begin}
+	arg.Promoted, arg.Visibility, arg.Readonly = p.parsePromotion()
+	arg.TypeHint = p.parseTypeHint()
+	arg.ByRef = p.accept(token.ReferenceOperator)
+	arg.Variadic = p.accept(token.Ellipsis)
 	p.expect(token.VariableOperator)
-	p.next()
-	arg.Variable = ast.NewVariable(p.current.Val)
+	arg.Variable = p.parseVariableName()
 	if p.peek().Typ == token.AssignmentOperator {
 		p.expect(token.AssignmentOperator)
 		p.next()
@@ -74,8 +82,30 @@ func (p *Parser) parseFunctionArgument() *ast.FunctionArgument {
 	return arg
 }
 
+// parsePromotion parses the modifiers of a promoted constructor parameter. An
+// unmodified parameter is not promoted, and a promoted parameter without a
+// visibility is public.
+func (p *Parser) parsePromotion() (promoted bool, vis ast.Visibility, readonly bool) {
+	var foundVis bool
+	for {
+		switch p.peek().Typ {
+		case token.Private, token.Public, token.Protected:
+			vis, foundVis = p.parseVisibility()
+		case token.Readonly:
+			readonly = true
+			p.next()
+		default:
+			if promoted && !foundVis {
+				vis = ast.Public
+			}
+			return promoted, vis, readonly
+		}
+		promoted = true
+	}
+}
+
 func (p *Parser) parseFunctionCall(callable ast.Expr) *ast.FunctionCallExpr {
-	expr := &ast.FunctionCallExpr{}
+	expr := &ast.FunctionCallExpr{Begin: begin(callable)}
 	expr.FunctionName = callable
 	return p.parseFunctionArguments(expr)
 }
@@ -87,10 +117,19 @@ func (p *Parser) parseFunctionArguments(expr *ast.FunctionCallExpr) *ast.Functio
 		p.expect(token.CloseParen)
 		return expr
 	}
-	expr.Arguments = append(expr.Arguments, p.parseNextExpression())
+	if p.accept(token.Ellipsis) && p.accept(token.CloseParen) {
+		expr.FirstClassCallable = true
+		return expr
+	}
+	expr.Arguments = append(expr.Arguments, p.parseArgument())
 	for p.peek().Typ != token.CloseParen {
 		p.expect(token.Comma)
-		arg := p.parseNextExpression()
+		if p.peek().Typ == token.CloseParen {
+			// a trailing comma
+			break
+		}
+		p.accept(token.Ellipsis)
+		arg := p.parseArgument()
 		if arg == nil {
 			break
 		}
@@ -98,13 +137,30 @@ func (p *Parser) parseFunctionArguments(expr *ast.FunctionCallExpr) *ast.Functio
 	}
 	p.expect(token.CloseParen)
 	return expr
+}
 
+// parseArgument parses an argument of a call, starting before it or on the
+// ... unpacking it, as in f(...$args). An argument may be named by the
+// parameter it is passed to, as in f(name: $value).
+func (p *Parser) parseArgument() ast.Expr {
+	if p.current.Typ == token.Ellipsis {
+		op := p.current
+		return p.parseUnaryExpressionRight(p.parseNextExpression(), op)
+	}
+	if p.accept(token.ArgumentName) {
+		arg := &ast.NamedArgument{Begin: p.current.Begin, Name: p.current.Val}
+		p.expect(token.TernaryOperator2)
+		arg.Value = p.parseNextExpression()
+		return arg
+	}
+	return p.parseNextExpression()
 }
 
-func (p *Parser) parseAnonymousFunction() ast.Expr {
-	f := &ast.AnonymousFunction{}
+func (p *Parser) parseAnonymousFunction() *ast.AnonymousFunction {
+	f := &ast.AnonymousFunction{Begin: p.current.Begin}
 	f.Arguments = make([]*ast.FunctionArgument, 0)
 	f.ClosureVariables = make([]*ast.FunctionArgument, 0)
+	f.ByRef = p.accept(token.ReferenceOperator)
 	p.expect(token.OpenParen)
 	if p.peek().Typ != token.CloseParen {
 		f.Arguments = append(f.Arguments, p.parseFunctionArgument())
@@ -115,6 +171,10 @@ Loop:
 		switch p.peek().Typ {
 		case token.Comma:
 			p.expect(token.Comma)
+			if p.peek().Typ == token.CloseParen {
+				// a trailing comma
+				continue
+			}
 			f.Arguments = append(f.Arguments, p.parseFunctionArgument())
 		case token.CloseParen:
 			break Loop
@@ -135,6 +195,10 @@ Loop:
 			switch p.peek().Typ {
 			case token.Comma:
 				p.expect(token.Comma)
+				if p.peek().Typ == token.CloseParen {
+					// a trailing comma
+					continue
+				}
 				f.ClosureVariables = append(f.ClosureVariables, p.parseFunctionArgument())
 			case token.CloseParen:
 				break ClosureLoop
@@ -145,6 +209,7 @@ Loop:
 		}
 		p.expect(token.CloseParen)
 	}
+	f.ReturnType = p.parseReturnType()
 
 	p.scope = ast.NewScope(p.scope, p.FileSet.GlobalScope, p.FileSet.SuperGlobalScope)
 	f.Body = p.parseBlock()
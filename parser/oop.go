@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/stephens2424/php/ast"
 	"github.com/stephens2424/php/lexer"
 	"github.com/stephens2424/php/token"
@@ -8,34 +10,70 @@ import (
 
 func (p *Parser) parseInstantiation() ast.Expr {
 	p.expectCurrent(token.NewOperator)
+	expr := &ast.NewCallExpr{Begin: p.current.Begin}
 	p.next()
 
-	p.instantiation = true
-	expr := &ast.NewCallExpr{}
-	expr.Class = p.parseOperand()
-	p.instantiation = false
+	if p.current.Typ == token.Class {
+		return p.parseAnonymousClass(expr)
+	}
+
+	if p.current.Typ == token.OpenParen {
+		// a class named by an arbitrary expression, as in new (getClass())
+		p.next()
+		expr.Class = p.parseExpression()
+		p.expect(token.CloseParen)
+	} else {
+		p.instantiation = true
+		expr.Class = p.parseOperand()
+		p.instantiation = false
+	}
+
+	p.parseInstantiationArguments(expr)
+	return expr
+}
 
+// parseInstantiationArguments parses the optional constructor arguments
+// following the class of a new expression.
+func (p *Parser) parseInstantiationArguments(expr *ast.NewCallExpr) {
 	if p.peek().Typ == token.OpenParen {
 		p.expect(token.OpenParen)
+		expr.Arguments = make([]ast.Expr, 0)
 		if p.peek().Typ != token.CloseParen {
-			expr.Arguments = append(expr.Arguments, p.parseNextExpression())
+			p.accept(token.Ellipsis)
+			expr.Arguments = append(expr.Arguments, p.parseArgument())
 			for p.peek().Typ == token.Comma {
 				p.expect(token.Comma)
-				expr.Arguments = append(expr.Arguments, p.parseNextExpression())
+				if p.peek().Typ == token.CloseParen {
+					// a trailing comma
+					break
+				}
+				p.accept(token.Ellipsis)
+				expr.Arguments = append(expr.Arguments, p.parseArgument())
 			}
 		}
 		p.expect(token.CloseParen)
 	}
+}
+
+// parseAnonymousClass parses the class of new class(...) extends A { ... },
+// starting on the class keyword.
+func (p *Parser) parseAnonymousClass(expr *ast.NewCallExpr) ast.Expr {
+	c := &ast.Class{Begin: p.current.Begin}
+	p.parseInstantiationArguments(expr)
+	p.parseClassHeritage(c)
+	p.expect(token.BlockBegin)
+	expr.Class = &ast.AnonymousClass{Class: p.parseClassFields(c)}
 	return expr
 }
 
 func (p *Parser) parseClass() *ast.Class {
-	if p.current.Typ == token.Abstract {
-		p.expect(token.Class)
-	}
-	if p.current.Typ == token.Final {
-		p.expect(token.Class)
+	begin := p.current.Begin
+	var final, readonly bool
+	for ; p.current.Typ == token.Abstract || p.current.Typ == token.Final || p.current.Typ == token.Readonly; p.next() {
+		final = final || p.current.Typ == token.Final
+		readonly = readonly || p.current.Typ == token.Readonly
 	}
+	p.expectCurrent(token.Class)
 	switch p.next(); {
 	case p.current.Typ == token.Identifier:
 	case lexer.IsKeyword(p.current.Typ, p.current.Val):
@@ -43,29 +81,38 @@ func (p *Parser) parseClass() *ast.Class {
 		p.errorf("unexpected variable operand %s", p.current)
 	}
 
-	name := p.current.Val
-	if p.peek().Typ == token.Extends {
-		p.expect(token.Extends)
+	c := &ast.Class{Begin: begin, Name: p.current.Val, Final: final, Readonly: readonly}
+	p.parseClassHeritage(c)
+	p.expect(token.BlockBegin)
+	c = p.parseClassFields(c)
+	p.namespace.ClassesAndInterfaces[c.Name] = c
+	return c
+}
+
+// parseClassHeritage parses the optional extends and implements clauses of a
+// class declaration.
+func (p *Parser) parseClassHeritage(c *ast.Class) {
+	if p.accept(token.Extends) {
 		p.expect(token.Identifier)
+		c.Extends = p.current.Val
 	}
-	if p.peek().Typ == token.Implements {
-		p.expect(token.Implements)
-		p.expect(token.Identifier)
-		for p.peek().Typ == token.Comma {
-			p.expect(token.Comma)
+	if p.accept(token.Implements) {
+		for {
 			p.expect(token.Identifier)
+			c.Implements = append(c.Implements, p.current.Val)
+			if !p.accept(token.Comma) {
+				break
+			}
 		}
 	}
-	p.expect(token.BlockBegin)
-	c := p.parseClassFields(&ast.Class{Name: name})
-	p.namespace.ClassesAndInterfaces[c.Name] = c
-	return c
 }
 
 func (p *Parser) parseObjectLookup(r ast.Expr) (expr ast.Expr) {
-	p.expectCurrent(token.ObjectOperator)
+	p.expectCurrent(token.ObjectOperator, token.NullsafeObjectOperator)
 	prop := &ast.PropertyCallExpr{
+		Begin:    begin(r),
 		Receiver: r,
+		Nullsafe: p.current.Typ == token.NullsafeObjectOperator,
 	}
 	switch p.next(); p.current.Typ {
 	case token.BlockBegin:
@@ -74,17 +121,24 @@ func (p *Parser) parseObjectLookup(r ast.Expr) (expr ast.Expr) {
 	case token.VariableOperator:
 		prop.Name = p.parseExpression()
 	case token.Identifier:
-		prop.Name = &ast.Identifier{Value: p.current.Val}
+		prop.Name = &ast.Identifier{Begin: p.current.Begin, Value: p.current.Val}
+	default:
+		// keywords are all valid property and method names
+		if lexer.IsKeyword(p.current.Typ, p.current.Val) {
+			prop.Name = &ast.Identifier{Begin: p.current.Begin, Value: p.current.Val}
+		}
 	}
 	expr = prop
 	switch pk := p.peek(); pk.Typ {
 	case token.OpenParen:
+		call := p.parseFunctionCall(prop.Name)
+		call.Begin = prop.Begin
 		expr = &ast.MethodCallExpr{
 			Receiver:         r,
-			FunctionCallExpr: p.parseFunctionCall(prop.Name),
+			Nullsafe:         prop.Nullsafe,
+			FunctionCallExpr: call,
 		}
 	}
-	expr = p.parseOperation(p.parenLevel, expr)
 	return
 }
 
@@ -103,6 +157,18 @@ func (p *Parser) parseVisibility() (vis ast.Visibility, found bool) {
 	return vis, true
 }
 
+// parseTypeHint parses an optional type declaration such as the one preceding
+// a parameter or property. Nullable and compound types are lexed as a single
+// TypeHint. It returns an empty string if there is none.
+func (p *Parser) parseTypeHint() string {
+	switch p.peek().Typ {
+	case token.Identifier, token.Array, token.Self, token.Parent, token.TypeHint:
+		p.next()
+		return p.current.Val
+	}
+	return ""
+}
+
 func (p *Parser) parseAbstract() bool {
 	if p.peek().Typ == token.Abstract {
 		p.next()
@@ -116,18 +182,24 @@ func (p *Parser) parseClassFields(c *ast.Class) *ast.Class {
 	c.Methods = make([]*ast.Method, 0)
 	c.Properties = make([]*ast.Property, 0)
 	for p.peek().Typ != token.BlockEnd {
-		vis, _, _, abstract := p.parseClassMemberSettings()
+		vis, static, final, abstract, readonly := p.parseClassMemberSettings()
+		typeHint := p.parseTypeHint()
 		p.next()
 		switch p.current.Typ {
+		case token.Use:
+			p.parseTraitUse(c)
 		case token.Function:
-			p.parseClassMethod(c, abstract, vis)
+			p.parseClassMethod(c, vis, static, abstract, final)
 		case token.Var:
 			p.expect(token.VariableOperator)
 			fallthrough
 		case token.VariableOperator:
-			p.parseClassVariables(c, vis)
+			p.parseClassVariables(c, vis, static, readonly || c.Readonly, typeHint)
 		case token.Const:
-			p.parseClassConst(c)
+			for _, constant := range p.parseConstants() {
+				constant.Visibility = vis
+				c.Constants = append(c.Constants, constant)
+			}
 		default:
 			p.errorf("unexpected class member %v", p.current)
 			return c
@@ -137,23 +209,38 @@ func (p *Parser) parseClassFields(c *ast.Class) *ast.Class {
 	return c
 }
 
-func (p *Parser) parseClassConst(c *ast.Class) {
-	constant := &ast.Constant{}
-	p.expect(token.Identifier)
-	constant.Name = p.current.Val
-	if p.peek().Typ == token.AssignmentOperator {
-		p.expect(token.AssignmentOperator)
-		constant.Value = p.parseNextExpression()
+// parseConstants parses the comma-separated constants following the const
+// keyword, each with an optional type, through the end of the statement.
+func (p *Parser) parseConstants() []*ast.Constant {
+	var constants []*ast.Constant
+	typeHint := ""
+	if p.accept(token.TypeHint) {
+		typeHint = p.current.Val
+	}
+	for {
+		constant := &ast.Constant{Visibility: ast.Public, TypeHint: typeHint}
+		p.expect(token.Identifier)
+		constant.Name = p.current.Val
+		if p.peek().Typ == token.AssignmentOperator {
+			p.expect(token.AssignmentOperator)
+			constant.Value = p.parseNextExpression()
+		}
+		constants = append(constants, constant)
+		if p.accept(token.StatementEnd) {
+			return constants
+		}
+		p.expect(token.Comma)
 	}
-	c.Constants = append(c.Constants, constant)
-	p.expect(token.StatementEnd)
 }
 
-func (p *Parser) parseClassVariables(c *ast.Class, vis ast.Visibility) {
+func (p *Parser) parseClassVariables(c *ast.Class, vis ast.Visibility, static, readonly bool, typeHint string) {
 	for {
 		p.expect(token.Identifier)
 		prop := &ast.Property{
 			Visibility: vis,
+			Static:     static,
+			Readonly:   readonly,
+			TypeHint:   typeHint,
 			Name:       "$" + p.current.Val,
 		}
 		if p.peek().Typ == token.AssignmentOperator {
@@ -169,25 +256,82 @@ func (p *Parser) parseClassVariables(c *ast.Class, vis ast.Visibility) {
 	}
 }
 
-func (p *Parser) parseClassMethod(c *ast.Class, abstract bool, vis ast.Visibility) {
+func (p *Parser) parseClassMethod(c *ast.Class, vis ast.Visibility, static, abstract, final bool) {
 	if abstract {
+		if final {
+			p.errorf("abstract method %s cannot be final", p.peek().Val)
+		}
 		f := p.parseFunctionDefinition()
 		m := &ast.Method{
 			Visibility:   vis,
+			Static:       static,
+			Abstract:     true,
 			FunctionStmt: &ast.FunctionStmt{FunctionDefinition: f},
 		}
 		c.Methods = append(c.Methods, m)
 		p.expect(token.StatementEnd)
 	} else {
-		c.Methods = append(c.Methods, &ast.Method{
+		m := &ast.Method{
 			Visibility:   vis,
+			Static:       static,
+			Final:        final,
 			FunctionStmt: p.parseFunctionStmt(true),
+		}
+		c.Methods = append(c.Methods, m)
+		if strings.EqualFold(m.Name, "__construct") {
+			p.promoteArguments(c, m.Arguments)
+		}
+	}
+}
+
+// promoteArguments declares a property for each promoted constructor
+// parameter.
+func (p *Parser) promoteArguments(c *ast.Class, args []*ast.FunctionArgument) {
+	for _, arg := range args {
+		if !arg.Promoted {
+			continue
+		}
+		if arg.Variadic {
+			p.errorf("variadic parameter %s cannot be promoted", arg.Variable)
+		}
+		c.Properties = append(c.Properties, &ast.Property{
+			Name:       arg.Variable.String(),
+			Visibility: arg.Visibility,
+			Readonly:   arg.Readonly || c.Readonly,
+			TypeHint:   arg.TypeHint,
 		})
 	}
 }
 
+func (p *Parser) parseTrait() *ast.Trait {
+	begin := p.current.Begin
+	p.expect(token.Identifier)
+	name := p.current.Val
+	p.expect(token.BlockBegin)
+	t := &ast.Trait{Class: p.parseClassFields(&ast.Class{Begin: begin, Name: name})}
+	p.namespace.ClassesAndInterfaces[t.Name] = t
+	return t
+}
+
+// parseTraitUse parses the list of traits in a use statement within a class
+// body.
+func (p *Parser) parseTraitUse(c *ast.Class) {
+	for {
+		p.expect(token.Identifier)
+		c.Traits = append(c.Traits, p.current.Val)
+		if !p.accept(token.Comma) {
+			break
+		}
+	}
+	if p.peek().Typ == token.BlockBegin {
+		p.errorf("trait adaptations are not supported")
+	}
+	p.expect(token.StatementEnd)
+}
+
 func (p *Parser) parseInterface() *ast.Interface {
 	i := &ast.Interface{
+		Begin:    p.current.Begin,
 		Inherits: make([]string, 0),
 	}
 	p.expect(token.Identifier)
@@ -206,10 +350,7 @@ func (p *Parser) parseInterface() *ast.Interface {
 	}
 	p.expect(token.BlockBegin)
 	for p.peek().Typ != token.BlockEnd {
-		vis, _ := p.parseVisibility()
-		if p.peek().Typ == token.Static {
-			p.next()
-		}
+		vis, _, _, _, _ := p.parseClassMemberSettings()
 		p.next()
 		switch p.current.Typ {
 		case token.Function:
@@ -221,15 +362,10 @@ func (p *Parser) parseInterface() *ast.Interface {
 			i.Methods = append(i.Methods, m)
 			p.expect(token.StatementEnd)
 		case token.Const:
-			constant := ast.Constant{}
-			p.expect(token.Identifier)
-			constant.Name = p.current.Val
-			if p.peek().Typ == token.AssignmentOperator {
-				p.expect(token.AssignmentOperator)
-				constant.Value = p.parseNextExpression()
+			for _, constant := range p.parseConstants() {
+				constant.Visibility = vis
+				i.Constants = append(i.Constants, *constant)
 			}
-			i.Constants = append(i.Constants, constant)
-			p.expect(token.StatementEnd)
 		default:
 			p.errorf("unexpected interface member %v", p.current)
 		}
@@ -238,7 +374,7 @@ func (p *Parser) parseInterface() *ast.Interface {
 	return i
 }
 
-func (p *Parser) parseClassMemberSettings() (vis ast.Visibility, static, final, abstract bool) {
+func (p *Parser) parseClassMemberSettings() (vis ast.Visibility, static, final, abstract, readonly bool) {
 	var foundVis bool
 	vis = ast.Public
 	for {
@@ -266,6 +402,12 @@ func (p *Parser) parseClassMemberSettings() (vis ast.Visibility, static, final,
 			}
 			static = true
 			p.next()
+		case token.Readonly:
+			if readonly {
+				p.errorf("found multiple readonly declarations")
+			}
+			readonly = true
+			p.next()
 		default:
 			return
 		}
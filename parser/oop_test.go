@@ -36,8 +36,9 @@ func TestClass(t *testing.T) {
 		Name: "TestClass",
 		Constants: []*ast.Constant{
 			{
-				Name:  "my_const",
-				Value: &ast.Literal{Type: ast.String, Value: `"test"`},
+				Name:       "my_const",
+				Value:      &ast.Literal{Type: ast.String, Value: `"test"`},
+				Visibility: ast.Public,
 			},
 		},
 		Properties: []*ast.Property{
@@ -63,6 +64,7 @@ func TestClass(t *testing.T) {
 		Methods: []*ast.Method{
 			{
 				Visibility: ast.Public,
+				Abstract:   true,
 				FunctionStmt: &ast.FunctionStmt{
 					FunctionDefinition: &ast.FunctionDefinition{
 						Name: "method0",
@@ -164,3 +166,585 @@ func TestInstantiation(t *testing.T) {
 		t.Fatalf("Instantiation did not parse correctly")
 	}
 }
+
+func TestDynamicInstantiation(t *testing.T) {
+	tests := map[string]*ast.NewCallExpr{
+		`new $class(1);`: {
+			Class:     ast.NewVariable("class"),
+			Arguments: []ast.Expr{&ast.Literal{Type: ast.Float, Value: "1"}},
+		},
+		`new $class;`: {
+			Class: ast.NewVariable("class"),
+		},
+		`new (getClass())(1);`: {
+			Class:     &ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: "getClass"}, Arguments: []ast.Expr{}},
+			Arguments: []ast.Expr{&ast.Literal{Type: ast.Float, Value: "1"}},
+		},
+		`new ($prefix . "Foo");`: {
+			Class: ast.BinaryExpr{
+				Antecedent: ast.NewVariable("prefix"),
+				Subsequent: &ast.Literal{Type: ast.String, Value: `"Foo"`},
+				Operator:   ".",
+				Type:       ast.String,
+			},
+		},
+	}
+	for src, tree := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php "+src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if len(a.Nodes) != 1 || !assertEquals(a.Nodes[0], ast.ExprStmt{tree}) {
+			t.Errorf("%s did not correctly parse", src)
+		}
+	}
+}
+
+func TestClassNameConstant(t *testing.T) {
+	class := &ast.Identifier{Value: "class"}
+	tests := map[string]ast.Expr{
+		`Foo::class;`:  &ast.ClassExpr{Receiver: &ast.Identifier{Value: "Foo"}, Expr: class},
+		`self::class;`: &ast.ClassExpr{Receiver: &ast.Identifier{Value: "self"}, Expr: class},
+		`$obj::class;`: &ast.ClassExpr{Receiver: ast.NewVariable("obj"), Expr: class},
+		`new (static::class)();`: &ast.NewCallExpr{
+			Class:     &ast.ClassExpr{Receiver: &ast.Identifier{Value: "static"}, Expr: class},
+			Arguments: []ast.Expr{},
+		},
+	}
+	for src, tree := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php "+src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if len(a.Nodes) != 1 || !assertEquals(a.Nodes[0], ast.ExprStmt{tree}) {
+			t.Errorf("%s did not correctly parse", src)
+		}
+	}
+}
+
+func TestStaticTypedProperty(t *testing.T) {
+	testStr := `<?php
+    class TestClass {
+      public static ?int $instances = 0;
+      static public int $x;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.Class{
+		Name:    "TestClass",
+		Methods: []*ast.Method{},
+		Properties: []*ast.Property{
+			{
+				Visibility:     ast.Public,
+				Static:         true,
+				TypeHint:       "?int",
+				Name:           "$instances",
+				Initialization: &ast.Literal{Type: ast.Float, Value: "0"},
+			},
+			{
+				Visibility: ast.Public,
+				Static:     true,
+				TypeHint:   "int",
+				Name:       "$x",
+			},
+		},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Static typed properties did not parse correctly")
+	}
+}
+
+func TestTypedProperties(t *testing.T) {
+	testStr := `<?php
+    class TestClass {
+      private int $x;
+      public ?Foo $f = null;
+      protected int|string $id;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.Class{
+		Name:    "TestClass",
+		Methods: []*ast.Method{},
+		Properties: []*ast.Property{
+			{
+				Visibility: ast.Private,
+				TypeHint:   "int",
+				Name:       "$x",
+			},
+			{
+				Visibility:     ast.Public,
+				TypeHint:       "?Foo",
+				Name:           "$f",
+				Initialization: &ast.Literal{Type: ast.Null, Value: "null"},
+			},
+			{
+				Visibility: ast.Protected,
+				TypeHint:   "int|string",
+				Name:       "$id",
+			},
+		},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Typed properties did not parse correctly")
+	}
+}
+
+func TestTrait(t *testing.T) {
+	testStr := `<?php
+    trait Counter {
+      private static $count = 0;
+      abstract protected function name();
+      public static function increment() {}
+    }
+    class Page {
+      use Counter, Loggable;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.Trait{Class: &ast.Class{
+			Name: "Counter",
+			Methods: []*ast.Method{
+				{
+					Visibility: ast.Protected,
+					Abstract:   true,
+					FunctionStmt: &ast.FunctionStmt{FunctionDefinition: &ast.FunctionDefinition{
+						Name:      "name",
+						Arguments: []*ast.FunctionArgument{},
+					}},
+				},
+				{
+					Visibility: ast.Public,
+					Static:     true,
+					FunctionStmt: &ast.FunctionStmt{
+						FunctionDefinition: &ast.FunctionDefinition{
+							Name:      "increment",
+							Arguments: []*ast.FunctionArgument{},
+						},
+						Body: &ast.Block{},
+					},
+				},
+			},
+			Properties: []*ast.Property{
+				{
+					Visibility:     ast.Private,
+					Static:         true,
+					Name:           "$count",
+					Initialization: &ast.Literal{Type: ast.Float, Value: "0"},
+				},
+			},
+		}},
+		&ast.Class{
+			Name:       "Page",
+			Traits:     []string{"Counter", "Loggable"},
+			Methods:    []*ast.Method{},
+			Properties: []*ast.Property{},
+		},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Trait did not correctly parse")
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	testStr := `<?php
+    const BAR = 2, BAZ = 3;
+    class A {
+      const FOO = 1;
+      protected const int TYPED = 2;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.ConstantStmt{Constants: []*ast.Constant{
+			{Name: "BAR", Value: &ast.Literal{Type: ast.Float, Value: "2"}, Visibility: ast.Public},
+			{Name: "BAZ", Value: &ast.Literal{Type: ast.Float, Value: "3"}, Visibility: ast.Public},
+		}},
+		&ast.Class{
+			Name: "A",
+			Constants: []*ast.Constant{
+				{Name: "FOO", Value: &ast.Literal{Type: ast.Float, Value: "1"}, Visibility: ast.Public},
+				{Name: "TYPED", Value: &ast.Literal{Type: ast.Float, Value: "2"}, Visibility: ast.Protected, TypeHint: "int"},
+			},
+			Methods:    []*ast.Method{},
+			Properties: []*ast.Property{},
+		},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Constants did not correctly parse")
+		}
+	}
+}
+
+func TestFinalAndReadonly(t *testing.T) {
+	testStr := `<?php
+    final class A {
+      final public function f() {}
+      public readonly int $x;
+    }
+    readonly class B {
+      protected string $y;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.Class{
+			Name:  "A",
+			Final: true,
+			Methods: []*ast.Method{
+				{
+					Visibility: ast.Public,
+					Final:      true,
+					FunctionStmt: &ast.FunctionStmt{
+						FunctionDefinition: &ast.FunctionDefinition{
+							Name:      "f",
+							Arguments: []*ast.FunctionArgument{},
+						},
+						Body: &ast.Block{},
+					},
+				},
+			},
+			Properties: []*ast.Property{
+				{Visibility: ast.Public, Readonly: true, TypeHint: "int", Name: "$x"},
+			},
+		},
+		&ast.Class{
+			Name:     "B",
+			Readonly: true,
+			Methods:  []*ast.Method{},
+			Properties: []*ast.Property{
+				{Visibility: ast.Protected, Readonly: true, TypeHint: "string", Name: "$y"},
+			},
+		},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("final and readonly did not correctly parse")
+		}
+	}
+}
+
+func TestConstructorPromotion(t *testing.T) {
+	testStr := `<?php
+    class Point {
+      public function __construct(private int $x, public readonly ?int $y = null, readonly $z = 0, $scale = 1) {}
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := a.Nodes[0].(*ast.Class)
+	if !ok {
+		t.Fatalf("expected a class, found %T", a.Nodes[0])
+	}
+	args := []*ast.FunctionArgument{
+		{Promoted: true, Visibility: ast.Private, TypeHint: "int", Variable: ast.NewVariable("x")},
+		{Promoted: true, Visibility: ast.Public, Readonly: true, TypeHint: "?int", Variable: ast.NewVariable("y"), Default: &ast.Literal{Type: ast.Null, Value: "null"}},
+		{Promoted: true, Visibility: ast.Public, Readonly: true, Variable: ast.NewVariable("z"), Default: &ast.Literal{Type: ast.Float, Value: "0"}},
+		{Variable: ast.NewVariable("scale"), Default: &ast.Literal{Type: ast.Float, Value: "1"}},
+	}
+	for i, arg := range args {
+		if !assertEquals(c.Methods[0].Arguments[i], arg) {
+			t.Fatalf("Promoted constructor argument %d did not correctly parse", i)
+		}
+	}
+	props := []*ast.Property{
+		{Name: "$x", Visibility: ast.Private, TypeHint: "int"},
+		{Name: "$y", Visibility: ast.Public, Readonly: true, TypeHint: "?int"},
+		{Name: "$z", Visibility: ast.Public, Readonly: true},
+	}
+	if len(c.Properties) != len(props) {
+		t.Fatalf("expected %d promoted properties, found %d", len(props), len(c.Properties))
+	}
+	for i, prop := range props {
+		if !assertEquals(c.Properties[i], prop) {
+			t.Fatalf("Promoted property %d was not declared", i)
+		}
+	}
+}
+
+func TestAnonymousClass(t *testing.T) {
+	testStr := `<?php
+    new class {};
+    new class(1, 2) extends Base {};
+    new class implements Countable, I {
+      public function count() {}
+    };`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.NewCallExpr{
+			Class: &ast.AnonymousClass{Class: &ast.Class{
+				Methods:    []*ast.Method{},
+				Properties: []*ast.Property{},
+			}},
+		}},
+		ast.ExprStmt{&ast.NewCallExpr{
+			Class: &ast.AnonymousClass{Class: &ast.Class{
+				Extends:    "Base",
+				Methods:    []*ast.Method{},
+				Properties: []*ast.Property{},
+			}},
+			Arguments: []ast.Expr{
+				&ast.Literal{Type: ast.Float, Value: "1"},
+				&ast.Literal{Type: ast.Float, Value: "2"},
+			},
+		}},
+		ast.ExprStmt{&ast.NewCallExpr{
+			Class: &ast.AnonymousClass{Class: &ast.Class{
+				Implements: []string{"Countable", "I"},
+				Methods: []*ast.Method{
+					{
+						Visibility: ast.Public,
+						FunctionStmt: &ast.FunctionStmt{
+							FunctionDefinition: &ast.FunctionDefinition{
+								Name:      "count",
+								Arguments: []*ast.FunctionArgument{},
+							},
+							Body: &ast.Block{},
+						},
+					},
+				},
+				Properties: []*ast.Property{},
+			}},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Anonymous class did not correctly parse")
+		}
+	}
+}
+
+func TestAbstractMethods(t *testing.T) {
+	testStr := `<?php
+    abstract class Shape {
+      abstract public function area(): float;
+      public function name(): string {}
+    }
+    interface Named {
+      public function name(?string $prefix = null): string;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.Class{
+			Name: "Shape",
+			Methods: []*ast.Method{
+				{
+					Visibility: ast.Public,
+					Abstract:   true,
+					FunctionStmt: &ast.FunctionStmt{FunctionDefinition: &ast.FunctionDefinition{
+						Name:       "area",
+						Arguments:  []*ast.FunctionArgument{},
+						ReturnType: "float",
+					}},
+				},
+				{
+					Visibility: ast.Public,
+					FunctionStmt: &ast.FunctionStmt{
+						FunctionDefinition: &ast.FunctionDefinition{
+							Name:       "name",
+							Arguments:  []*ast.FunctionArgument{},
+							ReturnType: "string",
+						},
+						Body: &ast.Block{},
+					},
+				},
+			},
+			Properties: []*ast.Property{},
+		},
+		&ast.Interface{
+			Name:     "Named",
+			Inherits: []string{},
+			Methods: []ast.Method{
+				{
+					Visibility: ast.Public,
+					FunctionStmt: &ast.FunctionStmt{FunctionDefinition: &ast.FunctionDefinition{
+						Name: "name",
+						Arguments: []*ast.FunctionArgument{
+							{
+								TypeHint: "?string",
+								Variable: ast.NewVariable("prefix"),
+								Default:  &ast.Literal{Type: ast.Null, Value: "null"},
+							},
+						},
+						ReturnType: "string",
+					}},
+				},
+			},
+		},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Abstract methods did not parse correctly")
+		}
+	}
+}
+
+func TestModifierOrder(t *testing.T) {
+	tests := map[string]ast.Method{
+		"public abstract function f();":           {Visibility: ast.Public, Abstract: true},
+		"abstract public function f();":           {Visibility: ast.Public, Abstract: true},
+		"abstract protected static function f();": {Visibility: ast.Protected, Static: true, Abstract: true},
+		"static abstract protected function f();": {Visibility: ast.Protected, Static: true, Abstract: true},
+		"final protected static function f() {}":  {Visibility: ast.Protected, Static: true, Final: true},
+		"static final protected function f() {}":  {Visibility: ast.Protected, Static: true, Final: true},
+		"protected final static function f() {}":  {Visibility: ast.Protected, Static: true, Final: true},
+		"private static function f() {}":          {Visibility: ast.Private, Static: true},
+		"static function f() {}":                  {Visibility: ast.Public, Static: true},
+	}
+	for src, expected := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php abstract class A { "+src+" }")
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		m := a.Nodes[0].(*ast.Class).Methods[0]
+		if m.Visibility != expected.Visibility || m.Static != expected.Static || m.Abstract != expected.Abstract || m.Final != expected.Final {
+			t.Errorf("%s: parsed %+v, expected %+v", src, *m, expected)
+		}
+	}
+
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", "<?php interface I { static public function f(); public static function g(); }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if methods := a.Nodes[0].(*ast.Interface).Methods; len(methods) != 2 {
+		t.Errorf("parsed %d interface methods, expected 2", len(methods))
+	}
+
+	p = NewParser()
+	if _, err := p.Parse("test.php", "<?php abstract class A { final abstract public function f(); }"); err == nil {
+		t.Error("expected an error for an abstract final method")
+	}
+}
+
+func TestMultipleInheritance(t *testing.T) {
+	testStr := `<?php
+    interface Collection extends \Countable, IteratorAggregate {}
+    class Bag extends Base implements Collection, \ArrayAccess, Foo\Bar {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.Interface{
+			Name:     "Collection",
+			Inherits: []string{`\Countable`, "IteratorAggregate"},
+		},
+		&ast.Class{
+			Name:       "Bag",
+			Extends:    "Base",
+			Implements: []string{"Collection", `\ArrayAccess`, `Foo\Bar`},
+			Methods:    []*ast.Method{},
+			Properties: []*ast.Property{},
+		},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Multiple inheritance did not parse correctly")
+		}
+	}
+}
+
+func TestMultipleClassExtends(t *testing.T) {
+	p := NewParser()
+	p.disableScoping = true
+	if _, err := p.Parse("test.php", `<?php class Bag extends A, B {}`); err == nil {
+		t.Fatal("expected an error for a class extending two classes")
+	}
+}
+
+func TestMemberConstants(t *testing.T) {
+	testStr := `<?php
+    interface I {
+      const X = 1;
+      public const int Y = 2, Z = 3;
+    }
+    trait T {
+      protected const X = 1;
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.Interface{
+			Name:     "I",
+			Inherits: []string{},
+			Constants: []ast.Constant{
+				{Name: "X", Value: &ast.Literal{Type: ast.Float, Value: "1"}, Visibility: ast.Public},
+				{Name: "Y", Value: &ast.Literal{Type: ast.Float, Value: "2"}, Visibility: ast.Public, TypeHint: "int"},
+				{Name: "Z", Value: &ast.Literal{Type: ast.Float, Value: "3"}, Visibility: ast.Public, TypeHint: "int"},
+			},
+		},
+		&ast.Trait{Class: &ast.Class{
+			Name: "T",
+			Constants: []*ast.Constant{
+				{Name: "X", Value: &ast.Literal{Type: ast.Float, Value: "1"}, Visibility: ast.Protected},
+			},
+			Methods:    []*ast.Method{},
+			Properties: []*ast.Property{},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Member constants did not parse correctly")
+		}
+	}
+}
@@ -38,12 +38,14 @@ func operationTypeForToken(t token.Token) operationType {
 		token.BitwiseXorOperator,
 		token.BitwiseOrOperator,
 		token.BitwiseShiftOperator,
+		token.CoalesceOperator,
+		token.PipeOperator,
 		token.WrittenAndOperator,
 		token.WrittenXorOperator,
 		token.WrittenOrOperator,
 		token.InstanceofOperator:
 		return binaryOperation
-	case token.TernaryOperator1:
+	case token.TernaryOperator1, token.ElvisOperator:
 		return ternaryOperation
 	case token.AssignmentOperator:
 		return assignmentOperation
@@ -55,19 +57,34 @@ func operationTypeForToken(t token.Token) operationType {
 	return nilOperation
 }
 
+// isOperator reports whether t is an operator that parseOperation applies to
+// the expression before it.
+func isOperator(t token.Token) bool {
+	switch operationTypeForToken(t) {
+	case unaryOperation, binaryOperation, ternaryOperation, assignmentOperation:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) newBinaryOperation(operator token.Item, expr1, expr2 ast.Expr) ast.Expr {
 	var t ast.Type = ast.Numeric
 	switch operator.Typ {
 	case token.AssignmentOperator:
 		return p.parseAssignmentOperation(expr1, expr2, operator)
-	case token.ComparisonOperator, token.AndOperator, token.OrOperator, token.WrittenAndOperator, token.WrittenOrOperator, token.WrittenXorOperator:
+	case token.PipeOperator:
+		return &ast.PipeExpr{Begin: begin(expr1), Value: expr1, Callable: expr2}
+	case token.ComparisonOperator, token.AndOperator, token.OrOperator, token.WrittenAndOperator, token.WrittenOrOperator, token.WrittenXorOperator, token.InstanceofOperator:
 		t = ast.Boolean
 	case token.ConcatenationOperator:
 		t = ast.String
 	case token.AmpersandOperator, token.BitwiseXorOperator, token.BitwiseOrOperator, token.BitwiseShiftOperator:
 		t = ast.Unknown
+	case token.CoalesceOperator:
+		t = expr1.EvaluatesTo().Union(expr2.EvaluatesTo())
 	}
 	return ast.BinaryExpr{
+		Begin:      begin(expr1),
 		Type:       t,
 		Antecedent: expr1,
 		Subsequent: expr2,
@@ -77,29 +94,48 @@ func (p *Parser) newBinaryOperation(operator token.Item, expr1, expr2 ast.Expr)
 
 func (p *Parser) parseBinaryOperation(lhs ast.Expr, operator token.Item, originalParenLevel int) ast.Expr {
 	p.next()
-	rhs := p.parseOperand()
-	currentPrecedence := operatorPrecedence[operator.Typ]
-	for {
-		nextOperator := p.peek()
-		nextPrecedence, ok := operatorPrecedence[nextOperator.Typ]
-		if !ok || nextPrecedence < currentPrecedence {
-			break
-		}
-		rhs = p.parseOperation(originalParenLevel, rhs)
+	var rhs ast.Expr
+	if operator.Typ == token.InstanceofOperator {
+		// the class operand is parsed as the class of new is, so that a name,
+		// self, static or parent is an identifier rather than a constant
+		p.instantiation = true
+		rhs = p.parseOperand()
+		p.instantiation = false
+	} else {
+		rhs = p.parseOperand()
+	}
+	level, rightAssoc, _ := operator.Typ.Precedence()
+	if operator.Typ == token.AssignmentOperator {
+		// an assignment binds tightly to the variable on its left, but its
+		// value extends over every operator but and, or and xor
+		level, _, _ = token.TernaryOperator1.Precedence()
 	}
+	if rightAssoc {
+		// the operand takes the operators of the same level that follow it
+		level--
+	}
+	rhs = p.parseOperation(originalParenLevel, rhs, level)
 	return p.newBinaryOperation(operator, lhs, rhs)
 }
 
 func (p *Parser) parseTernaryOperation(lhs ast.Expr) ast.Expr {
-	var truthy ast.Expr
-	if p.peek().Typ == token.TernaryOperator2 {
-		truthy = lhs
-	} else {
+	// a short ternary, as in $a ?: $b, uses its condition as the true value
+	truthy := lhs
+	switch {
+	case p.current.Typ == token.ElvisOperator:
+	case p.accept(token.TernaryOperator2):
+		// a short ternary with a comment between its ? and :
+	default:
 		truthy = p.parseNextExpression()
+		p.expect(token.TernaryOperator2)
 	}
-	p.expect(token.TernaryOperator2)
-	falsy := p.parseNextExpression()
+	// the false value ends at an operator that binds as loosely as the
+	// ternary, such as the ? of a following ternary or an and
+	level, _, _ := token.TernaryOperator1.Precedence()
+	p.next()
+	falsy := p.parseOperation(p.parenLevel, p.parseOperand(), level)
 	return &ast.TernaryCallExpr{
+		Begin:     begin(lhs),
 		Condition: lhs,
 		True:      truthy,
 		False:     falsy,
@@ -107,17 +143,23 @@ func (p *Parser) parseTernaryOperation(lhs ast.Expr) ast.Expr {
 	}
 }
 
+// parseUnaryExpressionRight returns operator applied to the operand on its
+// right, as in -$a.
 func (p *Parser) parseUnaryExpressionRight(operand ast.Expr, operator token.Item) ast.Expr {
 	return ast.UnaryCallExpr{
-		Operand:  operand,
-		Operator: operator.Val,
+		Begin:     operator.Begin,
+		Operand:   operand,
+		Operator:  operator.Val,
+		Preceding: true,
 	}
 }
 
+// parseUnaryExpressionLeft returns operator applied to the operand on its
+// left, as in $a++.
 func (p *Parser) parseUnaryExpressionLeft(operand ast.Expr, operator token.Item) ast.Expr {
 	return ast.UnaryCallExpr{
-		Operand:   operand,
-		Operator:  operator.Val,
-		Preceding: true,
+		Begin:    begin(operand),
+		Operand:  operand,
+		Operator: operator.Val,
 	}
 }
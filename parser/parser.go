@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"path"
 
@@ -18,6 +19,10 @@ type Parser struct {
 	MaxErrors   int  // Indicates the number of errors to allow before triggering a panic. The default is 10.
 	FileSet     *ast.FileSet
 
+	// Version, if set, causes the parser to report uses of syntax newer than
+	// that version of PHP as errors.
+	Version lexer.Version
+
 	lexer      token.Stream
 	previous   []token.Item
 	idx        int
@@ -36,6 +41,17 @@ type Parser struct {
 	disableScoping bool
 
 	instantiation bool
+
+	// skipped holds the array literals with skipped elements, as in
+	// [, $b], that are not yet known to be destructuring patterns.
+	skipped []skippedElements
+}
+
+// skippedElements is an array literal with skipped elements and the line of
+// the first of them.
+type skippedElements struct {
+	array *ast.ArrayExpr
+	line  int
 }
 
 // NewParser readies a parser
@@ -83,17 +99,29 @@ func (p ParseError) String() string {
 	return p.Error()
 }
 
+// Parse parses input as the contents of a single PHP file using a new Parser.
+func Parse(input string) (*ast.File, error) {
+	return NewParser().Parse("", input)
+}
+
 // Parse consumes the input string to produce an AST that represents it.
 func (p *Parser) Parse(filepath, input string) (file *ast.File, err error) {
-	file = &ast.File{Namespace: p.FileSet.GlobalNamespace, Name: path.Base(filepath)}
+	file = &ast.File{
+		Namespace: p.FileSet.GlobalNamespace,
+		Name:      path.Base(filepath),
+		Imports:   ast.NewImports(),
+	}
 	p.file = file
+	p.skipped = nil
 	p.scope = p.FileSet.Scope
 	p.namespace = p.FileSet.GlobalNamespace
-	p.lexer = token.Subset(lexer.NewLexer(input), token.Significant)
+	l := lexer.NewLexer(input, lexer.TargetVersion(p.Version))
+	p.lexer = token.Subset(l, token.Significant)
 
 	p.FileSet.Files[filepath] = p.file
 	defer func() {
 		if r := recover(); r != nil {
+			p.errors = append(p.errors, p.violations(l)...)
 			err = append(ParseErrorList{errorf(p, "%s", r)}, p.errors...)
 			if p.Debug {
 				for _, err := range p.errors {
@@ -118,17 +146,37 @@ TokenLoop:
 			}
 		}
 	}
+	for _, s := range p.skipped {
+		p.errors = append(p.errors, ParseError{error: errors.New("cannot use empty array elements in arrays"), Line: s.line, File: p.file})
+	}
+	p.errors = append(p.errors, p.violations(l)...)
 	if p.errors != nil {
 		err = p.errors
 	}
 	return
 }
 
+// violations returns an error for each use of syntax newer than the targeted
+// version that l has found.
+func (p *Parser) violations(l token.Stream) ParseErrorList {
+	var errs ParseErrorList
+	for _, v := range lexer.Violations(l) {
+		errs = append(errs, ParseError{error: v, Line: v.Begin.Line, File: p.file})
+	}
+	return errs
+}
+
 func (p *Parser) parseNode() ast.Node {
 	switch p.current.Typ {
 	case token.HTML:
-		return ast.Echo(ast.Literal{Type: ast.String, Value: p.current.Val})
+		echo := ast.Echo(ast.Literal{Begin: p.current.Begin, Type: ast.String, Value: p.current.Val})
+		echo.Begin = p.current.Begin
+		return echo
 	case token.PHPBegin:
+		if p.current.Val == "<?=" {
+			// <?= is shorthand for an echo of the expressions up to ?>
+			return p.parseEcho()
+		}
 		return nil
 	case token.PHPEnd:
 		return nil
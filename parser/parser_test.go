@@ -6,11 +6,15 @@ import (
 	"testing"
 
 	"github.com/stephens2424/php/ast"
+	"github.com/stephens2424/php/lexer"
 	"github.com/stephens2424/php/passes/printing"
+	"github.com/stephens2424/php/token"
 )
 
 func assertEquals(found, expected ast.Node) bool {
 	w := printing.NewWalker()
+	clearPositions(reflect.ValueOf(&found).Elem(), map[uintptr]bool{})
+	clearPositions(reflect.ValueOf(&expected).Elem(), map[uintptr]bool{})
 	if !reflect.DeepEqual(found, expected) {
 		fmt.Printf("Found:    %s\n", found)
 		w.Walk(found)
@@ -22,6 +26,50 @@ func assertEquals(found, expected ast.Node) bool {
 	return true
 }
 
+var positionType = reflect.TypeOf(token.Position{})
+
+// clearPositions zeroes the source positions recorded in a parsed tree so
+// that it may be compared against a tree built by hand.
+func clearPositions(v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		clearPositions(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		e := v.Elem()
+		if e.Kind() == reflect.Ptr {
+			clearPositions(e, seen)
+			return
+		}
+		c := reflect.New(e.Type()).Elem()
+		c.Set(e)
+		clearPositions(c, seen)
+		if v.CanSet() {
+			v.Set(c)
+		}
+	case reflect.Struct:
+		if v.Type() == positionType {
+			if v.CanSet() {
+				v.Set(reflect.Zero(positionType))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			clearPositions(v.Field(i), seen)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPositions(v.Index(i), seen)
+		}
+	}
+}
+
 func findDifference(found, expected ast.Node) {
 	w := printing.NewWalker()
 	foundChildren := found.Children()
@@ -83,6 +131,32 @@ func TestInclude(t *testing.T) {
 	}
 }
 
+func TestIncludeForms(t *testing.T) {
+	path := &ast.Literal{Type: ast.String, Value: "'f.php'"}
+	for _, keyword := range []string{"include", "include_once", "require", "require_once"} {
+		tests := map[string]ast.Node{
+			keyword + " 'f.php';":  ast.ExprStmt{ast.Include{Keyword: keyword, Expressions: []ast.Expr{path}}},
+			keyword + "('f.php');": ast.ExprStmt{ast.Include{Keyword: keyword, Expressions: []ast.Expr{path}}},
+			"$x = " + keyword + " 'f.php';": ast.ExprStmt{ast.AssignmentExpr{
+				Assignee: ast.NewVariable("x"),
+				Value:    ast.Include{Keyword: keyword, Expressions: []ast.Expr{path}},
+				Operator: "=",
+			}},
+		}
+		for src, tree := range tests {
+			p := NewParser()
+			p.disableScoping = true
+			a, err := p.Parse("test.php", "<?php "+src)
+			if err != nil {
+				t.Fatalf("%s: %s", src, err)
+			}
+			if len(a.Nodes) != 1 || !assertEquals(a.Nodes[0], tree) {
+				t.Errorf("%s did not correctly parse", src)
+			}
+		}
+	}
+}
+
 func TestIf(t *testing.T) {
 	testStr := `<?php
     if (true)
@@ -266,7 +340,7 @@ func TestExpressionParsing(t *testing.T) {
 	if !ok {
 		t.Fatalf("If did not correctly parse")
 	}
-	if !reflect.DeepEqual(*parsedIf, ifStmt) {
+	if !assertEquals(*parsedIf, ifStmt) {
 		t.Fatalf("If did not correctly parse")
 	}
 
@@ -306,7 +380,7 @@ func TestExpressionParsing(t *testing.T) {
 	if !ok {
 		t.Fatalf("If did not correctly parse")
 	}
-	if !reflect.DeepEqual(*parsedIf, ifStmt) {
+	if !assertEquals(*parsedIf, ifStmt) {
 		t.Fatalf("If did not correctly parse")
 	}
 
@@ -337,8 +411,7 @@ func TestArray(t *testing.T) {
 			Assignee: ast.NewVariable("var"),
 			Operator: "=",
 			Value: &ast.ArrayExpr{
-				ast.ArrayType{},
-				[]ast.ArrayPair{
+				Pairs: []ast.ArrayPair{
 					{Value: &ast.Literal{Type: ast.String, Value: `"one"`}},
 					{Value: &ast.Literal{Type: ast.String, Value: `"two"`}},
 					{Value: &ast.Literal{Type: ast.String, Value: `"three"`}},
@@ -346,9 +419,7 @@ func TestArray(t *testing.T) {
 			},
 		},
 	}
-	if !reflect.DeepEqual(a.Nodes[0], tree) {
-		fmt.Printf("Found:    %+v\n", a.Nodes[0])
-		fmt.Printf("Expected: %+v\n", tree)
+	if !assertEquals(a.Nodes[0], tree) {
 		t.Fatalf("Array did not correctly parse")
 	}
 }
@@ -366,8 +437,7 @@ func TestArrayKeys(t *testing.T) {
 		Assignee: ast.NewVariable("var"),
 		Operator: "=",
 		Value: &ast.ArrayExpr{
-			ast.ArrayType{},
-			[]ast.ArrayPair{
+			Pairs: []ast.ArrayPair{
 				{Key: &ast.Literal{Type: ast.Float, Value: "1"}, Value: &ast.Literal{Type: ast.String, Value: `"one"`}},
 				{Key: &ast.Literal{Type: ast.Float, Value: "2"}, Value: &ast.Literal{Type: ast.String, Value: `"two"`}},
 				{Key: &ast.Literal{Type: ast.Float, Value: "3"}, Value: &ast.Literal{Type: ast.String, Value: `"three"`}},
@@ -561,6 +631,48 @@ func TestForLoop(t *testing.T) {
 	}
 }
 
+func TestForLoopEmptyClauses(t *testing.T) {
+	i := ast.NewVariable("i")
+	init := ast.AssignmentExpr{
+		Assignee: i,
+		Value:    &ast.Literal{Type: ast.Float, Value: "0"},
+		Operator: "=",
+	}
+	cond := ast.BinaryExpr{
+		Antecedent: i,
+		Subsequent: &ast.Literal{Type: ast.Float, Value: "10"},
+		Operator:   "<",
+		Type:       ast.Boolean,
+	}
+	incr := ast.UnaryCallExpr{Operator: "++", Operand: i}
+
+	tests := []struct {
+		src  string
+		tree *ast.ForStmt
+	}{
+		{`<? for (;;) {}`, &ast.ForStmt{}},
+		{`<? for ($i = 0;;$i++) {}`, &ast.ForStmt{Initialization: []ast.Expr{init}, Iteration: []ast.Expr{incr}}},
+		{`<? for (; $i < 10;) {}`, &ast.ForStmt{Termination: []ast.Expr{cond}}},
+		{`<? for (; $i < 10; $i++) {}`, &ast.ForStmt{Termination: []ast.Expr{cond}, Iteration: []ast.Expr{incr}}},
+		{`<? for ($i = 0; $i < 10;) {}`, &ast.ForStmt{Initialization: []ast.Expr{init}, Termination: []ast.Expr{cond}}},
+		{`<? for ($i = 0;; ) $i++;`, &ast.ForStmt{Initialization: []ast.Expr{init}, LoopBlock: ast.ExprStmt{incr}}},
+	}
+	for _, test := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", test.src)
+		if err != nil {
+			t.Fatalf("%s: %s", test.src, err)
+		}
+		if test.tree.LoopBlock == nil {
+			test.tree.LoopBlock = &ast.Block{}
+		}
+		if !assertEquals(a.Nodes[0], test.tree) {
+			t.Fatalf("For with empty clauses did not correctly parse: %s", test.src)
+		}
+	}
+}
+
 func TestWhileLoopWithAssignment(t *testing.T) {
 	testStr := `<?
   while ($var = mysql_assoc()) {
@@ -720,6 +832,7 @@ func TestLiterals(t *testing.T) {
 			Operator: "=",
 		}},
 	}
+	clearPositions(reflect.ValueOf(a.Nodes), map[uintptr]bool{})
 	if !reflect.DeepEqual(a.Nodes, tree) {
 		fmt.Printf("Found:    %+v\n", a)
 		fmt.Printf("Expected: %+v\n", tree)
@@ -740,6 +853,7 @@ func TestComments(t *testing.T) {
 	p := NewParser()
 	p.disableScoping = true
 	a, _ := p.Parse("test.php", testStr)
+	clearPositions(reflect.ValueOf(a.Nodes), map[uintptr]bool{})
 	if !reflect.DeepEqual(a.Nodes, tree) {
 		fmt.Printf("Found:    %+v\n", a)
 		fmt.Printf("Expected: %+v\n", tree)
@@ -804,7 +918,7 @@ func TestCastOperator(t *testing.T) {
 			Value: ast.UnaryCallExpr{
 				Operand:   &ast.Literal{Type: ast.Float, Value: "1.0"},
 				Operator:  "(double)",
-				Preceding: false,
+				Preceding: true,
 			},
 			Operator: "=",
 		}},
@@ -814,6 +928,29 @@ func TestCastOperator(t *testing.T) {
 	}
 }
 
+func TestSpacedCastOperator(t *testing.T) {
+	testStr := `<?php
+  ( int )$x;
+  (	string	) $y;
+  ($int);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.UnaryCallExpr{Operand: ast.NewVariable("x"), Operator: "(int)", Preceding: true}},
+		ast.ExprStmt{ast.UnaryCallExpr{Operand: ast.NewVariable("y"), Operator: "(string)", Preceding: true}},
+		ast.ExprStmt{ast.NewVariable("int")},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("spaced cast %d did not correctly parse", i)
+		}
+	}
+}
+
 func TestInterface(t *testing.T) {
 	testStr := `<?
   interface MyInterface extends YourInterface, HerInterface {
@@ -868,3 +1005,1552 @@ func TestGlobal(t *testing.T) {
 		t.Fatalf("Global did not parse correctly")
 	}
 }
+
+func TestStaticVariables(t *testing.T) {
+	testStr := `<?php
+  static $n = 0, $m, $max = self::MAX + 1, $list = [1];`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.StaticVariableDeclaration{
+		Declarations: []ast.Dynamic{
+			&ast.AssignmentExpr{
+				Assignee: ast.NewVariable("n"),
+				Value:    &ast.Literal{Type: ast.Float, Value: "0"},
+				Operator: "=",
+			},
+			ast.NewVariable("m"),
+			&ast.AssignmentExpr{
+				Assignee: ast.NewVariable("max"),
+				Value: ast.BinaryExpr{
+					Type:       ast.Numeric,
+					Antecedent: &ast.ClassExpr{Receiver: &ast.Identifier{Value: "self"}, Expr: ast.ConstantExpr{ast.NewVariable("MAX")}},
+					Subsequent: &ast.Literal{Type: ast.Float, Value: "1"},
+					Operator:   "+",
+				},
+				Operator: "=",
+			},
+			&ast.AssignmentExpr{
+				Assignee: ast.NewVariable("list"),
+				Value: &ast.ArrayExpr{
+					Pairs: []ast.ArrayPair{{Value: &ast.Literal{Type: ast.Float, Value: "1"}}},
+				},
+				Operator: "=",
+			},
+		},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Static variables did not parse correctly")
+	}
+}
+
+func TestEchoHeredoc(t *testing.T) {
+	testStr := `<?php
+$heredoc = <<<EOT
+hello
+EOT;
+echo $heredoc, "\n";
+echo <<<EOT
+world
+EOT
+. $more, "\n";
+print $a . $b;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Nodes) != 4 {
+		t.Fatalf("Echo of heredocs did not correctly parse")
+	}
+	tree := []ast.Node{
+		ast.EchoStmt{Expressions: []ast.Expr{
+			ast.NewVariable("heredoc"),
+			&ast.Literal{Type: ast.String, Value: `"\n"`},
+		}},
+		ast.EchoStmt{Expressions: []ast.Expr{
+			ast.BinaryExpr{
+				Type:       ast.String,
+				Operator:   ".",
+				Antecedent: &ast.Literal{Type: ast.String, Value: "<<<EOT\nworld\nEOT"},
+				Subsequent: ast.NewVariable("more"),
+			},
+			&ast.Literal{Type: ast.String, Value: `"\n"`},
+		}},
+		ast.ExprStmt{&ast.PrintExpr{
+			Expr: ast.BinaryExpr{
+				Type:       ast.String,
+				Operator:   ".",
+				Antecedent: ast.NewVariable("a"),
+				Subsequent: ast.NewVariable("b"),
+			},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i+1], node) {
+			t.Fatalf("Echo of heredocs did not correctly parse")
+		}
+	}
+}
+
+func TestFunctionTrailingComma(t *testing.T) {
+	testStr := `<?php
+    function f(
+      int $a,
+      string $b,
+      $c = 3,
+    ) {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.FunctionStmt{
+		FunctionDefinition: &ast.FunctionDefinition{
+			Name: "f",
+			Arguments: []*ast.FunctionArgument{
+				{TypeHint: "int", Variable: ast.NewVariable("a")},
+				{TypeHint: "string", Variable: ast.NewVariable("b")},
+				{Variable: ast.NewVariable("c"), Default: &ast.Literal{Type: ast.Float, Value: "3"}},
+			},
+		},
+		Body: &ast.Block{},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Function with trailing comma did not correctly parse")
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct{ trailing, plain string }{
+		{`f(1, 2,);`, `f(1, 2);`},
+		{`$o->m($a,);`, `$o->m($a);`},
+		{`A::m($a,);`, `A::m($a);`},
+		{`new A(1,);`, `new A(1);`},
+		{`$a = [1, 2,];`, `$a = [1, 2];`},
+		{`$a = array(1, 2,);`, `$a = array(1, 2);`},
+		{`function f($a, $b,) {}`, `function f($a, $b) {}`},
+		{`$f = function ($a,) use ($b, $c,) {};`, `$f = function ($a) use ($b, $c) {};`},
+	}
+	for _, tt := range tests {
+		parse := func(src string) ast.Node {
+			p := NewParser()
+			p.disableScoping = true
+			a, err := p.Parse("test.php", "<?php "+src)
+			if err != nil {
+				t.Fatalf("%s: %s", src, err)
+			}
+			n := a.Nodes[0]
+			clearPositions(reflect.ValueOf(&n).Elem(), map[uintptr]bool{})
+			return n
+		}
+		if !assertEquals(parse(tt.trailing), parse(tt.plain)) {
+			t.Errorf("%s did not parse as %s", tt.trailing, tt.plain)
+		}
+	}
+}
+
+func TestNullsafeCoalesceAssignment(t *testing.T) {
+	testStr := `<?php
+    $var->go ??= $res;
+    $x = $var?->go;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: &ast.PropertyCallExpr{
+				Receiver: ast.NewVariable("var"),
+				Name:     &ast.Identifier{Value: "go"},
+			},
+			Operator: "??=",
+			Value:    ast.NewVariable("res"),
+		}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value: &ast.PropertyCallExpr{
+				Receiver: ast.NewVariable("var"),
+				Name:     &ast.Identifier{Value: "go"},
+				Nullsafe: true,
+			},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Nullsafe and coalesce assignment did not correctly parse")
+		}
+	}
+
+	for _, src := range []string{
+		`<?php $var?->go ??= $res;`,
+		`<?php $var?->go->on = $res;`,
+		`<?php $var?->go()[0] = $res;`,
+	} {
+		p := NewParser()
+		p.disableScoping = true
+		if _, err := p.Parse("test.php", src); err == nil {
+			t.Errorf("expected an error assigning to a nullsafe lookup in %q", src)
+		}
+	}
+}
+
+func TestReturnType(t *testing.T) {
+	testStr := `<?php
+    function f(int $a): ?MyClass {}
+    $g = function() use ($a): \Foo\Bar {};`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := a.Nodes[0].(*ast.FunctionStmt)
+	if fn.ReturnType != "?MyClass" {
+		t.Errorf("function return type was %q, expected ?MyClass", fn.ReturnType)
+	}
+	closure := a.Nodes[1].(ast.ExprStmt).Expr.(ast.AssignmentExpr).Value.(*ast.AnonymousFunction)
+	if closure.ReturnType != `\Foo\Bar` {
+		t.Errorf(`closure return type was %q, expected \Foo\Bar`, closure.ReturnType)
+	}
+}
+
+func TestUnionTypeArguments(t *testing.T) {
+	testStr := `<?php
+    function f(int|string $a, (A&B)|null $b): int|false {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.FunctionStmt{
+		FunctionDefinition: &ast.FunctionDefinition{
+			Name: "f",
+			Arguments: []*ast.FunctionArgument{
+				{TypeHint: "int|string", Variable: ast.NewVariable("a")},
+				{TypeHint: "(A&B)|null", Variable: ast.NewVariable("b")},
+			},
+			ReturnType: "int|false",
+		},
+		Body: &ast.Block{},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Function with union types did not correctly parse")
+	}
+}
+
+func TestNullableArgument(t *testing.T) {
+	testStr := `<?php
+    function f(?int $a, ?\App\Model $b = null) {}
+    $a ? $b : null;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := a.Nodes[0].(*ast.FunctionStmt).Arguments
+	if len(args) != 2 || args[0].TypeHint != "?int" || args[1].TypeHint != `?\App\Model` {
+		t.Fatalf("Nullable arguments did not correctly parse: %v", args)
+	}
+	if _, ok := a.Nodes[1].(ast.ExprStmt).Expr.(*ast.TernaryCallExpr); !ok {
+		t.Fatalf("Ternary after nullable arguments did not correctly parse")
+	}
+}
+
+func TestShortEcho(t *testing.T) {
+	testStr := `<p><?=
+    $greeting .
+      $name
+  ?></p>`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.Echo(ast.Literal{Type: ast.String, Value: "<p>"}),
+		ast.Echo(ast.BinaryExpr{
+			Antecedent: ast.NewVariable("greeting"),
+			Subsequent: ast.NewVariable("name"),
+			Operator:   ".",
+			Type:       ast.String,
+		}),
+		ast.Echo(ast.Literal{Type: ast.String, Value: "</p>"}),
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("Short echo parsed to %d nodes, expected %d", len(a.Nodes), len(tree))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Short echo did not correctly parse")
+		}
+	}
+}
+
+func TestVariadicArgument(t *testing.T) {
+	testStr := `<?php function f(string $format, int &...$args) {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := a.Nodes[0].(*ast.FunctionStmt).Arguments
+	if len(args) != 2 || args[0].Variadic || !args[1].Variadic || args[1].TypeHint != "int" {
+		t.Fatalf("Variadic argument did not correctly parse: %v", args)
+	}
+}
+
+func TestYield(t *testing.T) {
+	testStr := `<?php
+    yield;
+    yield $v;
+    yield $k => $v;
+    $x = yield $v;
+    yield from gen();`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.YieldExpr{}},
+		ast.ExprStmt{&ast.YieldExpr{Value: ast.NewVariable("v")}},
+		ast.ExprStmt{&ast.YieldExpr{Key: ast.NewVariable("k"), Value: ast.NewVariable("v")}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value:    &ast.YieldExpr{Value: ast.NewVariable("v")},
+		}},
+		ast.ExprStmt{&ast.YieldExpr{
+			Value: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "gen"},
+				Arguments:    make([]ast.Expr, 0),
+			},
+			From: true,
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Yield did not correctly parse")
+		}
+	}
+
+	p = NewParser()
+	p.disableScoping = true
+	if _, err := p.Parse("test.php", `<?php yield from;`); err == nil {
+		t.Fatal("expected an error for yield from without an expression")
+	}
+}
+
+func TestClone(t *testing.T) {
+	testStr := `<?php
+    $b = clone $a;
+    clone (new Foo);
+    clone $a->b;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("b"),
+			Operator: "=",
+			Value:    &ast.CloneExpr{Expr: ast.NewVariable("a")},
+		}},
+		ast.ExprStmt{&ast.CloneExpr{Expr: &ast.NewCallExpr{Class: &ast.Identifier{Value: "Foo"}}}},
+		ast.ExprStmt{&ast.CloneExpr{Expr: &ast.PropertyCallExpr{
+			Receiver: ast.NewVariable("a"),
+			Name:     &ast.Identifier{Value: "b"},
+		}}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Clone did not correctly parse")
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	testStr := `<?php
+    print "hi";
+    $x = print "hi";
+    print_r($arr);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hi := &ast.Literal{Type: ast.String, Value: `"hi"`}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.PrintExpr{Expr: hi}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value:    &ast.PrintExpr{Expr: hi},
+		}},
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "print_r"},
+			Arguments:    []ast.Expr{ast.NewVariable("arr")},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Print did not correctly parse")
+		}
+	}
+}
+
+func TestAssociativity(t *testing.T) {
+	testStr := `<?php
+    1 - 2 - 3;
+    $a = $b = 3;
+    1 - 2 * 3 - 4;
+    $a && $b == $c && $d;
+    $a || $b && $c || $d;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.BinaryExpr{
+			Antecedent: ast.BinaryExpr{
+				Antecedent: &ast.Literal{Type: ast.Float, Value: "1"},
+				Subsequent: &ast.Literal{Type: ast.Float, Value: "2"},
+				Operator:   "-",
+				Type:       ast.Numeric,
+			},
+			Subsequent: &ast.Literal{Type: ast.Float, Value: "3"},
+			Operator:   "-",
+			Type:       ast.Numeric,
+		}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("a"),
+			Operator: "=",
+			Value: ast.AssignmentExpr{
+				Assignee: ast.NewVariable("b"),
+				Operator: "=",
+				Value:    &ast.Literal{Type: ast.Float, Value: "3"},
+			},
+		}},
+		ast.ExprStmt{binary(
+			binary(
+				&ast.Literal{Type: ast.Float, Value: "1"},
+				"-",
+				binary(&ast.Literal{Type: ast.Float, Value: "2"}, "*", &ast.Literal{Type: ast.Float, Value: "3"}, ast.Numeric),
+				ast.Numeric,
+			),
+			"-",
+			&ast.Literal{Type: ast.Float, Value: "4"},
+			ast.Numeric,
+		)},
+		ast.ExprStmt{binary(
+			binary(
+				ast.NewVariable("a"),
+				"&&",
+				binary(ast.NewVariable("b"), "==", ast.NewVariable("c"), ast.Boolean),
+				ast.Boolean,
+			),
+			"&&",
+			ast.NewVariable("d"),
+			ast.Boolean,
+		)},
+		ast.ExprStmt{binary(
+			binary(
+				ast.NewVariable("a"),
+				"||",
+				binary(ast.NewVariable("b"), "&&", ast.NewVariable("c"), ast.Boolean),
+				ast.Boolean,
+			),
+			"||",
+			ast.NewVariable("d"),
+			ast.Boolean,
+		)},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Associativity was not respected")
+		}
+	}
+}
+
+// binary returns the operation op on a and b, which evaluates to typ.
+func binary(a ast.Expr, op string, b ast.Expr, typ ast.Type) ast.BinaryExpr {
+	return ast.BinaryExpr{Antecedent: a, Subsequent: b, Operator: op, Type: typ}
+}
+
+func TestAssignmentPrecedence(t *testing.T) {
+	testStr := `<?php
+    $x = $a + $b;
+    $y = $a ?? $b;
+    $z = 1 and 2;
+    ($w = 1) + 2;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value: ast.BinaryExpr{
+				Antecedent: ast.NewVariable("a"),
+				Subsequent: ast.NewVariable("b"),
+				Operator:   "+",
+				Type:       ast.Numeric,
+			},
+		}},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("y"),
+			Operator: "=",
+			Value: ast.BinaryExpr{
+				Antecedent: ast.NewVariable("a"),
+				Subsequent: ast.NewVariable("b"),
+				Operator:   "??",
+				Type:       ast.Unknown,
+			},
+		}},
+		ast.ExprStmt{ast.BinaryExpr{
+			Antecedent: ast.AssignmentExpr{
+				Assignee: ast.NewVariable("z"),
+				Operator: "=",
+				Value:    &ast.Literal{Type: ast.Float, Value: "1"},
+			},
+			Subsequent: &ast.Literal{Type: ast.Float, Value: "2"},
+			Operator:   "and",
+			Type:       ast.Boolean,
+		}},
+		ast.ExprStmt{ast.BinaryExpr{
+			Antecedent: ast.AssignmentExpr{
+				Assignee: ast.NewVariable("w"),
+				Operator: "=",
+				Value:    &ast.Literal{Type: ast.Float, Value: "1"},
+			},
+			Subsequent: &ast.Literal{Type: ast.Float, Value: "2"},
+			Operator:   "+",
+			Type:       ast.Numeric,
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d statements, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Assignment precedence was not respected")
+		}
+	}
+}
+
+func TestAssignmentBeforeColon(t *testing.T) {
+	// the : of a ternary or a case ends the value of an assignment before it
+	for _, src := range []string{
+		`<?php $c ? $a += 1 : 2;`,
+		`<?php switch ($v) { case $a = 1: }`,
+	} {
+		if _, err := Parse(src); err != nil {
+			t.Errorf("%s: %s", src, err)
+		}
+	}
+	if _, err := Parse(`<?php $a = b:c;`); err == nil {
+		t.Errorf("expected an error for a colon after an assignment")
+	}
+
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", `<?php $r = $x ? $a = 1 : 2;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := ast.ExprStmt{ast.AssignmentExpr{
+		Assignee: ast.NewVariable("r"),
+		Operator: "=",
+		Value: &ast.TernaryCallExpr{
+			Condition: ast.NewVariable("x"),
+			True: ast.AssignmentExpr{
+				Assignee: ast.NewVariable("a"),
+				Operator: "=",
+				Value:    &ast.Literal{Type: ast.Float, Value: "1"},
+			},
+			False: &ast.Literal{Type: ast.Float, Value: "2"},
+			Type:  ast.Float.Union(ast.Float),
+		},
+	}}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("An assignment in a ternary did not correctly parse")
+	}
+}
+
+func TestLanguageConstructs(t *testing.T) {
+	testStr := `<?php
+    isset($a, $b["x"]);
+    empty($a);
+    unset($a, $b);
+    list(, $second) = $arr;
+    $coll->empty();`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "isset"},
+			Arguments: []ast.Expr{
+				ast.NewVariable("a"),
+				&ast.ArrayLookupExpr{
+					Array: ast.NewVariable("b"),
+					Index: &ast.Literal{Type: ast.String, Value: `"x"`},
+				},
+			},
+		}},
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "empty"},
+			Arguments:    []ast.Expr{ast.NewVariable("a")},
+		}},
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "unset"},
+			Arguments:    []ast.Expr{ast.NewVariable("a"), ast.NewVariable("b")},
+		}},
+		ast.ExprStmt{&ast.ListStatement{
+			Operator:  "=",
+			Assignees: []ast.Assignable{nil, ast.NewVariable("second")},
+			Value:     ast.NewVariable("arr"),
+		}},
+		ast.ExprStmt{&ast.MethodCallExpr{
+			Receiver: ast.NewVariable("coll"),
+			FunctionCallExpr: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "empty"},
+				Arguments:    []ast.Expr{},
+			},
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Language constructs did not parse correctly")
+		}
+	}
+
+	for _, src := range []string{`<?php isset(1);`, `<?php unset(f());`, `<?php empty($a, $b);`} {
+		if _, err := NewParser().Parse("test.php", src); err == nil {
+			t.Errorf("expected an error parsing %q", src)
+		}
+	}
+}
+
+func TestMethodChain(t *testing.T) {
+	testStr := `<?php
+    $o->a()->b()->c()->d()->e;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expr ast.Expr = ast.NewVariable("o")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		expr = &ast.MethodCallExpr{
+			Receiver: expr,
+			FunctionCallExpr: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: name},
+				Arguments:    []ast.Expr{},
+			},
+		}
+	}
+	expr = &ast.PropertyCallExpr{Receiver: expr, Name: &ast.Identifier{Value: "e"}}
+	if !assertEquals(a.Nodes[0], ast.ExprStmt{expr}) {
+		t.Fatalf("method chain did not correctly parse")
+	}
+}
+
+func TestExit(t *testing.T) {
+	testStr := `<?php
+    die;
+    die("bye");
+    exit;
+    exit(1);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.ExitStmt{},
+		&ast.ExitStmt{Expr: &ast.Literal{Type: ast.String, Value: `"bye"`}},
+		&ast.ExitStmt{},
+		&ast.ExitStmt{Expr: &ast.Literal{Type: ast.Float, Value: "1"}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Exit did not correctly parse")
+		}
+	}
+}
+
+func TestPipeOperator(t *testing.T) {
+	testStr := `<?php
+    $x |> "trim" |> $g;
+    $a | $b;
+    $s |> strtoupper(...);
+    $x |> f(...) |> g(...);
+    $a . $b |> f(...);
+    $a << 1 |> f(...);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callable := func(name string) ast.Expr {
+		return &ast.FunctionCallExpr{
+			FunctionName:       &ast.Identifier{Value: name},
+			Arguments:          []ast.Expr{},
+			FirstClassCallable: true,
+		}
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.PipeExpr{
+			Value: &ast.PipeExpr{
+				Value:    ast.NewVariable("x"),
+				Callable: &ast.Literal{Type: ast.String, Value: `"trim"`},
+			},
+			Callable: ast.NewVariable("g"),
+		}},
+		ast.ExprStmt{ast.BinaryExpr{
+			Type:       ast.Unknown,
+			Antecedent: ast.NewVariable("a"),
+			Subsequent: ast.NewVariable("b"),
+			Operator:   "|",
+		}},
+		ast.ExprStmt{&ast.PipeExpr{
+			Value: ast.NewVariable("s"),
+			Callable: &ast.FunctionCallExpr{
+				FunctionName:       &ast.Identifier{Value: "strtoupper"},
+				Arguments:          []ast.Expr{},
+				FirstClassCallable: true,
+			},
+		}},
+		ast.ExprStmt{&ast.PipeExpr{
+			Value:    &ast.PipeExpr{Value: ast.NewVariable("x"), Callable: callable("f")},
+			Callable: callable("g"),
+		}},
+		ast.ExprStmt{&ast.PipeExpr{
+			Value: ast.BinaryExpr{
+				Type:       ast.String,
+				Antecedent: ast.NewVariable("a"),
+				Subsequent: ast.NewVariable("b"),
+				Operator:   ".",
+			},
+			Callable: callable("f"),
+		}},
+		ast.ExprStmt{&ast.PipeExpr{
+			Value: ast.BinaryExpr{
+				Type:       ast.Unknown,
+				Antecedent: ast.NewVariable("a"),
+				Subsequent: &ast.Literal{Type: ast.Float, Value: "1"},
+				Operator:   "<<",
+			},
+			Callable: callable("f"),
+		}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Pipe operator did not correctly parse")
+		}
+	}
+}
+
+func TestDeclare(t *testing.T) {
+	testStr := `<?php
+    declare(strict_types=1);
+    declare(ticks=1) {
+      f();
+    }
+    declare(ticks=1, encoding='UTF-8');`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.DeclareBlock{Declarations: []string{"strict_types=1"}},
+		&ast.DeclareBlock{
+			Declarations: []string{"ticks=1"},
+			Statements: &ast.Block{Statements: []ast.Statement{
+				ast.ExprStmt{&ast.FunctionCallExpr{
+					FunctionName: &ast.Identifier{Value: "f"},
+					Arguments:    []ast.Expr{},
+				}},
+			}},
+		},
+		&ast.DeclareBlock{Declarations: []string{"ticks=1", "encoding='UTF-8'"}},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Declare did not correctly parse")
+		}
+	}
+	if children := a.Nodes[0].Children(); len(children) != 0 {
+		t.Errorf("declare statement without a block has children: %v", children)
+	}
+}
+
+func TestGoto(t *testing.T) {
+	testStr := `<?php
+    goto end;
+    $x ? 1 : 2;
+    end:
+    echo "done";`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.GotoStmt{Label: "end"},
+		ast.ExprStmt{&ast.TernaryCallExpr{
+			Condition: ast.NewVariable("x"),
+			True:      &ast.Literal{Type: ast.Float, Value: "1"},
+			False:     &ast.Literal{Type: ast.Float, Value: "2"},
+			Type:      ast.Float.Union(ast.Float),
+		}},
+		&ast.LabelStmt{Name: "end"},
+		ast.Echo(&ast.Literal{Type: ast.String, Value: `"done"`}),
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Goto did not correctly parse")
+		}
+	}
+}
+
+func TestShortTernary(t *testing.T) {
+	testStr := `<?php
+    $a ?: $b;
+    $a ? : $b;
+    $a ? $b : $c;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := ast.ExprStmt{&ast.TernaryCallExpr{
+		Condition: ast.NewVariable("a"),
+		True:      ast.NewVariable("a"),
+		False:     ast.NewVariable("b"),
+		Type:      ast.Unknown.Union(ast.Unknown),
+	}}
+	tree := []ast.Node{
+		short,
+		short,
+		ast.ExprStmt{&ast.TernaryCallExpr{
+			Condition: ast.NewVariable("a"),
+			True:      ast.NewVariable("b"),
+			False:     ast.NewVariable("c"),
+			Type:      ast.Unknown.Union(ast.Unknown),
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("parsed %d statements, expected %d", len(a.Nodes), len(tree))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("ternary %d did not correctly parse", i)
+		}
+	}
+}
+
+func TestThrowExpression(t *testing.T) {
+	testStr := `<?php
+    $a ?? throw new E();
+    $a ?: throw new E();
+    throw new E();`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	throw := &ast.ThrowExpr{Expr: &ast.NewCallExpr{Class: &ast.Identifier{Value: "E"}, Arguments: []ast.Expr{}}}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.BinaryExpr{
+			Antecedent: ast.NewVariable("a"),
+			Subsequent: throw,
+			Operator:   "??",
+			Type:       ast.Unknown.Union(ast.Unknown),
+		}},
+		ast.ExprStmt{&ast.TernaryCallExpr{
+			Condition: ast.NewVariable("a"),
+			True:      ast.NewVariable("a"),
+			False:     throw,
+			Type:      ast.Unknown.Union(ast.Unknown),
+		}},
+		ast.ThrowStmt{Expr: &ast.NewCallExpr{Class: &ast.Identifier{Value: "E"}, Arguments: []ast.Expr{}}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("parsed %d statements, expected %d", len(a.Nodes), len(tree))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("throw %d did not correctly parse", i)
+		}
+	}
+}
+
+func TestCatchTypes(t *testing.T) {
+	testStr := `<?php
+    try {
+    } catch (TypeError | ValueError $e) {
+    } catch (Throwable) {
+    }`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.TryStmt{
+		TryBlock: &ast.Block{},
+		CatchStmts: []*ast.CatchStmt{
+			{CatchType: "TypeError | ValueError", CatchVar: ast.NewVariable("e"), CatchBlock: &ast.Block{}},
+			{CatchType: "Throwable", CatchBlock: &ast.Block{}},
+		},
+	}
+	if len(a.Nodes) != 1 {
+		t.Fatalf("parsed %d statements, expected 1", len(a.Nodes))
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatal("try did not correctly parse")
+	}
+}
+
+func TestFinally(t *testing.T) {
+	call := func(name string) ast.Statement {
+		return ast.ExprStmt{&ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: name}, Arguments: make([]ast.Expr, 0)}}
+	}
+	tests := map[string]*ast.TryStmt{
+		`try { a(); } finally { b(); }`: {
+			TryBlock:     &ast.Block{Statements: []ast.Statement{call("a")}},
+			FinallyBlock: &ast.Block{Statements: []ast.Statement{call("b")}},
+		},
+		`try { a(); } catch (A $e) { b(); } catch (B $e) { c(); } finally { d(); }`: {
+			TryBlock: &ast.Block{Statements: []ast.Statement{call("a")}},
+			CatchStmts: []*ast.CatchStmt{
+				{CatchType: "A", CatchVar: ast.NewVariable("e"), CatchBlock: &ast.Block{Statements: []ast.Statement{call("b")}}},
+				{CatchType: "B", CatchVar: ast.NewVariable("e"), CatchBlock: &ast.Block{Statements: []ast.Statement{call("c")}}},
+			},
+			FinallyBlock: &ast.Block{Statements: []ast.Statement{call("d")}},
+		},
+		`try {} catch (A $e) {} finally {}`: {
+			TryBlock:     &ast.Block{},
+			CatchStmts:   []*ast.CatchStmt{{CatchType: "A", CatchVar: ast.NewVariable("e"), CatchBlock: &ast.Block{}}},
+			FinallyBlock: &ast.Block{},
+		},
+	}
+	for src, tree := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php "+src+" e();")
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if len(a.Nodes) != 2 {
+			t.Fatalf("%s: parsed %d statements, expected 2", src, len(a.Nodes))
+		}
+		if !assertEquals(a.Nodes[0], tree) || !assertEquals(a.Nodes[1], call("e")) {
+			t.Errorf("%s did not correctly parse", src)
+		}
+	}
+
+	p := NewParser()
+	if _, err := p.Parse("test.php", "<?php try {} e();"); err == nil {
+		t.Error("expected an error for try without catch or finally")
+	}
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	// the @ operator applies to the operand following it, like a cast
+	silence := func(e ast.Expr) ast.UnaryCallExpr {
+		return ast.UnaryCallExpr{Operand: e, Operator: "@", Preceding: true}
+	}
+	call := func(name string, args ...ast.Expr) *ast.FunctionCallExpr {
+		return &ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: name}, Arguments: append([]ast.Expr{}, args...)}
+	}
+	tests := map[string]ast.Node{
+		`$a = @file_get_contents($x);`: ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("a"),
+			Operator: "=",
+			Value:    silence(call("file_get_contents", ast.NewVariable("x"))),
+		}},
+		`@@foo();`: ast.ExprStmt{silence(silence(call("foo")))},
+		`$c = !@$a[0];`: ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("c"),
+			Operator: "=",
+			Value: ast.UnaryCallExpr{
+				Operand: silence(&ast.ArrayLookupExpr{
+					Array: ast.NewVariable("a"),
+					Index: &ast.Literal{Type: ast.Float, Value: "0"},
+				}),
+				Operator:  "!",
+				Preceding: true,
+			},
+		}},
+		`@$a + 1;`: ast.ExprStmt{binary(silence(ast.NewVariable("a")), "+", &ast.Literal{Type: ast.Float, Value: "1"}, ast.Numeric)},
+	}
+	for src, tree := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php "+src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		if len(a.Nodes) != 1 || !assertEquals(a.Nodes[0], tree) {
+			t.Errorf("%s did not correctly parse", src)
+		}
+	}
+}
+
+func TestEmbeddedComments(t *testing.T) {
+	tests := map[string]string{
+		`$a = /* note */ 1;`:                                               `$a = 1;`,
+		`$a = $b /* c */ ? /* d */ : $c;`:                                  `$a = $b ?: $c;`,
+		"f(/* a */ $a, // b\n $b);":                                        `f($a, $b);`,
+		`function f(/* x */ int $a) {}`:                                    `function f(int $a) {}`,
+		`function f(?int /* x */ $a): /* r */ ?int { return /* v */ $a; }`: `function f(?int $a): ?int { return $a; }`,
+	}
+	for src, plain := range tests {
+		p := NewParser()
+		p.disableScoping = true
+		a, err := p.Parse("test.php", "<?php "+src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+		p = NewParser()
+		p.disableScoping = true
+		b, err := p.Parse("test.php", "<?php "+plain)
+		if err != nil {
+			t.Fatalf("%s: %s", plain, err)
+		}
+		expected := b.Nodes[0]
+		clearPositions(reflect.ValueOf(&expected).Elem(), map[uintptr]bool{})
+		if len(a.Nodes) != 1 || !assertEquals(a.Nodes[0], expected) {
+			t.Errorf("%s did not parse like %s", src, plain)
+		}
+	}
+}
+
+func TestAlternateSyntax(t *testing.T) {
+	testStr := `<?php
+    if ($a):
+      echo 1;
+    elseif ($b):
+      echo 2;
+    else:
+      echo 3;
+    endif;
+    while ($c) {
+      f();
+    }
+    foreach ($d as $e):
+      foreach ($e as $f) {
+        h();
+      }
+    endforeach;
+    g();`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.IfStmt{
+			Branches: []ast.IfBranch{
+				{
+					Condition: ast.NewVariable("a"),
+					Block:     &ast.Block{Statements: []ast.Statement{ast.Echo(&ast.Literal{Type: ast.Float, Value: "1"})}},
+				},
+				{
+					Condition: ast.NewVariable("b"),
+					Block:     &ast.Block{Statements: []ast.Statement{ast.Echo(&ast.Literal{Type: ast.Float, Value: "2"})}},
+				},
+			},
+			ElseBlock: &ast.Block{Statements: []ast.Statement{ast.Echo(&ast.Literal{Type: ast.Float, Value: "3"})}},
+			Alternate: true,
+		},
+		&ast.WhileStmt{
+			Termination: ast.NewVariable("c"),
+			LoopBlock: &ast.Block{Statements: []ast.Statement{
+				ast.ExprStmt{&ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: "f"}, Arguments: []ast.Expr{}}},
+			}},
+		},
+		&ast.ForeachStmt{
+			Source: ast.NewVariable("d"),
+			Value:  ast.NewVariable("e"),
+			LoopBlock: &ast.Block{Statements: []ast.Statement{
+				&ast.ForeachStmt{
+					Source: ast.NewVariable("e"),
+					Value:  ast.NewVariable("f"),
+					LoopBlock: &ast.Block{Statements: []ast.Statement{
+						ast.ExprStmt{&ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: "h"}, Arguments: []ast.Expr{}}},
+					}},
+				},
+			}},
+			Alternate: true,
+		},
+		ast.ExprStmt{&ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: "g"}, Arguments: []ast.Expr{}}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d statements, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Alternate syntax did not correctly parse")
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	testStr := `<?php
+    $a =& $b;
+    function f(array &$x) {}
+    foreach ($a as $k => &$v) {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("a"),
+			Operator: "=",
+			Value:    ast.UnaryCallExpr{Operator: "&", Operand: ast.NewVariable("b"), Preceding: true},
+		}},
+		&ast.FunctionStmt{
+			FunctionDefinition: &ast.FunctionDefinition{
+				Name: "f",
+				Arguments: []*ast.FunctionArgument{
+					{TypeHint: "array", ByRef: true, Variable: ast.NewVariable("x")},
+				},
+			},
+			Body: &ast.Block{},
+		},
+		&ast.ForeachStmt{
+			Source:    ast.NewVariable("a"),
+			Key:       ast.NewVariable("k"),
+			Value:     ast.NewVariable("v"),
+			ByRef:     true,
+			LoopBlock: &ast.Block{},
+		},
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("References did not correctly parse")
+		}
+	}
+}
+
+func TestReturnByReference(t *testing.T) {
+	testStr := `<?php
+    function &getRef() {}
+    $fn = function &() use (&$x) {};`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		&ast.FunctionStmt{
+			FunctionDefinition: &ast.FunctionDefinition{
+				Name:      "getRef",
+				Arguments: []*ast.FunctionArgument{},
+				ByRef:     true,
+			},
+			Body: &ast.Block{},
+		},
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("fn"),
+			Operator: "=",
+			Value: &ast.AnonymousFunction{
+				Arguments:        []*ast.FunctionArgument{},
+				ClosureVariables: []*ast.FunctionArgument{{ByRef: true, Variable: ast.NewVariable("x")}},
+				Body:             &ast.Block{},
+				ByRef:            true,
+			},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("parsed %d statements, expected %d", len(a.Nodes), len(tree))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Return by reference did not correctly parse")
+		}
+	}
+}
+
+func TestParameterDefaults(t *testing.T) {
+	testStr := `<?php
+    function f(int $x = 5, ?string $name = null, array $opts = [], $c = self::FOO, int ...$rest) {}`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := &ast.FunctionStmt{
+		FunctionDefinition: &ast.FunctionDefinition{
+			Name: "f",
+			Arguments: []*ast.FunctionArgument{
+				{TypeHint: "int", Variable: ast.NewVariable("x"), Default: &ast.Literal{Type: ast.Float, Value: "5"}},
+				{TypeHint: "?string", Variable: ast.NewVariable("name"), Default: &ast.Literal{Type: ast.Null, Value: "null"}},
+				{TypeHint: "array", Variable: ast.NewVariable("opts"), Default: &ast.ArrayExpr{}},
+				{
+					Variable: ast.NewVariable("c"),
+					Default: &ast.ClassExpr{
+						Receiver: &ast.Identifier{Value: "self"},
+						Expr:     ast.ConstantExpr{Variable: ast.NewVariable("FOO")},
+					},
+				},
+				{TypeHint: "int", Variadic: true, Variable: ast.NewVariable("rest")},
+			},
+		},
+		Body: &ast.Block{},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Parameter defaults did not correctly parse")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	testStr := `<?php
+    function f(#[SensitiveParameter] $p, $b) {}
+    #[Pure] function g() {} echo 1;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Nodes) != 3 {
+		t.Fatalf("expected 3 statements, found %d", len(a.Nodes))
+	}
+	tree := &ast.FunctionStmt{
+		FunctionDefinition: &ast.FunctionDefinition{
+			Name: "f",
+			Arguments: []*ast.FunctionArgument{
+				{Variable: ast.NewVariable("p")},
+				{Variable: ast.NewVariable("b")},
+			},
+		},
+		Body: &ast.Block{},
+	}
+	if !assertEquals(a.Nodes[0], tree) {
+		t.Fatalf("Attributed parameter did not correctly parse")
+	}
+	tree = &ast.FunctionStmt{
+		FunctionDefinition: &ast.FunctionDefinition{Name: "g", Arguments: []*ast.FunctionArgument{}},
+		Body:               &ast.Block{},
+	}
+	if !assertEquals(a.Nodes[1], tree) {
+		t.Fatalf("Attributed function did not correctly parse")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	src := "<?php\n$a ??= 1;"
+	if _, err := NewParser().Parse("test.php", src); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewParser()
+	p.Version = lexer.PHP73
+	_, err := p.Parse("test.php", src)
+	if err == nil || err.Error() != "test.php:2: null coalescing assignment requires PHP 7.4" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	p = NewParser()
+	p.Version = lexer.PHP74
+	if _, err := p.Parse("test.php", src); err != nil {
+		t.Error(err)
+	}
+
+	// violations are reported even if parsing gives up
+	p = NewParser()
+	p.Version = lexer.PHP73
+	p.MaxErrors = 0
+	_, err = p.Parse("test.php", src+"\n1 1;\n2 2;")
+	errs, ok := err.(ParseErrorList)
+	if !ok || len(errs) == 0 || errs[len(errs)-1].Error() != "test.php:2: null coalescing assignment requires PHP 7.4" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	testStr := `<?php
+    $x = match ($a) {
+      1, 2 => "low",
+      default => "high",
+    };
+    match(true) { $b => f() };`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{ast.AssignmentExpr{
+			Assignee: ast.NewVariable("x"),
+			Operator: "=",
+			Value: &ast.MatchExpr{
+				Subject: ast.NewVariable("a"),
+				Arms: []*ast.MatchArm{
+					{
+						Conditions: []ast.Expr{
+							&ast.Literal{Type: ast.Float, Value: "1"},
+							&ast.Literal{Type: ast.Float, Value: "2"},
+						},
+						Body: &ast.Literal{Type: ast.String, Value: `"low"`},
+					},
+					{Body: &ast.Literal{Type: ast.String, Value: `"high"`}},
+				},
+			},
+		}},
+		ast.ExprStmt{&ast.MatchExpr{
+			Subject: &ast.Literal{Type: ast.Boolean, Value: "true"},
+			Arms: []*ast.MatchArm{
+				{
+					Conditions: []ast.Expr{ast.NewVariable("b")},
+					Body:       &ast.FunctionCallExpr{FunctionName: &ast.Identifier{Value: "f"}, Arguments: []ast.Expr{}},
+				},
+			},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Match expression did not parse correctly")
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	testStr := `<?php
+    f(name: 1);
+    new Point(x: 1, ...$rest);
+    $o->m($a, flag: true);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "f"},
+			Arguments: []ast.Expr{
+				&ast.NamedArgument{Name: "name", Value: &ast.Literal{Type: ast.Float, Value: "1"}},
+			},
+		}},
+		ast.ExprStmt{&ast.NewCallExpr{
+			Class: &ast.Identifier{Value: "Point"},
+			Arguments: []ast.Expr{
+				&ast.NamedArgument{Name: "x", Value: &ast.Literal{Type: ast.Float, Value: "1"}},
+				ast.UnaryCallExpr{Operator: "...", Operand: ast.NewVariable("rest"), Preceding: true},
+			},
+		}},
+		ast.ExprStmt{&ast.MethodCallExpr{
+			Receiver: ast.NewVariable("o"),
+			FunctionCallExpr: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "m"},
+				Arguments: []ast.Expr{
+					ast.NewVariable("a"),
+					&ast.NamedArgument{Name: "flag", Value: &ast.Literal{Type: ast.Boolean, Value: "true"}},
+				},
+			},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Named arguments did not parse correctly")
+		}
+	}
+}
+
+func TestFirstClassCallable(t *testing.T) {
+	testStr := `<?php
+    strlen(...);
+    $obj->method(...);
+    MyClass::method(...);
+    f(...$args);`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName:       &ast.Identifier{Value: "strlen"},
+			Arguments:          []ast.Expr{},
+			FirstClassCallable: true,
+		}},
+		ast.ExprStmt{&ast.MethodCallExpr{
+			Receiver: ast.NewVariable("obj"),
+			FunctionCallExpr: &ast.FunctionCallExpr{
+				FunctionName:       &ast.Identifier{Value: "method"},
+				Arguments:          []ast.Expr{},
+				FirstClassCallable: true,
+			},
+		}},
+		ast.ExprStmt{&ast.ClassExpr{
+			Receiver: &ast.Identifier{Value: "MyClass"},
+			Expr: &ast.FunctionCallExpr{
+				FunctionName:       &ast.Identifier{Value: "method"},
+				Arguments:          []ast.Expr{},
+				FirstClassCallable: true,
+			},
+		}},
+		ast.ExprStmt{&ast.FunctionCallExpr{
+			FunctionName: &ast.Identifier{Value: "f"},
+			Arguments: []ast.Expr{
+				ast.UnaryCallExpr{Operator: "...", Operand: ast.NewVariable("args"), Preceding: true},
+			},
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("First-class callable did not parse correctly")
+		}
+	}
+}
+
+func TestClassReferences(t *testing.T) {
+	testStr := `<?php
+    $this->x;
+    self::CONST;
+    parent::__construct();
+    static::create();
+    new static;
+    static function() {};`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := []ast.Node{
+		ast.ExprStmt{&ast.PropertyCallExpr{
+			Receiver: ast.NewVariable("this"),
+			Name:     &ast.Identifier{Value: "x"},
+		}},
+		ast.ExprStmt{&ast.ClassExpr{
+			Receiver: &ast.Identifier{Value: "self"},
+			Expr:     ast.ConstantExpr{ast.NewVariable("CONST")},
+		}},
+		ast.ExprStmt{&ast.ClassExpr{
+			Receiver: &ast.Identifier{Value: "parent"},
+			Expr: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "__construct"},
+				Arguments:    []ast.Expr{},
+			},
+		}},
+		ast.ExprStmt{&ast.ClassExpr{
+			Receiver: &ast.Identifier{Value: "static"},
+			Expr: &ast.FunctionCallExpr{
+				FunctionName: &ast.Identifier{Value: "create"},
+				Arguments:    []ast.Expr{},
+			},
+		}},
+		ast.ExprStmt{&ast.NewCallExpr{Class: &ast.Identifier{Value: "static"}}},
+		ast.ExprStmt{&ast.AnonymousFunction{
+			Arguments:        []*ast.FunctionArgument{},
+			ClosureVariables: []*ast.FunctionArgument{},
+			Body:             &ast.Block{},
+			Static:           true,
+		}},
+	}
+	if len(a.Nodes) != len(tree) {
+		t.Fatalf("expected %d nodes, found %d", len(tree), len(a.Nodes))
+	}
+	for i, node := range tree {
+		if !assertEquals(a.Nodes[i], node) {
+			t.Fatalf("Class reference did not parse correctly")
+		}
+	}
+}
+
+func TestInstanceof(t *testing.T) {
+	testStr := `<?php
+    $x instanceof Foo;
+    $x instanceof \Foo\Bar;
+    $x instanceof namespace\Foo;
+    $x instanceof $class;
+    $x instanceof self;
+    $x instanceof static;
+    $x instanceof parent;`
+	p := NewParser()
+	p.disableScoping = true
+	a, err := p.Parse("test.php", testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operands := []ast.Expr{
+		&ast.Identifier{Value: "Foo"},
+		&ast.Identifier{Value: `\Foo\Bar`},
+		&ast.Identifier{Value: `namespace\Foo`},
+		ast.NewVariable("class"),
+		&ast.Identifier{Value: "self"},
+		&ast.Identifier{Value: "static"},
+		&ast.Identifier{Value: "parent"},
+	}
+	if len(a.Nodes) != len(operands) {
+		t.Fatalf("expected %d nodes, found %d", len(operands), len(a.Nodes))
+	}
+	for i, operand := range operands {
+		tree := ast.ExprStmt{ast.BinaryExpr{
+			Type:       ast.Boolean,
+			Antecedent: ast.NewVariable("x"),
+			Subsequent: operand,
+			Operator:   "instanceof",
+		}}
+		if !assertEquals(a.Nodes[i], tree) {
+			t.Fatalf("instanceof did not parse correctly")
+		}
+	}
+}
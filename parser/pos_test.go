@@ -1,6 +1,106 @@
 package parser
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/stephens2424/php/ast"
+)
 
 func TestPosition(t *testing.T) {
+	testStr := `<?php
+function max($a, $b) {
+  if ($a > $b) {
+    return $a;
+  } else {
+    return $b;
+  }
+}`
+	file, err := Parse(testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Nodes) != 1 {
+		t.Fatalf("expected 1 node, found %d", len(file.Nodes))
+	}
+	fn, ok := file.Nodes[0].(*ast.FunctionStmt)
+	if !ok {
+		t.Fatalf("expected function statement, found %T", file.Nodes[0])
+	}
+	if fn.Name != "max" || len(fn.Arguments) != 2 {
+		t.Fatalf("unexpected function definition %v", fn.FunctionDefinition)
+	}
+	if len(fn.Body.Statements) != 1 {
+		t.Fatalf("expected 1 statement in function body, found %d", len(fn.Body.Statements))
+	}
+	ifStmt, ok := fn.Body.Statements[0].(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("expected if statement, found %T", fn.Body.Statements[0])
+	}
+	if len(ifStmt.Branches) != 1 || ifStmt.ElseBlock == nil {
+		t.Fatalf("expected a single branch and an else block, found %v", ifStmt)
+	}
+	ret := ifStmt.Branches[0].Block.(*ast.Block).Statements[0].(*ast.ReturnStmt)
+	elseRet := ifStmt.ElseBlock.(*ast.Block).Statements[0].(*ast.ReturnStmt)
+
+	positions := []struct {
+		node         ast.Positioner
+		line, offset int
+	}{
+		{fn.FunctionDefinition, 2, 6},
+		{fn.Arguments[0], 2, 19},
+		{fn.Arguments[1], 2, 23},
+		{ifStmt, 3, 31},
+		{ret, 4, 50},
+		{elseRet, 6, 76},
+	}
+	for _, p := range positions {
+		pos := p.node.Position()
+		if pos.Line != p.line || pos.Position != p.offset {
+			t.Errorf("%s: expected line %d offset %d, found line %d offset %d", p.node, p.line, p.offset, pos.Line, pos.Position)
+		}
+	}
+}
+
+func TestExpressionPosition(t *testing.T) {
+	testStr := `<?php
+$total = $a + f($b)->c[0] ?: Foo::BAR;
+echo -$x, [1, 2], $y++;`
+	file, err := Parse(testStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, found %d", len(file.Nodes))
+	}
+	assign := file.Nodes[0].(ast.ExprStmt).Expr.(ast.AssignmentExpr)
+	ternary := assign.Value.(*ast.TernaryCallExpr)
+	sum := ternary.Condition.(ast.BinaryExpr)
+	lookup := sum.Subsequent.(*ast.ArrayLookupExpr)
+	prop := lookup.Array.(*ast.PropertyCallExpr)
+	call := prop.Receiver.(*ast.FunctionCallExpr)
+	echo := file.Nodes[1].(ast.EchoStmt)
+
+	positions := []struct {
+		node         ast.Positioner
+		line, offset int
+	}{
+		{assign, 2, 6},
+		{assign.Assignee.(ast.Positioner), 2, 6},
+		{ternary, 2, 15},
+		{sum, 2, 15},
+		{lookup, 2, 20},
+		{prop, 2, 20},
+		{call, 2, 20},
+		{call.Arguments[0].(ast.Positioner), 2, 22},
+		{ternary.False.(ast.Positioner), 2, 35},
+		{echo.Expressions[0].(ast.Positioner), 3, 50},
+		{echo.Expressions[1].(ast.Positioner), 3, 55},
+		{echo.Expressions[2].(ast.Positioner), 3, 63},
+	}
+	for _, p := range positions {
+		pos := p.node.Position()
+		if pos.Line != p.line || pos.Position != p.offset {
+			t.Errorf("%s: expected line %d offset %d, found line %d offset %d", p.node, p.line, p.offset, pos.Line, pos.Position)
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/stephens2424/php/ast"
@@ -104,3 +105,172 @@ func ExpectClasses(ns *ast.Namespace, classes []string, t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestResolveName(t *testing.T) {
+	src := `<?php
+	namespace App\Models;
+	use Vendor\Package\Client as HttpClient, Vendor\Util;
+	use \Psr\Log\LoggerInterface;
+	`
+
+	p := NewParser()
+	a, err := p.Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, expected string
+	}{
+		{`HttpClient`, `Vendor\Package\Client`},
+		{`httpclient`, `Vendor\Package\Client`},
+		{`Util\Strings`, `Vendor\Util\Strings`},
+		{`LoggerInterface`, `Psr\Log\LoggerInterface`},
+		{`User`, `App\Models\User`},
+		{`Relations\HasMany`, `App\Models\Relations\HasMany`},
+		{`namespace\User`, `App\Models\User`},
+		{`\Exception`, `Exception`},
+		{`\HttpClient`, `HttpClient`},
+		{`self`, `self`},
+	}
+	for _, test := range tests {
+		if got := ast.ResolveName(a, test.name); got != test.expected {
+			t.Errorf("ResolveName(%q) = %q, expected %q", test.name, got, test.expected)
+		}
+	}
+
+	p = NewParser()
+	a, err = p.Parse("global.php", `<?php use Foo\Bar;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ast.ResolveName(a, "Baz"); got != "Baz" {
+		t.Errorf("ResolveName in the global namespace = %q, expected Baz", got)
+	}
+	if got := ast.ResolveName(a, "Bar"); got != `Foo\Bar` {
+		t.Errorf("ResolveName of an import in the global namespace = %q, expected Foo\\Bar", got)
+	}
+}
+
+func TestNamespaceImports(t *testing.T) {
+	for _, src := range []string{
+		`<?php
+	namespace App { use Vendor\Client; new Client; }
+	namespace Other { new Client; }
+	`,
+		`<?php
+	namespace App; use Vendor\Client; new Client;
+	namespace Other; new Client;
+	`,
+	} {
+		a, err := NewParser().Parse("test.php", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var namespaces []*ast.NamespaceStmt
+		for _, n := range a.Nodes {
+			if ns, ok := n.(*ast.NamespaceStmt); ok {
+				namespaces = append(namespaces, ns)
+			}
+		}
+		if len(namespaces) != 2 {
+			t.Fatalf("expected 2 namespaces, found %d", len(namespaces))
+		}
+		if got := namespaces[0].ResolveName("Client"); got != `Vendor\Client` {
+			t.Errorf("ResolveName in the first namespace = %q, expected Vendor\\Client", got)
+		}
+		if got := namespaces[1].ResolveName("Client"); got != `Other\Client` {
+			t.Errorf("ResolveName in the second namespace = %q, expected Other\\Client", got)
+		}
+		if got := ast.ResolveName(a, "Client"); got != `Other\Client` {
+			t.Errorf("ResolveName in the file = %q, expected Other\\Client", got)
+		}
+	}
+}
+
+func TestUseImports(t *testing.T) {
+	src := `<?php
+	use Vendor\{Client, Util\Strings as Str,};
+	use Vendor\Package\Client as HttpClient;
+	use function Vendor\Functions\map, Vendor\Functions\Filter as select;
+	use const Vendor\VERSION;
+	use Vendor\{function reduce, const Util\MAX};
+	`
+	p := NewParser()
+	a, err := p.Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"client":     `Vendor\Client`,
+		"str":        `Vendor\Util\Strings`,
+		"httpclient": `Vendor\Package\Client`,
+	}
+	if !reflect.DeepEqual(a.Uses, expected) {
+		t.Errorf("Uses = %v, expected %v", a.Uses, expected)
+	}
+	expected = map[string]string{
+		"map":    `Vendor\Functions\map`,
+		"select": `Vendor\Functions\Filter`,
+		"reduce": `Vendor\reduce`,
+	}
+	if !reflect.DeepEqual(a.FunctionUses, expected) {
+		t.Errorf("FunctionUses = %v, expected %v", a.FunctionUses, expected)
+	}
+	expected = map[string]string{
+		"VERSION": `Vendor\VERSION`,
+		"MAX":     `Vendor\Util\MAX`,
+	}
+	if !reflect.DeepEqual(a.ConstantUses, expected) {
+		t.Errorf("ConstantUses = %v, expected %v", a.ConstantUses, expected)
+	}
+
+	stmts := []ast.Node{
+		&ast.UseStmt{Prefix: `Vendor\`, Imports: []ast.UseImport{
+			{Name: "Client"},
+			{Name: `Util\Strings`, Alias: "Str"},
+		}},
+		&ast.UseStmt{Imports: []ast.UseImport{{Name: `Vendor\Package\Client`, Alias: "HttpClient"}}},
+		&ast.UseStmt{Kind: "function", Imports: []ast.UseImport{
+			{Name: `Vendor\Functions\map`},
+			{Name: `Vendor\Functions\Filter`, Alias: "select"},
+		}},
+		&ast.UseStmt{Kind: "const", Imports: []ast.UseImport{{Name: `Vendor\VERSION`}}},
+		&ast.UseStmt{Prefix: `Vendor\`, Imports: []ast.UseImport{
+			{Kind: "function", Name: "reduce"},
+			{Kind: "const", Name: `Util\MAX`},
+		}},
+	}
+	if !reflect.DeepEqual(a.Nodes, stmts) {
+		t.Errorf("use statements parsed as %#v, expected %#v", a.Nodes, stmts)
+	}
+}
+
+func TestAliasedConstantUse(t *testing.T) {
+	src := `<?php
+	use const Foo\BAR as BAZ;
+	use Vendor\{const Util\MAX as M, function f as g};
+	`
+	a, err := NewParser().Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"BAZ": `Foo\BAR`,
+		"M":   `Vendor\Util\MAX`,
+	}
+	if !reflect.DeepEqual(a.ConstantUses, expected) {
+		t.Errorf("ConstantUses = %v, expected %v", a.ConstantUses, expected)
+	}
+
+	stmts := []ast.Node{
+		&ast.UseStmt{Kind: "const", Imports: []ast.UseImport{{Name: `Foo\BAR`, Alias: "BAZ"}}},
+		&ast.UseStmt{Prefix: `Vendor\`, Imports: []ast.UseImport{
+			{Kind: "const", Name: `Util\MAX`, Alias: "M"},
+			{Kind: "function", Name: "f", Alias: "g"},
+		}},
+	}
+	if !reflect.DeepEqual(a.Nodes, stmts) {
+		t.Errorf("use statements parsed as %#v, expected %#v", a.Nodes, stmts)
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/stephens2424/php/ast"
 	"github.com/stephens2424/php/token"
 )
@@ -9,20 +11,27 @@ func (p *Parser) parseTopStmt() ast.Statement {
 	switch p.current.Typ {
 	case token.Namespace:
 		// TODO check that this comes before anything but a declare statement
-		p.expect(token.Identifier)
-		p.namespace = ast.NewNamespace(p.current.Val)
-		p.file.Namespace = p.namespace
-		p.expectStmtEnd()
-		return nil
-	case token.Use:
-		p.expect(token.Identifier)
-		if p.peek().Typ == token.AsOperator {
-			p.expect(token.AsOperator)
+		stmt := &ast.NamespaceStmt{Imports: ast.NewImports()}
+		if p.peek().Typ != token.BlockBegin {
+			// only a namespace declared with a block may be unnamed
 			p.expect(token.Identifier)
+			stmt.Name = p.current.Val
+		}
+		p.namespace = p.FileSet.GlobalNamespace
+		if stmt.Name != "" {
+			p.namespace = ast.NewNamespace(stmt.Name)
+		}
+		p.file.Namespace = p.namespace
+		// the imports of a namespace do not apply in the namespaces after it
+		p.file.Imports = stmt.Imports
+		if p.peek().Typ == token.BlockBegin {
+			stmt.Block = p.parseNamespaceBlock()
+			return stmt
 		}
 		p.expectStmtEnd()
-		// We are ignoring this for now
-		return nil
+		return stmt
+	case token.Use:
+		return p.parseUse()
 	case token.Declare:
 		return p.parseDeclareBlock()
 	default:
@@ -30,6 +39,105 @@ func (p *Parser) parseTopStmt() ast.Statement {
 	}
 }
 
+// parseNamespaceBlock parses the block of a namespace, as in
+// namespace Foo { ... }, which may hold anything the top level of a file may.
+func (p *Parser) parseNamespaceBlock() *ast.Block {
+	p.expect(token.BlockBegin)
+	block := &ast.Block{}
+	if !p.disableScoping {
+		block.Scope = p.scope
+	}
+	for {
+		p.next()
+		if p.current.Typ == token.BlockEnd || p.current.Typ == token.EOF {
+			break
+		}
+		if stmt, ok := p.parseNode().(ast.Statement); ok {
+			block.Statements = append(block.Statements, stmt)
+		}
+	}
+	p.expectCurrent(token.BlockEnd)
+	return block
+}
+
+// parseUse parses a use statement, including aliased imports, group uses
+// such as use Foo\{Bar, Baz as Qux}, and use function and use const imports.
+func (p *Parser) parseUse() *ast.UseStmt {
+	stmt := &ast.UseStmt{Kind: p.parseUseKind("")}
+	for {
+		p.expect(token.Identifier)
+		name := strings.TrimPrefix(p.current.Val, "\\")
+		if p.accept(token.BlockBegin) {
+			// name is the prefix shared by each import in the group
+			if len(stmt.Imports) > 0 {
+				p.errorf("a group use must be the only import of its statement")
+			}
+			stmt.Prefix = name
+			for p.peek().Typ != token.BlockEnd {
+				imp := ast.UseImport{Kind: p.parseUseKind("")}
+				p.expect(token.Identifier)
+				imp.Name = p.current.Val
+				p.parseUseAlias(&imp)
+				kind := stmt.Kind
+				if imp.Kind != "" {
+					kind = imp.Kind
+				}
+				p.addImport(kind, name+imp.Name, imp.Alias)
+				stmt.Imports = append(stmt.Imports, imp)
+				if !p.accept(token.Comma) {
+					break
+				}
+			}
+			p.expect(token.BlockEnd)
+			break
+		} else {
+			imp := ast.UseImport{Name: name}
+			p.parseUseAlias(&imp)
+			p.addImport(stmt.Kind, name, imp.Alias)
+			stmt.Imports = append(stmt.Imports, imp)
+		}
+		if !p.accept(token.Comma) {
+			break
+		}
+	}
+	p.expectStmtEnd()
+	return stmt
+}
+
+// parseUseKind parses the function or const keyword marking the kind of the
+// following imports. It returns kind if there is neither.
+func (p *Parser) parseUseKind(kind string) string {
+	if p.accept(token.Function) || p.accept(token.Const) {
+		return strings.ToLower(p.current.Val)
+	}
+	return kind
+}
+
+// parseUseAlias parses the optional alias of an import.
+func (p *Parser) parseUseAlias(imp *ast.UseImport) {
+	if p.accept(token.AsOperator) {
+		p.expect(token.Identifier)
+		imp.Alias = p.current.Val
+	}
+}
+
+// addImport records the import of name in the current namespace of the file,
+// under alias if it is not empty and otherwise under the last segment of
+// name.
+func (p *Parser) addImport(kind, name, alias string) {
+	if alias == "" {
+		alias = name[strings.LastIndex(name, "\\")+1:]
+	}
+	switch kind {
+	case "function":
+		p.file.FunctionUses[strings.ToLower(alias)] = name
+	case "const":
+		p.file.ConstantUses[alias] = name
+	default:
+		p.file.Uses[strings.ToLower(alias)] = name
+	}
+}
+
 func (p *Parser) parseStmt() ast.Statement {
 	switch p.current.Typ {
 	case token.BlockBegin:
@@ -56,11 +164,11 @@ func (p *Parser) parseStmt() ast.Statement {
 		p.expectStmtEnd()
 		return g
 	case token.Static:
-		if p.peek().Typ == token.ScopeResolutionOperator {
-			p.errorf("static keyword outside of class context")
+		if t := p.peek().Typ; t == token.ScopeResolutionOperator || t == token.Function {
+			// late static binding, as in static::f(), or a static closure
 			expr := p.parseExpression()
 			p.expectStmtEnd()
-			return expr
+			return ast.ExprStmt{expr}
 		}
 
 		s := &ast.StaticVariableDeclaration{Declarations: make([]ast.Dynamic, 0)}
@@ -80,13 +188,9 @@ func (p *Parser) parseStmt() ast.Statement {
 			if p.peek().Typ == token.AssignmentOperator {
 				p.expect(token.AssignmentOperator)
 				op := p.current.Val
-				p.expect(token.Null, token.StringLiteral, token.BooleanLiteral, token.NumberLiteral, token.Array)
-				switch p.current.Typ {
-				case token.Array:
-					s.Declarations = append(s.Declarations, &ast.AssignmentExpr{Assignee: v, Value: p.parseArrayDeclaration(), Operator: op})
-				default:
-					s.Declarations = append(s.Declarations, &ast.AssignmentExpr{Assignee: v, Value: p.parseLiteral(), Operator: op})
-				}
+				// the initializer is a constant expression, such as 0, [1, 2]
+				// or self::MAX, and since PHP 8.3 may be any expression
+				s.Declarations = append(s.Declarations, &ast.AssignmentExpr{Begin: v.Begin, Assignee: v, Value: p.parseNextExpression(), Operator: op})
 			} else {
 				s.Declarations = append(s.Declarations, v)
 			}
@@ -101,27 +205,19 @@ func (p *Parser) parseStmt() ast.Statement {
 		expr := ast.ExprStmt{p.parseExpression()}
 		p.expectStmtEnd()
 		return expr
-	case token.Print:
-		requireParen := false
-		if p.peek().Typ == token.OpenParen {
-			p.expect(token.OpenParen)
-			requireParen = true
-		}
-		stmt := ast.Echo(p.parseNextExpression())
-		if requireParen {
-			p.expect(token.CloseParen)
-		}
-		p.expectStmtEnd()
-		return stmt
 	case token.Function:
 		return p.parseFunctionStmt(false)
+	case token.Const:
+		return &ast.ConstantStmt{Constants: p.parseConstants()}
 	case token.PHPEnd:
 		if p.peek().Typ == token.EOF {
 			return nil
 		}
 		var expr ast.Statement
 		if p.accept(token.HTML) {
-			expr = ast.Echo(&ast.Literal{Type: ast.String, Value: p.current.Val})
+			echo := ast.Echo(&ast.Literal{Begin: p.current.Begin, Type: ast.String, Value: p.current.Val})
+			echo.Begin = p.current.Begin
+			expr = echo
 		}
 		p.next()
 		if p.current.Typ != token.EOF {
@@ -129,16 +225,7 @@ func (p *Parser) parseStmt() ast.Statement {
 		}
 		return expr
 	case token.Echo:
-		exprs := []ast.Expr{
-			p.parseNextExpression(),
-		}
-		for p.peek().Typ == token.Comma {
-			p.expect(token.Comma)
-			exprs = append(exprs, p.parseNextExpression())
-		}
-		p.expectStmtEnd()
-		echo := ast.Echo(exprs...)
-		return echo
+		return p.parseEcho()
 	case token.If:
 		return p.parseIf()
 	case token.While:
@@ -151,13 +238,15 @@ func (p *Parser) parseStmt() ast.Statement {
 		return p.parseForeach()
 	case token.Switch:
 		return p.parseSwitch()
-	case token.Abstract, token.Final, token.Class:
+	case token.Abstract, token.Final, token.Readonly, token.Class:
 		return p.parseClass()
 	case token.Interface:
 		return p.parseInterface()
+	case token.Trait:
+		return p.parseTrait()
 	case token.Return:
+		stmt := &ast.ReturnStmt{Begin: p.current.Begin}
 		p.next()
-		stmt := &ast.ReturnStmt{}
 		if p.current.Typ != token.StatementEnd {
 			stmt.Expr = p.parseExpression()
 			p.expectStmtEnd()
@@ -179,6 +268,15 @@ func (p *Parser) parseStmt() ast.Statement {
 			p.expectStmtEnd()
 		}
 		return stmt
+	case token.Goto:
+		p.expect(token.Identifier)
+		stmt := &ast.GotoStmt{Label: p.current.Val}
+		p.expectStmtEnd()
+		return stmt
+	case token.Label:
+		stmt := &ast.LabelStmt{Name: p.current.Val}
+		p.expect(token.TernaryOperator2)
+		return stmt
 	case token.Throw:
 		stmt := ast.ThrowStmt{Expr: p.parseNextExpression()}
 		p.expectStmtEnd()
@@ -195,26 +293,29 @@ func (p *Parser) parseStmt() ast.Statement {
 		p.expectStmtEnd()
 		return stmt
 	case token.Try:
-		stmt := &ast.TryStmt{}
+		stmt := &ast.TryStmt{Begin: p.current.Begin}
 		stmt.TryBlock = p.parseBlock()
-		for p.expect(token.Catch); p.current.Typ == token.Catch; p.next() {
+		for p.accept(token.Catch) {
 			caught := &ast.CatchStmt{}
 			p.expect(token.OpenParen)
-			p.expect(token.Identifier)
+			// several exception types are lexed as a single TypeHint
+			p.expect(token.Identifier, token.TypeHint)
 			caught.CatchType = p.current.Val
-			p.expect(token.VariableOperator)
-			p.expect(token.Identifier)
-			caught.CatchVar = ast.NewVariable(p.current.Val)
+			if p.accept(token.VariableOperator) {
+				begin := p.current.Begin
+				p.expect(token.Identifier)
+				caught.CatchVar = p.newVariable(begin)
+			}
 			p.expect(token.CloseParen)
 			caught.CatchBlock = p.parseBlock()
 			stmt.CatchStmts = append(stmt.CatchStmts, caught)
 		}
-		p.backup()
+		if p.accept(token.Finally) {
+			stmt.FinallyBlock = p.parseBlock()
+		} else if len(stmt.CatchStmts) == 0 {
+			p.errorf("try without catch or finally")
+		}
 		return stmt
-	case token.IgnoreErrorOperator:
-		// Ignore this operator
-		p.next()
-		return p.parseStmt()
 	case token.StatementEnd:
 		// this is an empty statement
 		return &ast.EmptyStatement{}
@@ -229,6 +330,21 @@ func (p *Parser) parseStmt() ast.Statement {
 	}
 }
 
+func (p *Parser) parseEcho() ast.Statement {
+	begin := p.current.Begin
+	exprs := []ast.Expr{
+		p.parseNextExpression(),
+	}
+	for p.peek().Typ == token.Comma {
+		p.expect(token.Comma)
+		exprs = append(exprs, p.parseNextExpression())
+	}
+	p.expectStmtEnd()
+	stmt := ast.Echo(exprs...)
+	stmt.Begin = begin
+	return stmt
+}
+
 func (p *Parser) expectStmtEnd() {
 	if p.peek().Typ != token.PHPEnd {
 		p.expect(token.StatementEnd)
@@ -0,0 +1,194 @@
+// Package duplicates finds names that are declared more than once where PHP
+// requires them to be unique, such as the parameters of a function, the
+// methods of a class or the functions and classes of a file, and conditions
+// repeated among the arms of a match.
+package duplicates
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stephens2424/php/ast"
+	"github.com/stephens2424/php/token"
+)
+
+// Duplicate describes a name that was declared a second time.
+type Duplicate struct {
+	Kind      string // Kind is the kind of declaration, e.g. "parameter", "method" or "match condition".
+	Name      string
+	Original  token.Position
+	Duplicate token.Position
+}
+
+func (d Duplicate) String() string {
+	return fmt.Sprintf("%s:%d: duplicate %s %s (previously declared on line %d)",
+		d.Duplicate.File, d.Duplicate.Line, d.Kind, d.Name, d.Original.Line)
+}
+
+// Find returns every duplicate declaration found in nodes and their
+// descendants.
+func Find(nodes []ast.Node) []Duplicate {
+	found := declarations(nodes)
+	find(nodes, &found)
+	return found
+}
+
+// declarations reports the functions and classes that nodes, the statements
+// of a file, declare again in the same namespace. Their names are
+// case-insensitive, and classes, interfaces and traits share one set of names.
+// Declarations nested in other statements are made only when those run, so
+// they are not compared.
+func declarations(nodes []ast.Node) []Duplicate {
+	var found []Duplicate
+	type declaration struct {
+		function bool
+		name     string
+	}
+	seen := map[declaration]token.Position{}
+	ns := ""
+	var declare func(node ast.Node)
+	declare = func(node ast.Node) {
+		var kind, name string
+		var begin token.Position
+		switch node := node.(type) {
+		case *ast.NamespaceStmt:
+			ns = strings.Trim(node.Name, `\`)
+			if node.Block != nil {
+				for _, stmt := range node.Block.Statements {
+					declare(stmt)
+				}
+				ns = ""
+			}
+			return
+		case *ast.FunctionStmt:
+			kind, name, begin = "function", node.Name, node.Begin
+		case *ast.Class:
+			kind, name, begin = "class", node.Name, node.Begin
+		case *ast.Trait:
+			kind, name, begin = "trait", node.Name, node.Begin
+		case *ast.Interface:
+			kind, name, begin = "interface", node.Name, node.Begin
+		default:
+			return
+		}
+		if ns != "" {
+			name = ns + `\` + name
+		}
+		key := declaration{kind == "function", strings.ToLower(name)}
+		if original, ok := seen[key]; ok {
+			found = append(found, Duplicate{
+				Kind:      kind,
+				Name:      name,
+				Original:  original,
+				Duplicate: begin,
+			})
+			return
+		}
+		seen[key] = begin
+	}
+	for _, node := range nodes {
+		declare(node)
+	}
+	return found
+}
+
+func find(nodes []ast.Node, found *[]Duplicate) {
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		switch node := node.(type) {
+		case *ast.FunctionDefinition:
+			*found = append(*found, arguments(node.Arguments)...)
+		case *ast.AnonymousFunction:
+			*found = append(*found, arguments(node.Arguments)...)
+		case *ast.Class:
+			*found = append(*found, methods(node.Methods)...)
+		case *ast.Trait:
+			*found = append(*found, methods(node.Methods)...)
+		case *ast.MatchExpr:
+			*found = append(*found, matchConditions(node.Arms)...)
+		case *ast.Interface:
+			seen := map[string]*ast.FunctionDefinition{}
+			for _, m := range node.Methods {
+				*found = append(*found, method(seen, m.FunctionDefinition)...)
+			}
+		}
+		find(node.Children(), found)
+	}
+}
+
+func methods(ms []*ast.Method) []Duplicate {
+	var found []Duplicate
+	seen := map[string]*ast.FunctionDefinition{}
+	for _, m := range ms {
+		found = append(found, method(seen, m.FunctionDefinition)...)
+	}
+	return found
+}
+
+func arguments(args []*ast.FunctionArgument) []Duplicate {
+	var found []Duplicate
+	seen := map[string]*ast.FunctionArgument{}
+	for _, arg := range args {
+		name := arg.Variable.String()
+		if original, ok := seen[name]; ok {
+			found = append(found, Duplicate{
+				Kind:      "parameter",
+				Name:      name,
+				Original:  original.Begin,
+				Duplicate: arg.Begin,
+			})
+			continue
+		}
+		seen[name] = arg
+	}
+	return found
+}
+
+// matchConditions reports the conditions of a match that repeat a literal
+// condition of an earlier arm, which can never be chosen.
+func matchConditions(arms []*ast.MatchArm) []Duplicate {
+	var found []Duplicate
+	type literal struct {
+		typ   ast.Type
+		value string
+	}
+	seen := map[literal]*ast.Literal{}
+	for _, arm := range arms {
+		for _, c := range arm.Conditions {
+			lit, ok := c.(*ast.Literal)
+			if !ok {
+				continue
+			}
+			key := literal{lit.Type, lit.Value}
+			if original, ok := seen[key]; ok {
+				found = append(found, Duplicate{
+					Kind:      "match condition",
+					Name:      lit.Value,
+					Original:  original.Begin,
+					Duplicate: lit.Begin,
+				})
+				continue
+			}
+			seen[key] = lit
+		}
+	}
+	return found
+}
+
+// method records def in seen, reporting it if a method of the same name has
+// already been seen. Method names are case-insensitive.
+func method(seen map[string]*ast.FunctionDefinition, def *ast.FunctionDefinition) []Duplicate {
+	name := strings.ToLower(def.Name)
+	if original, ok := seen[name]; ok {
+		return []Duplicate{{
+			Kind:      "method",
+			Name:      def.Name,
+			Original:  original.Begin,
+			Duplicate: def.Begin,
+		}}
+	}
+	seen[name] = def
+	return nil
+}
@@ -0,0 +1,133 @@
+package duplicates
+
+import (
+	"testing"
+
+	"github.com/stephens2424/php/parser"
+)
+
+func TestDuplicateParameter(t *testing.T) {
+	src := `<?php
+	function f($a,
+		$a) {
+	}`
+
+	file, err := parser.NewParser().Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups := Find(file.Nodes)
+	if len(dups) != 1 {
+		t.Fatalf("expected one duplicate, found %d", len(dups))
+	}
+	d := dups[0]
+	if d.Kind != "parameter" || d.Name != "$a" {
+		t.Errorf("unexpected duplicate %s", d)
+	}
+	if d.Original.Line != 2 || d.Duplicate.Line != 3 {
+		t.Errorf("duplicate had lines %d and %d, expected 2 and 3", d.Original.Line, d.Duplicate.Line)
+	}
+}
+
+func TestDuplicateMethod(t *testing.T) {
+	src := `<?php
+	class fizz {
+		function buzz() {}
+		function other($b) {}
+		function Buzz() {}
+	}`
+
+	file, err := parser.NewParser().Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups := Find(file.Nodes)
+	if len(dups) != 1 {
+		t.Fatalf("expected one duplicate, found %d", len(dups))
+	}
+	d := dups[0]
+	if d.Kind != "method" || d.Name != "Buzz" {
+		t.Errorf("unexpected duplicate %s", d)
+	}
+	if d.Original.Line != 3 || d.Duplicate.Line != 5 {
+		t.Errorf("duplicate had lines %d and %d, expected 3 and 5", d.Original.Line, d.Duplicate.Line)
+	}
+}
+
+func TestDuplicateMatchCondition(t *testing.T) {
+	src := `<?php
+	$size = match ($n) {
+		1, 2 => "small",
+		3, $limit => "medium",
+		$other,
+			2 => "tiny",
+		$limit => "large",
+		default => "huge",
+	};`
+
+	file, err := parser.NewParser().Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups := Find(file.Nodes)
+	if len(dups) != 1 {
+		t.Fatalf("expected one duplicate, found %d", len(dups))
+	}
+	d := dups[0]
+	if d.Kind != "match condition" || d.Name != "2" {
+		t.Errorf("unexpected duplicate %s", d)
+	}
+	if d.Original.Line != 3 || d.Duplicate.Line != 6 {
+		t.Errorf("duplicate had lines %d and %d, expected 3 and 6", d.Original.Line, d.Duplicate.Line)
+	}
+	if d.Original.Column != 6 {
+		t.Errorf("original condition at column %d, expected 6", d.Original.Column)
+	}
+}
+
+func TestDuplicateDeclaration(t *testing.T) {
+	src := `<?php
+	namespace A {
+		function f() {}
+		class Fizz {}
+		if (true) {
+			function g() {}
+		}
+		function g() {}
+		interface fizz {}
+		function F() {}
+	}
+	namespace B {
+		function f() {}
+		class Fizz {}
+	}`
+
+	file, err := parser.NewParser().Parse("test.php", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dups := Find(file.Nodes)
+	if len(dups) != 2 {
+		t.Fatalf("expected two duplicates, found %d: %v", len(dups), dups)
+	}
+	expected := []struct {
+		kind, name          string
+		original, duplicate int
+	}{
+		{"interface", `A\fizz`, 4, 9},
+		{"function", `A\F`, 3, 10},
+	}
+	for i, e := range expected {
+		d := dups[i]
+		if d.Kind != e.kind || d.Name != e.name {
+			t.Errorf("unexpected duplicate %s", d)
+		}
+		if d.Original.Line != e.original || d.Duplicate.Line != e.duplicate {
+			t.Errorf("duplicate had lines %d and %d, expected %d and %d", d.Original.Line, d.Duplicate.Line, e.original, e.duplicate)
+		}
+	}
+}
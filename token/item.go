@@ -27,6 +27,45 @@ func (i Item) Position() Position {
 	return i.Begin
 }
 
+// Equal returns true if i and o have the same type and value. Their positions
+// are not compared; use EqualPosition for that.
+func (i Item) Equal(o Item) bool {
+	return i.Typ == o.Typ && i.Val == o.Val
+}
+
+// EqualPosition returns true if i and o are Equal and begin and end at the
+// same positions.
+func (i Item) EqualPosition(o Item) bool {
+	return i.Equal(o) && i.Begin == o.Begin && i.End == o.End
+}
+
+// TokensEqual returns true if a and b are the same length and their items are
+// pairwise Equal.
+func TokensEqual(a, b []Item) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterTrivia returns the items that are not Trivia, in order. Trivia is
+// whitespace and comments of any kind, including doc comments; EOF and Error
+// items are kept.
+func FilterTrivia(items []Item) []Item {
+	filtered := make([]Item, 0, len(items))
+	for _, i := range items {
+		if !i.Typ.Type().Is(Trivia) {
+			filtered = append(filtered, i)
+		}
+	}
+	return filtered
+}
+
 // String renders a string representation of the item.
 func (i Item) String() string {
 	switch i.Typ {
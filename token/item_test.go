@@ -0,0 +1,40 @@
+package token
+
+import "testing"
+
+func TestItemEqual(t *testing.T) {
+	a := []Item{
+		{Typ: VariableOperator, Val: "$", Begin: Position{Line: 1, Position: 6}, End: Position{Line: 1, Position: 7}},
+		{Typ: Identifier, Val: "a", Begin: Position{Line: 1, Position: 7}, End: Position{Line: 1, Position: 8}},
+	}
+
+	same := append([]Item{}, a...)
+	if !TokensEqual(a, same) {
+		t.Errorf("equal streams compared unequal")
+	}
+	if !a[1].EqualPosition(same[1]) {
+		t.Errorf("equal items compared unequal with positions")
+	}
+
+	value := append([]Item{}, a...)
+	value[1].Val = "b"
+	if TokensEqual(a, value) {
+		t.Errorf("streams differing in value compared equal")
+	}
+	if a[1].EqualPosition(value[1]) {
+		t.Errorf("items differing in value compared equal with positions")
+	}
+
+	moved := append([]Item{}, a...)
+	moved[1].Begin.Line, moved[1].End.Line = 2, 2
+	if !TokensEqual(a, moved) {
+		t.Errorf("streams differing only in position compared unequal")
+	}
+	if a[1].EqualPosition(moved[1]) {
+		t.Errorf("items differing in position compared equal with positions")
+	}
+
+	if TokensEqual(a, a[:1]) {
+		t.Errorf("streams of different lengths compared equal")
+	}
+}
@@ -0,0 +1,47 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// jsonItem is the JSON representation of an Item.
+type jsonItem struct {
+	Type     string       `json:"type"`
+	Value    string       `json:"value"`
+	Location jsonLocation `json:"location"`
+}
+
+type jsonLocation struct {
+	Pos    int    `json:"pos"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	File   string `json:"file"`
+}
+
+// JSON consumes s up to its end and encodes the items read as a JSON array.
+// Each element is an object with the item's type name, as given by
+// PHPTokenName, its value and the location it begins at:
+//
+//	{"type": "T_STRING", "value": "foo", "location": {"pos": 6, "line": 1, "column": 7, "file": ""}}
+//
+// If s yields an Error item, JSON returns its message as the error.
+func JSON(s Stream) ([]byte, error) {
+	items := []jsonItem{}
+	for i := s.Next(); i.Typ != EOF; i = s.Next() {
+		if i.Typ == Error {
+			return nil, errors.New(i.Val)
+		}
+		items = append(items, jsonItem{
+			Type:  i.PHPTokenName(),
+			Value: i.Val,
+			Location: jsonLocation{
+				Pos:    i.Begin.Position,
+				Line:   i.Begin.Line,
+				Column: i.Begin.Column,
+				File:   i.Begin.File,
+			},
+		})
+	}
+	return json.Marshal(items)
+}
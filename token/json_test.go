@@ -0,0 +1,73 @@
+package token_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stephens2424/php/lexer"
+	"github.com/stephens2424/php/token"
+)
+
+func TestJSON(t *testing.T) {
+	b, err := token.JSON(lexer.NewLexer("<?php\necho $a;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(b, &items); err != nil {
+		t.Fatal(err)
+	}
+
+	loc := func(pos, line, column int) map[string]interface{} {
+		return map[string]interface{}{
+			"pos":    float64(pos),
+			"line":   float64(line),
+			"column": float64(column),
+			"file":   "",
+		}
+	}
+	expected := []map[string]interface{}{
+		{"type": "T_OPEN_TAG", "value": "<?php", "location": loc(0, 1, 1)},
+		{"type": "T_WHITESPACE", "value": "\n", "location": loc(5, 1, 6)},
+		{"type": "T_ECHO", "value": "echo", "location": loc(6, 2, 1)},
+		{"type": "T_WHITESPACE", "value": " ", "location": loc(10, 2, 5)},
+		{"type": "$", "value": "$", "location": loc(11, 2, 6)},
+		{"type": "T_STRING", "value": "a", "location": loc(12, 2, 7)},
+		{"type": ";", "value": ";", "location": loc(13, 2, 8)},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected JSON %s", b)
+	}
+}
+
+func TestJSONUnnamedTypes(t *testing.T) {
+	b, err := token.JSON(token.Subset(lexer.NewLexer("<?php function f(?int &$x) {}"), token.Significant))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []struct{ Type, Value string }
+	if err := json.Unmarshal(b, &items); err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]string{}
+	for _, i := range items {
+		types[i.Value] = i.Type
+	}
+	// PHP has no name for a nullable type, which is named by its token type
+	// rather than its text
+	if types["?int"] != token.TypeHint.String() {
+		t.Errorf("?int has type %q, expected %q", types["?int"], token.TypeHint.String())
+	}
+	if types["&"] != "&" {
+		t.Errorf("& has type %q, expected &", types["&"])
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	b, err := token.JSON(lexer.NewLexer("<?php $a = 1 \x01 2;"))
+	if err == nil {
+		t.Fatalf("expected an error, found %s", b)
+	}
+}
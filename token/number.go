@@ -0,0 +1,49 @@
+package token
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// NumberValue returns the value of a NumberLiteral item with any digit
+// separators removed, e.g. "1_000" becomes "1000". Val keeps the literal as
+// it appeared in the source.
+func (i Item) NumberValue() string {
+	return strings.Replace(i.Val, "_", "", -1)
+}
+
+// Base returns the base of the integer in a NumberLiteral item: 2 for 0b1010,
+// 8 for 0755 and 0o755, 16 for 0x1A, and 10 otherwise. Floats are base 10.
+func (i Item) Base() int {
+	v := strings.ToLower(i.Val)
+	switch {
+	case strings.HasPrefix(v, "0x"):
+		return 16
+	case strings.HasPrefix(v, "0b"):
+		return 2
+	case strings.HasPrefix(v, "0o"):
+		return 8
+	case len(v) > 1 && v[0] == '0' && !strings.ContainsAny(v, ".e"):
+		return 8
+	}
+	return 10
+}
+
+// Int returns the value of the integer in a NumberLiteral item. If the item
+// is a float the error wraps strconv.ErrSyntax, and if the integer does not
+// fit in 64 bits it wraps strconv.ErrRange.
+func (i Item) Int() (int64, error) {
+	v, base := i.NumberValue(), i.Base()
+	if base != 10 && strings.ContainsAny(v[:2], "xXbBoO") {
+		v = v[2:]
+	}
+	return strconv.ParseInt(v, base, 64)
+}
+
+// Overflows reports whether the integer in a NumberLiteral item is larger than
+// PHP_INT_MAX, in which case PHP silently reads it as a float.
+func (i Item) Overflows() bool {
+	_, err := i.Int()
+	return errors.Is(err, strconv.ErrRange)
+}
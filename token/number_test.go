@@ -0,0 +1,86 @@
+package token
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNumberValue(t *testing.T) {
+	tests := map[string]string{
+		"1_000_000":   "1000000",
+		"0xCA_FE":     "0xCAFE",
+		"0b1010_0101": "0b10100101",
+		"42":          "42",
+	}
+	for lit, expected := range tests {
+		if v := NewItem(NumberLiteral, lit).NumberValue(); v != expected {
+			t.Errorf("NumberValue of %q was %q, expected %q", lit, v, expected)
+		}
+	}
+}
+
+func TestBase(t *testing.T) {
+	tests := map[string]int{
+		"0x1A":    16,
+		"0XCA_FE": 16,
+		"0b1010":  2,
+		"0B1":     2,
+		"0755":    8,
+		"0o755":   8,
+		"0O17":    8,
+		"0":       10,
+		"42":      10,
+		"0.5":     10,
+		"07.5":    10,
+	}
+	for lit, expected := range tests {
+		if b := NewItem(NumberLiteral, lit).Base(); b != expected {
+			t.Errorf("Base of %q was %d, expected %d", lit, b, expected)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := map[string]int64{
+		"42":                  42,
+		"1_000":               1000,
+		"0x1A":                26,
+		"0b101":               5,
+		"0755":                493,
+		"0o17":                15,
+		"0":                   0,
+		"9223372036854775807": 9223372036854775807,
+		"0x7FFFFFFFFFFFFFFF":  9223372036854775807,
+	}
+	for lit, expected := range tests {
+		i := NewItem(NumberLiteral, lit)
+		if n, err := i.Int(); err != nil || n != expected {
+			t.Errorf("Int of %q was %d, %v, expected %d", lit, n, err, expected)
+		}
+		if i.Overflows() {
+			t.Errorf("%q overflows", lit)
+		}
+	}
+
+	for _, lit := range []string{"9223372036854775808", "0x8000000000000000", "0b1" + strings.Repeat("0", 63), "01000000000000000000000"} {
+		i := NewItem(NumberLiteral, lit)
+		if _, err := i.Int(); !errors.Is(err, strconv.ErrRange) {
+			t.Errorf("Int of %q returned %v, expected it out of range", lit, err)
+		}
+		if !i.Overflows() {
+			t.Errorf("%q does not overflow", lit)
+		}
+	}
+
+	for _, lit := range []string{"1.5", "1e3", "9223372036854775808.0"} {
+		i := NewItem(NumberLiteral, lit)
+		if _, err := i.Int(); !errors.Is(err, strconv.ErrSyntax) {
+			t.Errorf("Int of float %q returned %v", lit, err)
+		}
+		if i.Overflows() {
+			t.Errorf("float %q overflows", lit)
+		}
+	}
+}
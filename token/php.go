@@ -0,0 +1,201 @@
+package token
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PHPTokenName returns the name PHP's own tokenizer (token_get_all and
+// token_name) gives to the token i was lexed from, e.g. "T_VARIABLE" or
+// "T_IS_IDENTICAL". Tokens PHP represents by a single character are named by
+// that character. Because this package lexes $ separately from the variable
+// name, a VariableOperator is named "$". Any other token PHP has no name
+// for, such as an Error or a TypeHint, is named by its type's String, never
+// by the text it was lexed from.
+func (i Item) PHPTokenName() string {
+	switch i.Typ {
+	case HTML:
+		return "T_INLINE_HTML"
+	case PHPBegin:
+		if i.Val == "<?=" {
+			return "T_OPEN_TAG_WITH_ECHO"
+		}
+		return "T_OPEN_TAG"
+	case PHPEnd:
+		return "T_CLOSE_TAG"
+	case Space:
+		return "T_WHITESPACE"
+	case CommentLine, CommentBlock:
+		if strings.HasPrefix(i.Val, "/**") {
+			return "T_DOC_COMMENT"
+		}
+		return "T_COMMENT"
+	case Attribute:
+		return "T_ATTRIBUTE"
+	case Identifier, This:
+		switch {
+		case strings.HasPrefix(i.Val, "\\"):
+			return "T_NAME_FULLY_QUALIFIED"
+		case strings.HasPrefix(strings.ToLower(i.Val), "namespace\\"):
+			return "T_NAME_RELATIVE"
+		case strings.Contains(i.Val, "\\"):
+			return "T_NAME_QUALIFIED"
+		}
+		return "T_STRING"
+	case BooleanLiteral, Null, Self, Parent, Label, ArgumentName:
+		return "T_STRING"
+	case NumberLiteral:
+		if i.Base() == 10 && strings.ContainsAny(i.Val, ".eE") {
+			return "T_DNUMBER"
+		}
+		return "T_LNUMBER"
+	case StringLiteral, SingleQuotedString, DoubleQuotedString:
+		return "T_CONSTANT_ENCAPSED_STRING"
+	case Heredoc, Nowdoc:
+		return "T_START_HEREDOC"
+	case ShellCommand:
+		return "`"
+	case YieldFrom:
+		return "T_YIELD_FROM"
+	case CastOperator:
+		// a cast may have spaces inside its parentheses, as in ( int )
+		return phpTokenNames[strings.ToLower(strings.Join(strings.Fields(i.Val), ""))]
+	}
+	if name, ok := phpTokenNames[strings.ToLower(i.Val)]; ok {
+		return name
+	}
+	if i.Typ != Error && isPunctuation(i.Val) {
+		return i.Val
+	}
+	return i.Typ.String()
+}
+
+// isPunctuation reports whether s is a single character that is neither a
+// letter nor a digit, as the tokens PHP names by their text are.
+func isPunctuation(s string) bool {
+	r, size := utf8.DecodeRuneInString(s)
+	return size > 0 && size == len(s) && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+var phpTokenNames = map[string]string{
+	"abstract":     "T_ABSTRACT",
+	"and":          "T_LOGICAL_AND",
+	"array":        "T_ARRAY",
+	"as":           "T_AS",
+	"break":        "T_BREAK",
+	"case":         "T_CASE",
+	"catch":        "T_CATCH",
+	"class":        "T_CLASS",
+	"clone":        "T_CLONE",
+	"const":        "T_CONST",
+	"continue":     "T_CONTINUE",
+	"declare":      "T_DECLARE",
+	"default":      "T_DEFAULT",
+	"die":          "T_EXIT",
+	"do":           "T_DO",
+	"echo":         "T_ECHO",
+	"else":         "T_ELSE",
+	"elseif":       "T_ELSEIF",
+	"empty":        "T_EMPTY",
+	"enddeclare":   "T_ENDDECLARE",
+	"endfor":       "T_ENDFOR",
+	"endforeach":   "T_ENDFOREACH",
+	"endif":        "T_ENDIF",
+	"endswitch":    "T_ENDSWITCH",
+	"endwhile":     "T_ENDWHILE",
+	"exit":         "T_EXIT",
+	"extends":      "T_EXTENDS",
+	"final":        "T_FINAL",
+	"finally":      "T_FINALLY",
+	"for":          "T_FOR",
+	"foreach":      "T_FOREACH",
+	"function":     "T_FUNCTION",
+	"global":       "T_GLOBAL",
+	"goto":         "T_GOTO",
+	"if":           "T_IF",
+	"implements":   "T_IMPLEMENTS",
+	"include":      "T_INCLUDE",
+	"include_once": "T_INCLUDE_ONCE",
+	"instanceof":   "T_INSTANCEOF",
+	"interface":    "T_INTERFACE",
+	"isset":        "T_ISSET",
+	"list":         "T_LIST",
+	"namespace":    "T_NAMESPACE",
+	"new":          "T_NEW",
+	"or":           "T_LOGICAL_OR",
+	"print":        "T_PRINT",
+	"private":      "T_PRIVATE",
+	"protected":    "T_PROTECTED",
+	"public":       "T_PUBLIC",
+	"readonly":     "T_READONLY",
+	"require":      "T_REQUIRE",
+	"require_once": "T_REQUIRE_ONCE",
+	"return":       "T_RETURN",
+	"static":       "T_STATIC",
+	"switch":       "T_SWITCH",
+	"throw":        "T_THROW",
+	"trait":        "T_TRAIT",
+	"try":          "T_TRY",
+	"unset":        "T_UNSET",
+	"use":          "T_USE",
+	"var":          "T_VAR",
+	"while":        "T_WHILE",
+	"xor":          "T_LOGICAL_XOR",
+	"yield":        "T_YIELD",
+
+	"__line__":      "T_LINE",
+	"__file__":      "T_FILE",
+	"__dir__":       "T_DIR",
+	"__function__":  "T_FUNC_C",
+	"__class__":     "T_CLASS_C",
+	"__method__":    "T_METHOD_C",
+	"__namespace__": "T_NS_C",
+	"__trait__":     "T_TRAIT_C",
+
+	"(int)":     "T_INT_CAST",
+	"(integer)": "T_INT_CAST",
+	"(bool)":    "T_BOOL_CAST",
+	"(boolean)": "T_BOOL_CAST",
+	"(float)":   "T_DOUBLE_CAST",
+	"(double)":  "T_DOUBLE_CAST",
+	"(real)":    "T_DOUBLE_CAST",
+	"(string)":  "T_STRING_CAST",
+	"(array)":   "T_ARRAY_CAST",
+	"(object)":  "T_OBJECT_CAST",
+	"(unset)":   "T_UNSET_CAST",
+
+	"->":  "T_OBJECT_OPERATOR",
+	"?->": "T_NULLSAFE_OBJECT_OPERATOR",
+	"::":  "T_DOUBLE_COLON",
+	"=>":  "T_DOUBLE_ARROW",
+	"+=":  "T_PLUS_EQUAL",
+	"-=":  "T_MINUS_EQUAL",
+	"*=":  "T_MUL_EQUAL",
+	"/=":  "T_DIV_EQUAL",
+	".=":  "T_CONCAT_EQUAL",
+	"%=":  "T_MOD_EQUAL",
+	"&=":  "T_AND_EQUAL",
+	"|=":  "T_OR_EQUAL",
+	"^=":  "T_XOR_EQUAL",
+	"<<=": "T_SL_EQUAL",
+	">>=": "T_SR_EQUAL",
+	"??=": "T_COALESCE_EQUAL",
+	"...": "T_ELLIPSIS",
+	"===": "T_IS_IDENTICAL",
+	"==":  "T_IS_EQUAL",
+	"!==": "T_IS_NOT_IDENTICAL",
+	"!=":  "T_IS_NOT_EQUAL",
+	"<>":  "T_IS_NOT_EQUAL",
+	"<=>": "T_SPACESHIP",
+	"??":  "T_COALESCE",
+	"<=":  "T_IS_SMALLER_OR_EQUAL",
+	">=":  "T_IS_GREATER_OR_EQUAL",
+	"++":  "T_INC",
+	"--":  "T_DEC",
+	"&&":  "T_BOOLEAN_AND",
+	"||":  "T_BOOLEAN_OR",
+	"|>":  "T_PIPE",
+	"<<":  "T_SL",
+	">>":  "T_SR",
+}
@@ -18,6 +18,7 @@ const (
 	Function
 	Static
 	Self
+	This
 	Parent
 	Final
 	FunctionName
@@ -25,6 +26,7 @@ const (
 	VariableOperator
 	BlockBegin
 	BlockEnd
+	AlternateBlockBegin
 	Global
 
 	Namespace
@@ -32,6 +34,7 @@ const (
 
 	CommentLine
 	CommentBlock
+	Attribute
 
 	IgnoreErrorOperator
 
@@ -40,6 +43,9 @@ const (
 	StatementEnd
 	Echo
 	Print
+	Isset
+	Empty
+	Unset
 
 	If
 	Else
@@ -51,6 +57,7 @@ const (
 	EndForeach
 	EndWhile
 	EndSwitch
+	EndDeclare
 	AsOperator
 	While
 	Continue
@@ -72,16 +79,25 @@ const (
 	Private
 	Public
 	Protected
+	Readonly
 	Interface
+	Trait
 	Implements
 	Extends
 	NewOperator
 	Const
 
 	Null
+	// StringLiteral is any string literal. The lexer emits one of the
+	// kinds following it instead; see IsString.
 	StringLiteral
+	SingleQuotedString
+	DoubleQuotedString
+	Heredoc
+	Nowdoc
 	NumberLiteral
 	BooleanLiteral
+	MagicConstant
 
 	ShellCommand
 
@@ -104,6 +120,7 @@ const (
 	WrittenOrOperator
 
 	ObjectOperator
+	NullsafeObjectOperator
 	ScopeResolutionOperator
 
 	CastOperator
@@ -113,6 +130,10 @@ const (
 	ArrayKeyOperator
 	ArrayLookupOperatorLeft
 	ArrayLookupOperatorRight
+	CurlyLookupOperatorLeft
+	CurlyLookupOperatorRight
+	ShortArrayLeft
+	ShortArrayRight
 	List
 	BitwiseShiftOperator
 	StrongEqualityOperator
@@ -120,16 +141,27 @@ const (
 	EqualityOperator
 	NotEqualityOperator
 	AmpersandOperator
+	ReferenceOperator
 	BitwiseXorOperator
 	BitwiseOrOperator
 	BitwiseNotOperator
+	PipeOperator
 	TernaryOperator1
 	TernaryOperator2
+	ElvisOperator
+	CoalesceOperator
+	Ellipsis
 
 	Declare
+	Goto
+	Label
+	ArgumentName
 
 	Include
 	Exit
+	Yield
+	YieldFrom
+	Clone
 
 	maxToken
 )
@@ -145,6 +177,7 @@ var tokens = []string{
 	Function:         "Function",
 	Static:           "static",
 	Self:             "self",
+	This:             "this",
 	Parent:           "parent",
 	Final:            "final",
 	FunctionName:     "Function Name",
@@ -153,12 +186,17 @@ var tokens = []string{
 	BlockBegin:       "Block Begin",
 	BlockEnd:         "Block End",
 
+	AlternateBlockBegin: "Alternate Block Begin",
+
 	Global:       "global",
 	Return:       "Return",
 	Comma:        "Function Argument Separator",
 	StatementEnd: ";",
 	Echo:         "echo",
 	Print:        "Print",
+	Isset:        "isset",
+	Empty:        "empty",
+	Unset:        "unset",
 
 	Namespace: "namespace",
 	Use:       "use",
@@ -173,6 +211,7 @@ var tokens = []string{
 	EndForeach: "EndForeach",
 	EndWhile:   "EndWhile",
 	EndSwitch:  "EndSwitch",
+	EndDeclare: "EndDeclare",
 	Var:        "var",
 
 	For:        "for",
@@ -191,6 +230,7 @@ var tokens = []string{
 
 	CommentBlock: "/* */",
 	CommentLine:  "//",
+	Attribute:    "#[]",
 
 	Try:     "try",
 	Catch:   "catch",
@@ -203,15 +243,22 @@ var tokens = []string{
 	Private:     "Private",
 	Protected:   "Protected",
 	Public:      "Public",
+	Readonly:    "readonly",
 	Interface:   "Interface",
+	Trait:       "trait",
 	Implements:  "implements",
 	Extends:     "extends",
 	NewOperator: "new",
 
-	ShellCommand:   "`",
-	StringLiteral:  "string-literal",
-	NumberLiteral:  "number-literal",
-	BooleanLiteral: "bool-literal",
+	ShellCommand:       "`",
+	StringLiteral:      "string-literal",
+	SingleQuotedString: "single-quoted-string",
+	DoubleQuotedString: "double-quoted-string",
+	Heredoc:            "heredoc",
+	Nowdoc:             "nowdoc",
+	NumberLiteral:      "number-literal",
+	BooleanLiteral:     "bool-literal",
+	MagicConstant:      "magic-constant",
 
 	Identifier: "identifier",
 
@@ -224,6 +271,7 @@ var tokens = []string{
 	UnaryOperator:             "++|--",
 	ComparisonOperator:        "==<>",
 	ObjectOperator:            "->",
+	NullsafeObjectOperator:    "?->",
 	ScopeResolutionOperator:   "::",
 	InstanceofOperator:        "instanceof",
 	StrongNotEqualityOperator: "!==",
@@ -242,41 +290,101 @@ var tokens = []string{
 	ArrayKeyOperator:         "=>",
 	ArrayLookupOperatorLeft:  "[",
 	ArrayLookupOperatorRight: "]",
+	CurlyLookupOperatorLeft:  "curly-lookup-left",
+	CurlyLookupOperatorRight: "curly-lookup-right",
+	ShortArrayLeft:           "short-array-left",
+	ShortArrayRight:          "short-array-right",
 	BitwiseShiftOperator:     "<<>>",
 	EqualityOperator:         "!===",
 	AmpersandOperator:        "&",
+	ReferenceOperator:        "reference",
 	BitwiseXorOperator:       "^",
 	BitwiseOrOperator:        "|",
 	BitwiseNotOperator:       "~",
+	PipeOperator:             "|>",
 	TernaryOperator1:         "?",
 	TernaryOperator2:         ":",
+	ElvisOperator:            "?:",
+	CoalesceOperator:         "??",
+	Ellipsis:                 "...",
 
-	Include: "include",
-	Exit:    "exit",
+	Include:   "include",
+	Exit:      "exit",
+	Yield:     "yield",
+	YieldFrom: "yield from",
+	Clone:     "clone",
 
 	Declare: "declare",
+	Goto:    "goto",
+	Label:   "label",
+
+	ArgumentName: "argument-name",
 }
 
+// TokenList lists the strings in TokenMap longest first, so that a string
+// appears before any shorter string that is a prefix of it. Strings of equal
+// length are sorted lexicographically.
 var TokenList []string
 
 func init() {
-	TokenList = make([]string, len(TokenMap))
-	i := 0
+	TokenList = make([]string, 0, len(TokenMap))
 	for token := range TokenMap {
-		TokenList[i] = token
-		i += 1
+		TokenList = append(TokenList, token)
+	}
+	sort.Slice(TokenList, func(i, j int) bool {
+		if len(TokenList[i]) != len(TokenList[j]) {
+			return len(TokenList[i]) > len(TokenList[j])
+		}
+		return TokenList[i] < TokenList[j]
+	})
+}
+
+// Keywords returns the keywords in TokenMap, sorted.
+func Keywords() []string {
+	keywords := []string{}
+	for s, t := range TokenMap {
+		if t.IsKeyword() && isWord(s) {
+			keywords = append(keywords, s)
+		}
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// Operators returns the operators in TokenMap, sorted.
+func Operators() []string {
+	operators := []string{}
+	for s, t := range TokenMap {
+		if t.IsOperator() {
+			operators = append(operators, s)
+		}
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(TokenList)))
+	sort.Strings(operators)
+	return operators
+}
+
+// isWord returns true if s is made only of letters, underscores and spaces.
+// It excludes the variants of keywords TokenMap uses for lexing, e.g.
+// "endif;".
+func isWord(s string) bool {
+	for _, r := range s {
+		if r != '_' && r != ' ' && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
 }
 
 // TokenMap maps source code string tokens to  types when strings can
 // be represented directly. Not all  types will be represented here.
+// Keys are lowercase, as PHP keywords are matched case-insensitively.
 var TokenMap = map[string]Token{
 	"class":        Class,
-	"clone":        UnaryOperator,
+	"clone":        Clone,
 	"const":        Const,
 	"abstract":     Abstract,
 	"interface":    Interface,
+	"trait":        Trait,
 	"implements":   Implements,
 	"extends":      Extends,
 	"new":          NewOperator,
@@ -291,12 +399,15 @@ var TokenMap = map[string]Token{
 	"endif;":       EndIf,
 	"endif":        EndIf,
 	"endfor;":      EndFor,
+	"endfor":       EndFor,
 	"endforeach;":  EndForeach,
 	"endforeach":   EndForeach,
 	"endwhile;":    EndWhile,
 	"endwhile":     EndWhile,
 	"endswitch;":   EndSwitch,
 	"endswitch":    EndSwitch,
+	"enddeclare;":  EndDeclare,
+	"enddeclare":   EndDeclare,
 	"case":         Case,
 	"break":        Break,
 	"continue":     Continue,
@@ -321,23 +432,37 @@ var TokenMap = map[string]Token{
 	"finally":      Finally,
 	"private":      Private,
 	"public":       Public,
+	"readonly":     Readonly,
 	"protected":    Protected,
 	"true":         BooleanLiteral,
 	"false":        BooleanLiteral,
 	"instanceof":   InstanceofOperator,
 	"global":       Global,
 	"list":         List,
+	"isset":        Isset,
+	"empty":        Empty,
+	"unset":        Unset,
 	"array":        Array,
 	"exit":         Exit,
+	"die":          Exit,
+	"yield":        Yield,
 	"include":      Include,
 	"include_once": Include,
 	"require":      Include,
 	"require_once": Include,
 	"@":            IgnoreErrorOperator,
 	"null":         Null,
-	"NULL":         Null,
 	"var":          Var,
 
+	"__line__":      MagicConstant,
+	"__file__":      MagicConstant,
+	"__dir__":       MagicConstant,
+	"__function__":  MagicConstant,
+	"__class__":     MagicConstant,
+	"__method__":    MagicConstant,
+	"__namespace__": MagicConstant,
+	"__trait__":     MagicConstant,
+
 	"use":       Use,
 	"namespace": Namespace,
 
@@ -358,8 +483,9 @@ var TokenMap = map[string]Token{
 	"//": CommentLine,
 	"#":  CommentLine,
 
-	"->": ObjectOperator,
-	"::": ScopeResolutionOperator,
+	"->":  ObjectOperator,
+	"?->": NullsafeObjectOperator,
+	"::":  ScopeResolutionOperator,
 
 	"+=":  AssignmentOperator,
 	"-=":  AssignmentOperator,
@@ -372,6 +498,7 @@ var TokenMap = map[string]Token{
 	"^=":  AssignmentOperator,
 	"<<=": AssignmentOperator,
 	">>=": AssignmentOperator,
+	"??=": AssignmentOperator,
 	"=>":  ArrayKeyOperator,
 
 	"===": ComparisonOperator,
@@ -389,10 +516,12 @@ var TokenMap = map[string]Token{
 	"/":   MultOperator,
 	">=":  ComparisonOperator,
 	">":   ComparisonOperator,
+	"<=>": ComparisonOperator,
 	"<=":  ComparisonOperator,
 	"<":   ComparisonOperator,
 	"%":   MultOperator,
 	".":   ConcatenationOperator,
+	"...": Ellipsis,
 
 	"&&":  AndOperator,
 	"||":  OrOperator,
@@ -400,10 +529,13 @@ var TokenMap = map[string]Token{
 	"^":   BitwiseXorOperator,
 	"~":   BitwiseNotOperator,
 	"|":   BitwiseOrOperator,
+	"|>":  PipeOperator,
 	"<<":  BitwiseShiftOperator,
 	">>":  BitwiseShiftOperator,
 	"?":   TernaryOperator1,
 	":":   TernaryOperator2,
+	"?:":  ElvisOperator,
+	"??":  CoalesceOperator,
 	"and": WrittenAndOperator,
 	"xor": WrittenXorOperator,
 	"or":  WrittenOrOperator,
@@ -414,12 +546,22 @@ var TokenMap = map[string]Token{
 
 	"$":       VariableOperator,
 	"declare": Declare,
+	"goto":    Goto,
+}
+
+// TokenName returns the name of t, as String does, and whether t has one. It
+// returns false for a value that is not a token.
+func TokenName(t Token) (string, bool) {
+	if t < 0 || int(t) >= len(tokens) || tokens[t] == "" {
+		return "", false
+	}
+	return tokens[t], true
 }
 
+// String returns the name of i, or its number if it has no name.
 func (i Token) String() string {
-	TypeName := tokens[i]
-	if len(TypeName) == 0 {
-		return strconv.Itoa(int(i))
+	if name, ok := TokenName(i); ok {
+		return name
 	}
-	return TypeName
+	return strconv.Itoa(int(i))
 }
@@ -1,7 +1,22 @@
 package token
 
+import "fmt"
+
 type Position struct {
 	Line, Column int // The position relative to other characters in the file
 	Position     int // The position in bytes in the file
 	File         string
 }
+
+// String returns the position as file:line:column, e.g. "file.php:12:5". The
+// column is omitted if it is unknown, as is the file if it has no name.
+func (p Position) String() string {
+	s := fmt.Sprint(p.Line)
+	if p.Column > 0 {
+		s += fmt.Sprintf(":%d", p.Column)
+	}
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
@@ -0,0 +1,19 @@
+package token
+
+import "testing"
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{File: "file.php", Line: 12, Column: 5, Position: 200}, "file.php:12:5"},
+		{Position{File: "file.php", Line: 12}, "file.php:12"},
+		{Position{Line: 12, Column: 5}, "12:5"},
+	}
+	for _, tt := range tests {
+		if s := tt.pos.String(); s != tt.expected {
+			t.Errorf("%#v formatted as %q, expected %q", tt.pos, s, tt.expected)
+		}
+	}
+}
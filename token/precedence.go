@@ -0,0 +1,63 @@
+package token
+
+// precedence ranks the operators by how tightly they bind to their operands.
+// An operator with a higher level binds more tightly. The parser uses this
+// table to build expressions, and Precedence exposes it.
+var precedence = map[Token]int{
+	ArrayLookupOperatorLeft: 20,
+	UnaryOperator:           19,
+	BitwiseNotOperator:      19,
+	CastOperator:            19,
+	InstanceofOperator:      18,
+	NegationOperator:        17,
+	MultOperator:            16,
+	AdditionOperator:        15,
+	SubtractionOperator:     15,
+	ConcatenationOperator:   15,
+
+	BitwiseShiftOperator: 14,
+	PipeOperator:         13,
+	ComparisonOperator:   12,
+	EqualityOperator:     11,
+
+	AmpersandOperator:  10,
+	BitwiseXorOperator: 9,
+	BitwiseOrOperator:  8,
+	AndOperator:        7,
+	OrOperator:         6,
+	CoalesceOperator:   5,
+	TernaryOperator1:   4,
+	TernaryOperator2:   4,
+	ElvisOperator:      4,
+
+	/*
+	   PHP's documentation would have this operator be at 4, but it also notes:
+
+	       Although = has a lower precedence than most other operators, PHP will
+	       still allow expressions similar to the following: if (!$a = foo()), in
+	       which case the return value of foo() is put into $a.
+
+	   Thus, we put it at 18, so that it binds tightly to the variable on its
+	   left. The parser parses the value on its right as far as it would the
+	   operands of the ternary operator.
+	*/
+	AssignmentOperator: 18,
+	WrittenAndOperator: 3,
+	WrittenXorOperator: 2,
+	WrittenOrOperator:  1,
+}
+
+// rightAssociative lists the operators that group from the right, so that
+// $a = $b = $c assigns $c to $b before assigning the result to $a.
+var rightAssociative = map[Token]bool{
+	AssignmentOperator: true,
+	CoalesceOperator:   true,
+}
+
+// Precedence returns the precedence level of the operator t and whether it
+// is right-associative. Operators with a higher level bind more tightly. ok is
+// false if t is not an operator with a precedence.
+func (t Token) Precedence() (level int, rightAssoc bool, ok bool) {
+	level, ok = precedence[t]
+	return level, rightAssociative[t], ok
+}
@@ -0,0 +1,34 @@
+package token
+
+import "testing"
+
+func TestPrecedence(t *testing.T) {
+	mult, _, ok := MultOperator.Precedence()
+	if !ok {
+		t.Fatal("* has no precedence")
+	}
+	add, _, ok := AdditionOperator.Precedence()
+	if !ok {
+		t.Fatal("+ has no precedence")
+	}
+	if mult <= add {
+		t.Errorf("* has precedence %d, which does not outrank + at %d", mult, add)
+	}
+
+	pipe, _, _ := PipeOperator.Precedence()
+	shift, _, _ := BitwiseShiftOperator.Precedence()
+	comparison, _, _ := ComparisonOperator.Precedence()
+	if pipe >= shift || pipe <= comparison {
+		t.Errorf("|> has precedence %d, which is not between << at %d and < at %d", pipe, shift, comparison)
+	}
+
+	if _, right, _ := AssignmentOperator.Precedence(); !right {
+		t.Error("= is not right-associative")
+	}
+	if _, right, _ := SubtractionOperator.Precedence(); right {
+		t.Error("- is right-associative")
+	}
+	if _, _, ok := StatementEnd.Precedence(); ok {
+		t.Error("; has a precedence")
+	}
+}
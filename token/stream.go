@@ -97,6 +97,11 @@ func (s subsetStream) Next() Item {
 	return t
 }
 
+// Unwrap returns the stream s is a subset of.
+func (s subsetStream) Unwrap() Stream {
+	return s.s
+}
+
 func (s subsetStream) Previous() Item {
 	t := s.s.Previous()
 	for !t.Typ.Type().Is(s.t) && !t.Typ.Type().Is(InvalidType) {
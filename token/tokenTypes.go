@@ -18,6 +18,7 @@ const (
 	WhitespaceType
 
 	Significant = KeywordType | LiteralType | MarkerType | OperatorType | IdentifierType
+	Trivia      = CommentType | WhitespaceType
 )
 
 // Is returns true if ty is equal to or contained by t.
@@ -34,6 +35,39 @@ func (t Token) Type() Type {
 	return typ
 }
 
+// IsKeyword returns true if t is a keyword, e.g. if or class.
+func (t Token) IsKeyword() bool {
+	return tokenTypes[t].Is(KeywordType)
+}
+
+// IsOperator returns true if t is an operator, e.g. && or *.
+func (t Token) IsOperator() bool {
+	return tokenTypes[t].Is(OperatorType)
+}
+
+// IsLiteral returns true if t is a literal, e.g. a string, number, boolean or
+// null.
+func (t Token) IsLiteral() bool {
+	return tokenTypes[t].Is(LiteralType)
+}
+
+// IsString returns true if t is a string literal of any kind: single-quoted,
+// double-quoted, heredoc or nowdoc.
+func (t Token) IsString() bool {
+	switch t {
+	case StringLiteral, SingleQuotedString, DoubleQuotedString, Heredoc, Nowdoc:
+		return true
+	}
+	return false
+}
+
+// IsDeprecated returns true if t is only lexed from deprecated syntax, e.g.
+// the braces of a curly brace offset such as $s{0}, which PHP 7.4 deprecated
+// and PHP 8.0 removed.
+func (t Token) IsDeprecated() bool {
+	return t == CurlyLookupOperatorLeft || t == CurlyLookupOperatorRight
+}
+
 var tokenTypes = map[Token]Type{
 	HTML:     LiteralType,
 	PHPBegin: KeywordType,
@@ -48,6 +82,7 @@ var tokenTypes = map[Token]Type{
 	Function:  KeywordType,
 	Static:    KeywordType,
 	Self:      KeywordType,
+	This:      IdentifierType,
 	Parent:    KeywordType,
 	Final:     KeywordType,
 	Global:    KeywordType,
@@ -56,6 +91,9 @@ var tokenTypes = map[Token]Type{
 	Use:       KeywordType,
 	Echo:      KeywordType,
 	Print:     KeywordType,
+	Isset:     KeywordType,
+	Empty:     KeywordType,
+	Unset:     KeywordType,
 
 	FunctionName:     IdentifierType,
 	TypeHint:         IdentifierType,
@@ -64,8 +102,9 @@ var tokenTypes = map[Token]Type{
 	Comma:        MarkerType,
 	StatementEnd: MarkerType,
 
-	BlockBegin: MarkerType,
-	BlockEnd:   MarkerType,
+	BlockBegin:          MarkerType,
+	BlockEnd:            MarkerType,
+	AlternateBlockBegin: MarkerType,
 
 	IgnoreErrorOperator: OperatorType,
 
@@ -91,17 +130,21 @@ var tokenTypes = map[Token]Type{
 	EndForeach: KeywordType,
 	EndWhile:   KeywordType,
 	EndSwitch:  KeywordType,
+	EndDeclare: KeywordType,
 	Var:        KeywordType,
-	StrongEqualityOperator:    KeywordType,
-	StrongNotEqualityOperator: KeywordType,
-	NotEqualityOperator:       KeywordType,
+	StrongEqualityOperator:    OperatorType,
+	StrongNotEqualityOperator: OperatorType,
+	NotEqualityOperator:       OperatorType,
 
 	OpenParen:  MarkerType,
 	CloseParen: MarkerType,
 
-	Null:         IdentifierType,
+	Null:         LiteralType,
 	CommentLine:  CommentType,
 	CommentBlock: CommentType,
+	// the parser does not interpret attributes, which PHP before 8.0 read as
+	// comments
+	Attribute: CommentType,
 
 	Class:       KeywordType,
 	Const:       KeywordType,
@@ -109,15 +152,22 @@ var tokenTypes = map[Token]Type{
 	Private:     KeywordType,
 	Protected:   KeywordType,
 	Public:      KeywordType,
+	Readonly:    KeywordType,
 	Interface:   KeywordType,
+	Trait:       KeywordType,
 	Implements:  KeywordType,
 	Extends:     KeywordType,
 	NewOperator: KeywordType,
 
-	ShellCommand:   LiteralType,
-	StringLiteral:  LiteralType,
-	NumberLiteral:  LiteralType,
-	BooleanLiteral: LiteralType,
+	ShellCommand:       LiteralType,
+	StringLiteral:      LiteralType,
+	SingleQuotedString: LiteralType,
+	DoubleQuotedString: LiteralType,
+	Heredoc:            LiteralType,
+	Nowdoc:             LiteralType,
+	NumberLiteral:      LiteralType,
+	BooleanLiteral:     LiteralType,
+	MagicConstant:      LiteralType,
 
 	Identifier: IdentifierType,
 
@@ -130,6 +180,7 @@ var tokenTypes = map[Token]Type{
 	UnaryOperator:           OperatorType,
 	ComparisonOperator:      OperatorType,
 	ObjectOperator:          OperatorType,
+	NullsafeObjectOperator:  OperatorType,
 	ScopeResolutionOperator: OperatorType,
 	InstanceofOperator:      OperatorType,
 	AndOperator:             OperatorType,
@@ -141,21 +192,37 @@ var tokenTypes = map[Token]Type{
 
 	List:                     KeywordType,
 	Array:                    KeywordType,
-	ArrayKeyOperator:         KeywordType,
+	ArrayKeyOperator:         OperatorType,
 	ArrayLookupOperatorLeft:  MarkerType,
 	ArrayLookupOperatorRight: MarkerType,
+	CurlyLookupOperatorLeft:  MarkerType,
+	CurlyLookupOperatorRight: MarkerType,
+	ShortArrayLeft:           MarkerType,
+	ShortArrayRight:          MarkerType,
 
 	BitwiseShiftOperator: OperatorType,
 	EqualityOperator:     OperatorType,
 	AmpersandOperator:    OperatorType,
+	ReferenceOperator:    OperatorType,
 	BitwiseXorOperator:   OperatorType,
 	BitwiseOrOperator:    OperatorType,
 	BitwiseNotOperator:   OperatorType,
+	PipeOperator:         OperatorType,
 	TernaryOperator1:     OperatorType,
 	TernaryOperator2:     OperatorType,
+	ElvisOperator:        OperatorType,
+	CoalesceOperator:     OperatorType,
+	Ellipsis:             OperatorType,
 
-	Include: KeywordType,
-	Exit:    KeywordType,
+	Include:   KeywordType,
+	Exit:      KeywordType,
+	Yield:     KeywordType,
+	YieldFrom: KeywordType,
+	Clone:     KeywordType,
 
 	Declare: KeywordType,
+	Goto:    KeywordType,
+	Label:   IdentifierType,
+
+	ArgumentName: IdentifierType,
 }
@@ -2,19 +2,54 @@ package token
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
 func TestTokens(t *testing.T) {
-	for i := 0; i < int(maxToken); i++ {
-		if len(tokens)-1 < i {
-			t.Errorf("token %v has no string in tokens slice", i)
+	names := map[string]Token{}
+	for i := Token(0); i < maxToken; i++ {
+		name, ok := TokenName(i)
+		if !ok {
+			t.Errorf("token %v has no string in tokens slice", int(i))
 			continue
 		}
-		str := tokens[i]
-		if str == "" {
-			t.Errorf("token %v has no string in tokens slice", i)
-			continue
+		if _, err := strconv.Atoi(i.String()); err == nil {
+			t.Errorf("token %v is named by a number", int(i))
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("tokens %v and %v are both named %q", int(other), int(i), name)
+		}
+		names[name] = i
+	}
+	if len(tokens) > int(maxToken) {
+		t.Errorf("tokens slice names %d tokens, expected at most %d", len(tokens), int(maxToken))
+	}
+}
+
+func TestTokenName(t *testing.T) {
+	tests := map[Token]string{
+		EOF:              "EOF",
+		PHPBegin:         "PHP Begin",
+		OpenParen:        "open-paren",
+		TernaryOperator1: "?",
+		ElvisOperator:    "?:",
+		ShortArrayLeft:   "short-array-left",
+	}
+	for tok, expected := range tests {
+		if name, ok := TokenName(tok); !ok || name != expected || tok.String() != expected {
+			t.Errorf("TokenName(%d) = %q, %t, expected %q", int(tok), name, ok, expected)
+		}
+	}
+
+	for _, tok := range []Token{maxToken, -1, 10000} {
+		if name, ok := TokenName(tok); ok {
+			t.Errorf("TokenName(%d) = %q, expected no name", int(tok), name)
+		}
+		if s := tok.String(); s != strconv.Itoa(int(tok)) {
+			t.Errorf("token %d printed as %q", int(tok), s)
 		}
 	}
 }
@@ -27,3 +62,123 @@ func TestTokenTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenMapLowercase(t *testing.T) {
+	for s := range TokenMap {
+		if s != strings.ToLower(s) {
+			t.Errorf("token %q is not lowercase and will never be matched", s)
+		}
+	}
+}
+
+func TestTokenClassification(t *testing.T) {
+	tests := []struct {
+		tok                        Token
+		keyword, operator, literal bool
+	}{
+		{If, true, false, false},
+		{Class, true, false, false},
+		{AndOperator, false, true, false},
+		{MultOperator, false, true, false},
+		{StrongEqualityOperator, false, true, false},
+		{ArrayKeyOperator, false, true, false},
+		{StringLiteral, false, false, true},
+		{SingleQuotedString, false, false, true},
+		{DoubleQuotedString, false, false, true},
+		{Heredoc, false, false, true},
+		{Nowdoc, false, false, true},
+		{NumberLiteral, false, false, true},
+		{BooleanLiteral, false, false, true},
+		{Null, false, false, true},
+		{Identifier, false, false, false},
+		{OpenParen, false, false, false},
+	}
+	for _, tt := range tests {
+		if tt.tok.IsKeyword() != tt.keyword {
+			t.Errorf("%s: IsKeyword() = %v", tt.tok, !tt.keyword)
+		}
+		if tt.tok.IsOperator() != tt.operator {
+			t.Errorf("%s: IsOperator() = %v", tt.tok, !tt.operator)
+		}
+		if tt.tok.IsLiteral() != tt.literal {
+			t.Errorf("%s: IsLiteral() = %v", tt.tok, !tt.literal)
+		}
+	}
+}
+
+func TestStringTokens(t *testing.T) {
+	for _, tok := range []Token{StringLiteral, SingleQuotedString, DoubleQuotedString, Heredoc, Nowdoc} {
+		if !tok.IsString() {
+			t.Errorf("%s: IsString() = false", tok)
+		}
+	}
+	for _, tok := range []Token{NumberLiteral, ShellCommand, Identifier} {
+		if tok.IsString() {
+			t.Errorf("%s: IsString() = true", tok)
+		}
+	}
+}
+
+func TestDeprecatedTokens(t *testing.T) {
+	for _, tok := range []Token{CurlyLookupOperatorLeft, CurlyLookupOperatorRight} {
+		if !tok.IsDeprecated() {
+			t.Errorf("%s: IsDeprecated() = false", tok)
+		}
+	}
+	for _, tok := range []Token{ArrayLookupOperatorLeft, ArrayLookupOperatorRight, BlockBegin, BlockEnd} {
+		if tok.IsDeprecated() {
+			t.Errorf("%s: IsDeprecated() = true", tok)
+		}
+	}
+}
+
+func TestKeywordsAndOperators(t *testing.T) {
+	keywords := Keywords()
+	if !sort.StringsAreSorted(keywords) {
+		t.Errorf("keywords are not sorted: %v", keywords)
+	}
+	for _, k := range []string{"function", "class"} {
+		if !contains(keywords, k) {
+			t.Errorf("keywords do not contain %q", k)
+		}
+	}
+	for _, k := range []string{"endif;", "&&", "__class__"} {
+		if contains(keywords, k) {
+			t.Errorf("keywords contain %q", k)
+		}
+	}
+
+	operators := Operators()
+	if !sort.StringsAreSorted(operators) {
+		t.Errorf("operators are not sorted: %v", operators)
+	}
+	for _, o := range []string{"&&", "=>"} {
+		if !contains(operators, o) {
+			t.Errorf("operators do not contain %q", o)
+		}
+	}
+	if contains(operators, "function") {
+		t.Errorf("operators contain %q", "function")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTokenListLongestFirst(t *testing.T) {
+	if len(TokenList) != len(TokenMap) {
+		t.Fatalf("TokenList has %d strings, TokenMap %d", len(TokenList), len(TokenMap))
+	}
+	for i := 1; i < len(TokenList); i++ {
+		prev, s := TokenList[i-1], TokenList[i]
+		if len(prev) < len(s) || (len(prev) == len(s) && prev >= s) {
+			t.Errorf("%q is listed before %q", prev, s)
+		}
+	}
+}